@@ -4,7 +4,7 @@ package config
 
 import (
 	"errors"
-	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -29,6 +29,31 @@ type UserConfig struct {
 	Install         bool
 	LockfilePath    string
 	ImportMapPath   string
+	ResolutionMode  ResolutionMode
+	MetadataSource  MetadataSource
+	// Empty uses the host's, like "darwin", "x64" and "glibc"
+	TargetOS   string
+	TargetCPU  string
+	TargetLibc string
+	// Which of the root & workspace packages' dependency groups resolve
+	Dev  bool
+	Prod bool
+	Peer bool
+	// How node_modules is laid out when installing
+	Layout Layout
+	// Packages whose install scripts run, from "allow-scripts" in .duckenv
+	AllowScripts []string
+	// Install from the cache without downloading anything
+	Offline bool
+	// Fail instead of resolving when the lockfile doesn't match package.json
+	FrozenLockfile bool
+	// Scoped registries & credentials from .npmrc
+	Npmrc Npmrc
+	// Limits on the requests sent to each registry host. 0 is unlimited.
+	NetworkConcurrency       int
+	NetworkRequestsPerSecond float64
+	// How many times a request that fails with a network error, 429 or 5xx is sent again
+	NetworkRetries int
 }
 
 func (c *UserConfig) NormalizePackageJSONPath() {
@@ -37,34 +62,108 @@ func (c *UserConfig) NormalizePackageJSONPath() {
 	c.ImportMapPath = filepath.Join(c.PackageJSONPath, "../", "package.importmap")
 }
 
+// RegistrarString is a setting like --registrar, which lockfile.NewRegistrar reads.
 type RegistrarString string
 
 const JSRegistrarFormatterStringJSPM = RegistrarString("https://ga.jspm.io/npm:%s@%s/package.json")
 const JSRegistrarFormatterStringNPM = RegistrarString("https://registry.npmjs.org/%s/%s")
 const JSRegistrarFormatterStringSkypack = RegistrarString("https://cdn.skypack.dev/%s@%s/package.json")
 
-func (r RegistrarString) PackageJSON(name string, version string) string {
-	return fmt.Sprintf(string(r), name, version)
+// ResolutionMode picks which version wins when several satisfy a range.
+// "lowest-direct" only applies the lowest version to the root package's own dependencies.
+type ResolutionMode string
+
+const ResolutionModeHighest = ResolutionMode("highest")
+const ResolutionModeLowest = ResolutionMode("lowest")
+const ResolutionModeLowestDirect = ResolutionMode("lowest-direct")
+
+func (c *UserConfig) NormalizeResolutionMode() error {
+	switch c.ResolutionMode {
+	case "":
+		{
+			c.ResolutionMode = ResolutionModeHighest
+		}
+	case ResolutionModeHighest, ResolutionModeLowest, ResolutionModeLowestDirect:
+		{
+		}
+	default:
+		{
+			return errors.New("Expected resolution mode to be \"highest\", \"lowest\" or \"lowest-direct\"")
+		}
+	}
+
+	return nil
 }
 
-func (c *UserConfig) NormalizeRegistrar() error {
-	registrar := string(c.Registrar)
+// MetadataSource picks where the versions of each package come from.
+// "registry" reads abbreviated packuments from the registrar, which also have every version's package.json.
+type MetadataSource string
 
-	if registrar == "npm" || c.Registrar == JSRegistrarFormatterStringNPM {
-		c.Registrar = JSRegistrarFormatterStringNPM
-	} else if registrar == "skypack" || c.Registrar == JSRegistrarFormatterStringSkypack {
-		c.Registrar = JSRegistrarFormatterStringSkypack
-	} else if registrar == "jspm" || c.Registrar == JSRegistrarFormatterStringJSPM {
-		c.Registrar = JSRegistrarFormatterStringJSPM
-	} else if strings.HasPrefix(registrar, "https://") || strings.HasPrefix(registrar, "http://") {
+const MetadataSourceJSDelivr = MetadataSource("jsdelivr")
+const MetadataSourceRegistry = MetadataSource("registry")
 
-		if strings.Count(registrar, "%s") != 2 {
-			registrar += "%s/%s"
+func (c *UserConfig) NormalizeMetadataSource() error {
+	switch c.MetadataSource {
+	case "":
+		{
+			c.MetadataSource = MetadataSourceJSDelivr
+		}
+	case MetadataSourceJSDelivr, MetadataSourceRegistry:
+		{
 		}
+	default:
+		{
+			return errors.New("Expected metadata to be \"jsdelivr\" or \"registry\"")
+		}
+	}
 
-		c.Registrar = RegistrarString(registrar)
-	} else {
-		return errors.New("Expected registrar to be a url starting with https://, http://, or \"npm\", \"jspm\" or \"skypack\"")
+	return nil
+}
+
+const DefaultNetworkConcurrency = 16
+const DefaultNetworkRetries = 3
+
+func (c *UserConfig) NormalizeNetwork() error {
+	if c.NetworkConcurrency < 0 || c.NetworkRequestsPerSecond < 0 || c.NetworkRetries < 0 {
+		return errors.New("Expected network-concurrency, network-rps and network-retries to be 0 or more")
+	}
+
+	return nil
+}
+
+// Layout picks how packages are arranged in node_modules.
+// "hoisted" is like npm, and "isolated" is like pnpm, where packages can only load their own dependencies.
+type Layout string
+
+const LayoutHoisted = Layout("hoisted")
+const LayoutIsolated = Layout("isolated")
+
+func (c *UserConfig) NormalizeLayout() error {
+	switch c.Layout {
+	case "":
+		{
+			c.Layout = LayoutHoisted
+		}
+	case LayoutHoisted, LayoutIsolated:
+		{
+		}
+	default:
+		{
+			return errors.New("Expected layout to be \"hoisted\" or \"isolated\"")
+		}
+	}
+
+	return nil
+}
+
+// NormalizeDependencyGroups makes --prod leave out devDependencies and peerDependencies.
+func (c *UserConfig) NormalizeDependencyGroups() error {
+	if c.Prod && c.Dev {
+		return errors.New("Expected only one of --prod or --dev")
+	}
+
+	if c.Prod {
+		c.Peer = false
 	}
 
 	return nil
@@ -80,6 +179,24 @@ func (c *UserConfig) LoadCacheType() {
 	}
 }
 
+// GitCacheDir is where git dependencies are mirrored. Without a local cache, it's a temporary folder.
+func (c *UserConfig) GitCacheDir() string {
+	if c.From == CacheTypeLocal {
+		return filepath.Join(c.Cache, ".duckgit")
+	}
+
+	return filepath.Join(os.TempDir(), "duck-git")
+}
+
+// TarballCacheDir is where .tgz dependencies are downloaded. Without a local cache, it's a temporary folder.
+func (c *UserConfig) TarballCacheDir() string {
+	if c.From == CacheTypeLocal {
+		return filepath.Join(c.Cache, ".ducktarballs")
+	}
+
+	return filepath.Join(os.TempDir(), "duck-tarballs")
+}
+
 var Global UserConfig
 
 type Stopper interface {
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Npmrc is what duck reads from .npmrc files: which registry each scope uses, and how to log into each registry.
+type Npmrc struct {
+	// Registry is "registry", for unscoped packages & scopes without their own. Empty when no .npmrc sets it.
+	Registry string
+	// Scopes has the registry of each "@scope:registry", by scope
+	Scopes map[string]string
+	// Auth has the credentials of each registry, by the URL they're for without its protocol, like "//npm.acme.com/"
+	Auth map[string]RegistryAuth
+	// AlwaysAuth sends a package's registry credentials with its tarball, even when the tarball is on another host
+	AlwaysAuth bool
+}
+
+// RegistryAuth is the credentials from "//host/:_authToken" and friends.
+type RegistryAuth struct {
+	// Token is "_authToken", sent as a bearer token
+	Token string
+	// Basic is "_auth", which is already base64
+	Basic string
+	// Username & Password are "username" and the decoded "_password"
+	Username string
+	Password string
+	// AlwaysAuth is "always-auth", just for this registry
+	AlwaysAuth bool
+}
+
+// Header is the Authorization header for these credentials, or empty when there are none.
+func (a RegistryAuth) Header() string {
+	if len(a.Token) > 0 {
+		return "Bearer " + a.Token
+	} else if len(a.Basic) > 0 {
+		return "Basic " + a.Basic
+	} else if len(a.Username) > 0 && len(a.Password) > 0 {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+
+	return ""
+}
+
+// ReadNpmrc reads the user's .npmrc, then the project's in dir, which wins where they disagree.
+// Missing files are skipped.
+func ReadNpmrc(dir string) (Npmrc, error) {
+	var npmrc Npmrc
+
+	userconfig, ok := os.LookupEnv("NPM_CONFIG_USERCONFIG")
+	if !ok {
+		userconfig = filepath.Join(os.Getenv("HOME"), ".npmrc")
+	}
+
+	for _, path := range []string{userconfig, filepath.Join(dir, ".npmrc")} {
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return npmrc, err
+		}
+
+		err = npmrc.Parse(file, os.LookupEnv)
+		file.Close()
+		if err != nil {
+			return npmrc, err
+		}
+	}
+
+	return npmrc, nil
+}
+
+// LoadNpmrc reads the .npmrc files of the project in dir. Their "registry" becomes the registrar, unless one was passed.
+func (c *UserConfig) LoadNpmrc(dir string, registrarPassed bool) error {
+	npmrc, err := ReadNpmrc(dir)
+	if err != nil {
+		return err
+	}
+
+	c.Npmrc = npmrc
+	if !registrarPassed && len(npmrc.Registry) > 0 {
+		c.Registrar = RegistrarString(npmrc.Registry)
+	}
+
+	return nil
+}
+
+// "${NAME}" is replaced by $NAME, and "${NAME?}" is empty when $NAME isn't set
+var npmrcEnvPattern = regexp.MustCompile(`\$\{([^${}?]+)(\?)?\}`)
+
+// Parse adds the settings in file to n, replacing ones it already has.
+// Anything duck doesn't use is ignored.
+func (n *Npmrc) Parse(file io.Reader, lookupEnv func(string) (string, bool)) error {
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		n.parseLine(scanner.Text(), lookupEnv)
+	}
+
+	return scanner.Err()
+}
+
+func (n *Npmrc) parseLine(line string, lookupEnv func(string) (string, bool)) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+		return
+	}
+
+	equals := strings.IndexByte(line, '=')
+	if equals == -1 {
+		return
+	}
+
+	key := expandNpmrcEnv(strings.TrimSpace(line[:equals]), lookupEnv)
+	value := strings.TrimSpace(line[equals+1:])
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	value = expandNpmrcEnv(value, lookupEnv)
+
+	switch {
+	case key == "registry":
+		{
+			n.Registry = withTrailingSlash(value)
+		}
+	case key == "always-auth":
+		{
+			n.AlwaysAuth = value == "true"
+		}
+	case strings.HasPrefix(key, "@") && strings.HasSuffix(key, ":registry"):
+		{
+			if n.Scopes == nil {
+				n.Scopes = make(map[string]string)
+			}
+			n.Scopes[strings.TrimSuffix(key, ":registry")] = withTrailingSlash(value)
+		}
+	case strings.HasPrefix(key, "//"):
+		{
+			colon := strings.LastIndexByte(key, ':')
+			if colon == -1 {
+				return
+			}
+
+			nerf := withTrailingSlash(key[:colon])
+			if n.Auth == nil {
+				n.Auth = make(map[string]RegistryAuth)
+			}
+
+			auth := n.Auth[nerf]
+			switch key[colon+1:] {
+			case "_authToken":
+				{
+					auth.Token = value
+				}
+			case "_auth":
+				{
+					auth.Basic = value
+				}
+			case "username":
+				{
+					auth.Username = value
+				}
+			case "_password":
+				{
+					password, err := base64.StdEncoding.DecodeString(value)
+					if err != nil {
+						return
+					}
+					auth.Password = string(password)
+				}
+			case "always-auth":
+				{
+					auth.AlwaysAuth = value == "true"
+				}
+			default:
+				{
+					return
+				}
+			}
+			n.Auth[nerf] = auth
+		}
+	}
+}
+
+// Like npm, a variable that isn't set is left as is, unless it's optional
+func expandNpmrcEnv(value string, lookupEnv func(string) (string, bool)) string {
+	return npmrcEnvPattern.ReplaceAllStringFunc(value, func(match string) string {
+		groups := npmrcEnvPattern.FindStringSubmatch(match)
+		if env, ok := lookupEnv(groups[1]); ok {
+			return env
+		} else if len(groups[2]) > 0 {
+			return ""
+		}
+
+		return match
+	})
+}
+
+func withTrailingSlash(value string) string {
+	if strings.HasSuffix(value, "/") {
+		return value
+	}
+
+	return value + "/"
+}
+
+// ScopeRegistry is the registry of name's scope, when .npmrc gives it one.
+func (n *Npmrc) ScopeRegistry(name string) (string, bool) {
+	if n == nil || !strings.HasPrefix(name, "@") {
+		return "", false
+	}
+
+	slash := strings.IndexByte(name, '/')
+	if slash == -1 {
+		return "", false
+	}
+
+	registry, ok := n.Scopes[name[:slash]]
+	return registry, ok
+}
+
+// Authorization is the Authorization header for a request to rawURL, or empty when .npmrc has no credentials for it.
+// The credentials whose URL is the longest prefix of rawURL win. When none match, always-auth sends registry's.
+func (n *Npmrc) Authorization(rawURL string, registry string) string {
+	if n == nil || len(n.Auth) == 0 {
+		return ""
+	}
+
+	nerf := nerfDart(rawURL)
+	var match string
+	for prefix := range n.Auth {
+		if strings.HasPrefix(nerf, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+
+	if len(match) > 0 {
+		return n.Auth[match].Header()
+	}
+
+	if len(registry) > 0 {
+		if auth, ok := n.Auth[nerfDart(registry)]; ok && (n.AlwaysAuth || auth.AlwaysAuth) {
+			return auth.Header()
+		}
+	}
+
+	return ""
+}
+
+// nerfDart is rawURL without its protocol, query or credentials, like "//npm.acme.com/@acme%2fwidgets".
+func nerfDart(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || len(parsed.Host) == 0 {
+		return ""
+	}
+
+	return "//" + parsed.Host + parsed.EscapedPath()
+}
+
+// RedactURL hides the credentials in rawURL, so it can be logged.
+func RedactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+
+	if parsed.User == nil {
+		return rawURL
+	}
+
+	// url.User would escape the asterisks
+	parsed.User = nil
+	return strings.Replace(parsed.String(), "//", "//***@", 1)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/gammazero/workerpool"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	runner "github.com/jarred-sumner/devserverless/resolver/runner"
 	"github.com/jarred-sumner/peechy/buffer"
@@ -167,9 +168,12 @@ func (m *LocalPackageManifestCache) Put(name string, version string, manifest *l
 	}
 }
 
-const ManifestBucketName = "V1_ManifestCache"
+// V2 added overrides to JavascriptPackageManifestPartial, V3 added optional dependencies & platforms, V4 added optional peers, V5 added directories.bin, V6 kept install scripts, and V7 added integrity
+const ManifestBucketName = "V7_ManifestCache"
 const AliasBucketName = "V1_AliasCache"
-const RangeBucketName = "V1_RangeCache"
+
+// V2 added packuments
+const RangeBucketName = "V2_RangeCache"
 
 func NewLocalPackageManifestStore(databaseFile string) (*LocalPackageManifestStore, error) {
 	logger, _ := zap.NewDevelopment()
@@ -267,6 +271,7 @@ func NewLocalPackageManifestStore(databaseFile string) (*LocalPackageManifestSto
 		Emitter:            runner.New(),
 		NPMClient:          &fasthttp.Client{Name: "devserver"},
 		JSDelivrClient:     &fasthttp.Client{Name: "devserver"},
+		HTTP:               httpclient.New(httpclient.DefaultPolicy()),
 	}
 
 	store := LocalPackageManifestStore{
@@ -405,15 +410,15 @@ func (i *LocalPackageManifestStore) Flush(channel chan error, closeDB bool) {
 
 }
 
-func (i *LocalPackageRangeCache) Flush() ([]string, []lockfile.JSDelivrPackageData) {
-	values := make([]lockfile.JSDelivrPackageData, 0, 20)
+func (i *LocalPackageRangeCache) Flush() ([]string, []lockfile.PackageMetadata) {
+	values := make([]lockfile.PackageMetadata, 0, 20)
 	keys := make([]string, 0, 20)
 
 	i.ChangedKeys.Range(func(key string, value bool) bool {
 		i := i
 		v, ok := i.MemoryStore.Get(key)
 		if ok {
-			pkg := v.(lockfile.JSDelivrPackageData)
+			pkg := v.(lockfile.PackageMetadata)
 			values = append(values, pkg)
 			keys = append(keys, key)
 		}
@@ -425,11 +430,11 @@ func (i *LocalPackageRangeCache) Flush() ([]string, []lockfile.JSDelivrPackageDa
 	return keys, values
 }
 
-func (i *LocalPackageRangeCache) Get(name string) (*lockfile.JSDelivrPackageData, bool) {
+func (i *LocalPackageRangeCache) Get(name string) (*lockfile.PackageMetadata, bool) {
 	v, ok := i.MemoryStore.Get(name)
 
 	if ok {
-		pkg := v.(lockfile.JSDelivrPackageData)
+		pkg := v.(lockfile.PackageMetadata)
 		return &pkg, ok
 	} else {
 		bytes := unsafeGetBytes(name)
@@ -444,7 +449,7 @@ func (i *LocalPackageRangeCache) Get(name string) (*lockfile.JSDelivrPackageData
 
 		ok = true
 
-		pkg := lockfile.JSDelivrPackageData{}
+		pkg := lockfile.PackageMetadata{}
 		err := msgpack.Unmarshal(tempV, &pkg)
 
 		if err != nil {
@@ -457,7 +462,11 @@ func (i *LocalPackageRangeCache) Get(name string) (*lockfile.JSDelivrPackageData
 
 }
 
-func (i *LocalPackageRangeCache) Put(name string, manifest lockfile.JSDelivrPackageData) {
+func (i *LocalPackageRangeCache) Put(name string, manifest lockfile.PackageMetadata) {
 	i.MemoryStore.Set(name, manifest, 1)
-	i.ChangedKeys.Store(name, true)
+
+	// Don't save rate limits or server errors to disk, the next run should try again.
+	if manifest.Status != lockfile.PackageResolutionStatusRateLimit && manifest.Status != lockfile.PackageResolutionStatusInternal {
+		i.ChangedKeys.Store(name, true)
+	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/gammazero/workerpool"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	runner "github.com/jarred-sumner/devserverless/resolver/runner"
 	"github.com/valyala/fasthttp"
@@ -23,11 +24,11 @@ type MemoryPackageTagStore struct {
 	Store ristretto.Cache
 }
 
-func (i *MemoryPackageTagStore) Get(name string) (*lockfile.JSDelivrPackageData, bool) {
+func (i *MemoryPackageTagStore) Get(name string) (*lockfile.PackageMetadata, bool) {
 	v, ok := i.Store.Get(name)
 
 	if ok {
-		pkg := v.(lockfile.JSDelivrPackageData)
+		pkg := v.(lockfile.PackageMetadata)
 		return &pkg, ok
 	} else {
 		return nil, false
@@ -35,7 +36,7 @@ func (i *MemoryPackageTagStore) Get(name string) (*lockfile.JSDelivrPackageData,
 
 }
 
-func (i *MemoryPackageTagStore) Put(name string, manifest lockfile.JSDelivrPackageData) {
+func (i *MemoryPackageTagStore) Put(name string, manifest lockfile.PackageMetadata) {
 	i.Store.Set(name, manifest, 1)
 }
 
@@ -117,6 +118,7 @@ func NewMemoryPackageManifestStore() *lockfile.PackageManifestStore {
 		Emitter:            runner.New(),
 		NPMClient:          &fasthttp.Client{Name: "devserver"},
 		JSDelivrClient:     &fasthttp.Client{Name: "devserver"},
+		HTTP:               httpclient.New(httpclient.DefaultPolicy()),
 	}
 
 	if store.JSDelivrClient.TLSConfig == nil {
@@ -21,18 +21,24 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cespare/xxhash"
 	"github.com/jarred-sumner/devserverless/config"
 	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/internal/installer"
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer/lifecycle"
 	"github.com/jarred-sumner/devserverless/resolver/internal/server"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	"github.com/jarred-sumner/peechy/buffer"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/valyala/bytebufferpool"
 	"github.com/valyala/fasthttp"
 )
@@ -54,13 +60,66 @@ to quickly create a Cobra application.`,
 		pkgJsonPath := config.Global.PackageJSONPath
 		var err error
 
-		err = config.Global.NormalizeRegistrar()
+		err = config.Global.LoadNpmrc(filepath.Dir(pkgJsonPath), cmd.Flags().Changed("registrar"))
 		if err != nil {
 			cmd.PrintErr(err)
 			doExit(1, nil)
 			return
 		}
 
+		registrar, err := lockfile.NewRegistrar(string(config.Global.Registrar))
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+		// Saved in the lockfile the same way, however it was passed
+		config.Global.Registrar = config.RegistrarString(registrar.String())
+
+		err = config.Global.NormalizeResolutionMode()
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		err = config.Global.NormalizeMetadataSource()
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		err = lockfile.ValidateMetadataSource(registrar, config.Global.MetadataSource)
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		err = config.Global.NormalizeNetwork()
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		err = config.Global.NormalizeDependencyGroups()
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		err = config.Global.NormalizeLayout()
+		if err != nil {
+			cmd.PrintErr(err)
+			doExit(1, nil)
+			return
+		}
+
+		config.Global.AllowScripts = viper.GetStringSlice("allow-scripts")
+
 		var skipResolve = false
 
 		host := config.Global.Cache
@@ -92,6 +151,7 @@ to quickly create a Cobra application.`,
 			cmd.Println("An error occurred while reading " + pkgJsonPath)
 			cmd.PrintErr(err)
 			doExit(1, flushChannel)
+			return
 		}
 
 		file, err = lockfile.NewJavascriptPackageManifestPartial(&jsonText, config.BLACKLIST_PACKAGES, true)
@@ -100,14 +160,75 @@ to quickly create a Cobra application.`,
 			cmd.Println("An error occurred while parsing " + pkgJsonPath)
 			cmd.PrintErr(err)
 			doExit(1, flushChannel)
+			return
 		}
 		version = "1.0.0"
 		name = file.Name
 
-		packageHash := file.GeneratePackageHash()
+		rootDir := filepath.Dir(pkgJsonPath)
+		var workspaces []lockfile.Workspace
+		if patterns := lockfile.ReadWorkspacePatterns(&jsonText); len(patterns) > 0 {
+			workspaces, err = lockfile.LoadWorkspaces(rootDir, patterns, config.BLACKLIST_PACKAGES)
+
+			if err != nil {
+				cmd.Println("An error occurred while reading workspaces in " + pkgJsonPath)
+				cmd.PrintErr(err)
+				doExit(1, flushChannel)
+				return
+			}
+
+			if cacheType == config.CacheTypeRemote {
+				cmd.PrintErrf("<%d> [ERR]: Workspaces can't be resolved with a remote cache yet. Set --cache to \"none\" or to a directory.\n", lockfile.ErrorCodeGeneric)
+				doExit(1, flushChannel)
+				return
+			}
+		}
+
+		packageHash := lockfile.GenerateWorkspaceHash(&file, workspaces)
+		// Resolving & installing share it, so their requests to the same host count toward the same limits
+		httpClient := httpclient.New(httpclient.ConfigPolicy(&config.Global))
+		platform := lockfile.NewTargetPlatform(config.Global.TargetOS, config.Global.TargetCPU, config.Global.TargetLibc)
+		groups := lockfile.DependencyGroupProd | lockfile.DependencyGroupOptional
+		if config.Global.Dev {
+			groups |= lockfile.DependencyGroupDev
+		}
+		if config.Global.Peer {
+			groups |= lockfile.DependencyGroupPeer
+		}
+		resolveOptions := lockfile.ResolveOptions{Workspaces: workspaces, Groups: groups}
+		resolveOptions.RootDir, err = filepath.Abs(rootDir)
+		if err != nil {
+			cmd.Println("Unable to access " + rootDir)
+			cmd.PrintErr(err)
+			doExit(1, flushChannel)
+			return
+		}
+		if config.Global.ResolutionMode != config.ResolutionModeHighest {
+			// Switching strategies should re-resolve an existing lockfile
+			packageHash = packageHash + "-" + string(config.Global.ResolutionMode)
+		}
+		if groups != lockfile.DefaultDependencyGroups {
+			packageHash = packageHash + "-" + strconv.FormatUint(uint64(groups), 10)
+		}
+
+		// The resolve settings aren't in package.json, so they're part of what the lockfile's diff shows
+		resolutionModeInput := "resolution-mode=" + string(config.Global.ResolutionMode)
+		hashInputs := append(lockfile.WorkspaceHashInputs(&file, workspaces), resolutionModeInput, "groups="+strconv.FormatUint(uint64(groups), 10))
+
+		if config.Global.FrozenLockfile && config.Global.Resolve {
+			cmd.PrintErrf("<%d> [ERR]: --frozen-lockfile can't be used with --resolve\n", lockfile.ErrorCodeGeneric)
+			doExit(1, flushChannel)
+			return
+		}
 
 		if !config.Global.Resolve {
 			if _, err := os.Stat(config.Global.LockfilePath); os.IsNotExist(err) {
+				if config.Global.FrozenLockfile {
+					cmd.PrintErrf("<%d> [ERR]: No lockfile at %s, and --frozen-lockfile is set\n", lockfile.ErrorCodeGeneric, config.Global.LockfilePath)
+					doExit(1, flushChannel)
+					return
+				}
+
 				skipResolve = false
 			} else {
 				var manifestB []byte
@@ -117,6 +238,7 @@ to quickly create a Cobra application.`,
 					cmd.Println("Failed to read lockfile at " + config.Global.LockfilePath)
 					cmd.PrintErr(err)
 					doExit(1, flushChannel)
+					return
 				}
 
 				buf := buffer.Buffer{
@@ -124,16 +246,64 @@ to quickly create a Cobra application.`,
 				}
 
 				manifest, err = lockfile.DecodeJavascriptPackageManifest(&buf)
+				// Every lockfile has a hash, so a lockfile without one was cut short or is in another format
+				if err == nil && len(manifest.Hash) == 0 {
+					err = fmt.Errorf("%s has no hash", config.Global.LockfilePath)
+				} else if err == nil {
+					err = manifest.Validate()
+				}
 
-				if err != nil {
-					cmd.Println("Lockfile at " + config.Global.LockfilePath + " is corrupt or uses an older version of ducky.")
+				if err != nil && config.Global.FrozenLockfile {
+					cmd.PrintErrf("<%d> [ERR]: Lockfile at %s is corrupt or uses an older version of ducky, and --frozen-lockfile is set\n", lockfile.ErrorCodeGeneric, config.Global.LockfilePath)
 					cmd.PrintErr(err)
 					doExit(1, flushChannel)
+					return
+				} else if err != nil {
+					// Like a lockfile for other dependencies, it's resolved again from scratch
+					cmd.Println("Lockfile at " + config.Global.LockfilePath + " is corrupt or uses an older version of ducky. Resolving dependencies")
+					manifest = lockfile.JavascriptPackageManifest{}
+					err = nil
+				} else if config.Global.FrozenLockfile {
+					if !lockfile.SameRegistrar(manifest.Registrar, registrar) {
+						cmd.PrintErrf("<%d> [ERR]: Lockfile at %s was resolved from %s, not %s, and --frozen-lockfile is set\n", lockfile.ErrorCodeGeneric, config.Global.LockfilePath, manifest.Registrar, config.Global.Registrar)
+						doExit(1, flushChannel)
+						return
+					}
+
+					if manifest.ImportMapHost != string(config.Global.ImportMapHost) {
+						cmd.PrintErrf("<%d> [ERR]: Lockfile at %s was saved for import maps on %s, not %s, and --frozen-lockfile is set\n", lockfile.ErrorCodeGeneric, config.Global.LockfilePath, manifest.ImportMapHost, config.Global.ImportMapHost)
+						doExit(1, flushChannel)
+						return
+					}
+
+					if manifest.Hash != packageHash {
+						cmd.PrintErrf("<%d> [ERR]: Dependencies changed since %s was saved, and --frozen-lockfile is set\n", lockfile.ErrorCodeGeneric, config.Global.LockfilePath)
+						for _, line := range lockfile.DiffHashInputs(manifest.HashInputs, hashInputs) {
+							cmd.PrintErrln("  " + line)
+						}
+						cmd.PrintErrln("Run without --frozen-lockfile to update the lockfile.")
+						doExit(1, flushChannel)
+						return
+					}
 				}
 
 				if manifest.Hash != packageHash {
-					cmd.Println("Dependencies changed. Resolving dependencies")
+					// A corrupt lockfile already said so
+					if len(manifest.Hash) > 0 {
+						cmd.Println("Dependencies changed. Resolving dependencies")
+					}
 					skipResolve = false
+
+					// Unchanged dependencies keep their versions, unless they'd have resolved differently anyway
+					sameResolutionMode := false
+					for _, input := range manifest.HashInputs {
+						sameResolutionMode = sameResolutionMode || input == resolutionModeInput
+					}
+
+					if sameResolutionMode && lockfile.SameRegistrar(manifest.Registrar, registrar) {
+						locked := manifest
+						resolveOptions.Locked = &locked
+					}
 				} else {
 					skipResolve = true
 				}
@@ -141,6 +311,11 @@ to quickly create a Cobra application.`,
 		}
 
 		ctx := cmd.Context()
+		if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+			defer cancelTimeout()
+		}
 
 		if config.Global.Install {
 			installCtx, cancel := context.WithCancel(ctx)
@@ -159,6 +334,14 @@ to quickly create a Cobra application.`,
 			}
 
 			pkgInstaller, err = installer.NewPackageInstaller(absDir, host, &installCtx, installWaitGroup)
+			pkgInstaller.GitCacheFolder = config.Global.GitCacheDir()
+			pkgInstaller.TarballCacheFolder = config.Global.TarballCacheDir()
+			pkgInstaller.Platform = platform
+			pkgInstaller.Layout = config.Global.Layout
+			pkgInstaller.Offline = config.Global.Offline
+			pkgInstaller.Npmrc = &config.Global.Npmrc
+			pkgInstaller.Registrar = registrar
+			pkgInstaller.HTTP = httpClient
 
 			if shoulClear, _ := cmd.Flags().GetBool("nuke"); shoulClear {
 				os.RemoveAll(pkgInstaller.NodeModulesFolder)
@@ -172,11 +355,13 @@ to quickly create a Cobra application.`,
 			case config.CacheTypeRemote:
 				{
 					denylist := false
+					groupsValue := uint(groups)
 					req := lockfile.JavascriptPackageRequest{
 						Manifest:       &file,
 						ClientVersion:  &version,
 						Name:           &name,
 						EnableDenylist: &denylist,
+						Groups:         &groupsValue,
 					}
 
 					reqBuffer := buffer.Buffer{
@@ -199,7 +384,11 @@ to quickly create a Cobra application.`,
 					httpReq.SetRequestURI(fmt.Sprintf("%s/pkg/%d", host, hash))
 					cmd.Printf("> POST %s (%d bytes) \n", httpReq.URI().String(), reqBuffer.Offset)
 					httpReq.Header.Add("Content-Type", string(server.AcceptEncodingBinary))
-					fasthttp.DoDeadline(httpReq, httpResp, time.Now().Add(time.Second*30))
+					deadline := time.Now().Add(time.Second * 30)
+					if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+						deadline = ctxDeadline
+					}
+					fasthttp.DoDeadline(httpReq, httpResp, deadline)
 					statusCode := httpResp.StatusCode()
 					cmd.Printf("< Status: %d\n", statusCode)
 
@@ -239,6 +428,9 @@ to quickly create a Cobra application.`,
 					} else if resp.Result == nil {
 						cmd.Printf("<%d> [ERR]: %s", &resp.ErrorCode, "Something went wrong.")
 						os.Exit(1)
+					} else if err := resp.Result.Validate(); err != nil {
+						cmd.Printf("<%d> [ERR]: %s returned a corrupt lockfile: %s", lockfile.ErrorCodeGeneric, config.RedactURL(host), err.Error())
+						os.Exit(1)
 					}
 
 					manifest = *resp.Result
@@ -284,14 +476,20 @@ to quickly create a Cobra application.`,
 						os.Exit(1)
 					}
 
-					store.Store.RegistrarAPI = config.Global.Registrar
+					store.Store.Registrar = registrar
+					store.Store.ResolutionMode = config.Global.ResolutionMode
+					store.Store.MetadataSource = config.Global.MetadataSource
+					store.Store.Npmrc = &config.Global.Npmrc
+					store.Store.HTTP = httpClient
+					store.Store.GitCacheDir = config.Global.GitCacheDir()
+					store.Store.TarballCacheDir = config.Global.TarballCacheDir()
 					if config.Global.Install {
 						store.Store.Installer = installer.PackageInstallerBox{
 							Installer: &pkgInstaller,
 						}
 					}
 
-					manifest, err = store.Store.ResolveDependencies(&file, ctx)
+					manifest, err = store.Store.ResolveDependenciesWithOptions(&file, resolveOptions, ctx)
 
 					if err != nil {
 						cmd.Printf("<%d> [ERR]: %s", lockfile.ErrorCodeGeneric, err.Error())
@@ -303,14 +501,20 @@ to quickly create a Cobra application.`,
 			case config.CacheTypeNone:
 				{
 					store := cache.NewMemoryPackageManifestStore()
-					store.RegistrarAPI = config.Global.Registrar
+					store.Registrar = registrar
+					store.ResolutionMode = config.Global.ResolutionMode
+					store.MetadataSource = config.Global.MetadataSource
+					store.Npmrc = &config.Global.Npmrc
+					store.HTTP = httpClient
+					store.GitCacheDir = config.Global.GitCacheDir()
+					store.TarballCacheDir = config.Global.TarballCacheDir()
 					if config.Global.Install {
 						store.Installer = installer.PackageInstallerBox{
 							Installer: &pkgInstaller,
 						}
 					}
 
-					manifest, err = store.ResolveDependencies(&file, ctx)
+					manifest, err = store.ResolveDependenciesWithOptions(&file, resolveOptions, ctx)
 
 					if err != nil {
 						cmd.Printf("<%d> [ERR]: %s", lockfile.ErrorCodeGeneric, err.Error())
@@ -320,12 +524,43 @@ to quickly create a Cobra application.`,
 				}
 			}
 
+			if conflictCount := len(manifest.PeerConflicts.Name); conflictCount > 0 {
+				strict, _ := cmd.Flags().GetBool("strict-peer-deps")
+				if strict {
+					cmd.PrintErrf("<%d> [ERR]: %d peer dependency conflicts\n", lockfile.ErrorCodeGeneric, conflictCount)
+				} else {
+					cmd.PrintErrf("[WARN]: %d peer dependency conflicts\n", conflictCount)
+				}
+
+				for _, message := range manifest.PeerConflicts.Messages() {
+					cmd.PrintErrln("  " + message)
+				}
+
+				if strict {
+					doExit(1, flushChannel)
+					return
+				}
+			}
+
+			if failureCount := len(manifest.Failures.Name); failureCount > 0 {
+				cmd.PrintErrf("<%d> [ERR]: Failed to resolve %d packages\n", lockfile.ErrorCodeVersionDoesntExit, failureCount)
+				for _, message := range manifest.Failures.Messages() {
+					cmd.PrintErrln("  " + message)
+				}
+
+				if allowPartial, _ := cmd.Flags().GetBool("allow-partial"); !allowPartial {
+					cmd.PrintErrln("Pass --allow-partial to save the lockfile anyway.")
+					doExit(1, flushChannel)
+					return
+				}
+			}
+
 			var importBuffer []byte
 
 			importBuffer, err = lockfile.NewImportMap(&manifest, string(config.Global.ImportMapHost))
 
 			if err != nil {
-				cmd.Printf("<%d> [ERR]: %s\n", lockfile.ErrorCodeGeneric, "Failed to generate import map")
+				cmd.Printf("<%d> [ERR]: Failed to generate import map: %s\n", lockfile.ErrorCodeGeneric, err.Error())
 				doExit(1, flushChannel)
 				return
 			}
@@ -338,7 +573,31 @@ to quickly create a Cobra application.`,
 				return
 			}
 
+			// Each workspace gets an import map next to its package.json, where its own dependencies are top-level
+			for _, workspace := range workspaces {
+				index, ok := manifest.WorkspaceIndex(workspace.Path)
+				if !ok {
+					continue
+				}
+
+				workspaceImportMapPath := filepath.Join(rootDir, filepath.FromSlash(workspace.Path), filepath.Base(config.Global.ImportMapPath))
+				importBuffer, err = lockfile.NewWorkspaceImportMap(&manifest, string(config.Global.ImportMapHost), index)
+
+				if err == nil {
+					err = os.WriteFile(workspaceImportMapPath, importBuffer, os.ModePerm)
+				}
+
+				if err != nil {
+					cmd.Printf("<%d> [ERR]: Failed to write import map to %s: %s\n", lockfile.ErrorCodeGeneric, workspaceImportMapPath, err.Error())
+					doExit(1, flushChannel)
+					return
+				}
+			}
+
 			manifest.Hash = packageHash
+			manifest.HashInputs = hashInputs
+			manifest.Registrar = string(config.Global.Registrar)
+			manifest.ImportMapHost = string(config.Global.ImportMapHost)
 			manifestBuffer := buffer.Buffer{
 				Bytes: bytebufferpool.Get(),
 			}
@@ -353,6 +612,9 @@ to quickly create a Cobra application.`,
 			}
 
 			cmd.Printf("🔗 Saved import map to %s\n", config.Global.ImportMapPath)
+			if len(workspaces) > 0 {
+				cmd.Printf("🔗 Saved import maps for %d workspaces\n", len(workspaces))
+			}
 
 			err = os.WriteFile(config.Global.LockfilePath, manifestBuffer.Slice(), os.ModePerm)
 
@@ -372,8 +634,18 @@ to quickly create a Cobra application.`,
 				}
 				os.WriteFile(config.Global.LockfilePath+".json", formatJSON(json), os.ModePerm)
 			}
+
+			if config.Global.Install && !installPackages(cmd, ctx, &pkgInstaller, &file, &manifest) {
+				doExit(1, flushChannel)
+				return
+			}
 		} else if config.Global.Install {
-			// list := make([]lockfile.PackageManifestCache)
+			// The lockfile is up to date, so it's installed as is
+			pkgInstaller.EnqueueLockfile(&manifest)
+			if !installPackages(cmd, ctx, &pkgInstaller, &file, &manifest) {
+				doExit(1, flushChannel)
+				return
+			}
 		}
 
 		if config.Global.Install {
@@ -389,12 +661,60 @@ to quickly create a Cobra application.`,
 	},
 }
 
+// installPackages installs every package in manifest once it's fetched, and runs the install scripts allowed in .duckenv.
+// It prints what failed, and returns false if anything did.
+func installPackages(cmd *cobra.Command, ctx context.Context, pkgInstaller *installer.PackageInstaller, root *lockfile.JavascriptPackageManifestPartial, manifest *lockfile.JavascriptPackageManifest) bool {
+	pkgInstaller.Install(manifest)
+
+	installed := true
+	for _, job := range pkgInstaller.Jobs {
+		if job.StatusReason == installer.InstallPackageStatusReasonFailIntegrityError {
+			cmd.PrintErrf("<%d> [ERR]: %s@%s didn't match the integrity in the lockfile, so it wasn't installed: %s\n", lockfile.ErrorCodeGeneric, job.Manifest.Name, job.Manifest.Version.Tag, job.Error)
+			installed = false
+		} else if job.Status == installer.InstallPackageStatusFail {
+			cmd.PrintErrf("<%d> [ERR]: Failed to install %s to %s: %s\n", lockfile.ErrorCodeGeneric, job.Manifest.Name, job.DestinationPath, job.Error)
+			installed = false
+		}
+	}
+
+	if !installed {
+		return false
+	}
+
+	scriptPackages, skipped := pkgInstaller.ScriptPackages(root, manifest, config.Global.AllowScripts)
+	if len(skipped) > 0 {
+		cmd.PrintErrf("[WARN]: Skipped install scripts of %s. Add them to allow-scripts in .duckenv to run them.\n", strings.Join(skipped, ", "))
+	}
+
+	runner := lifecycle.Runner{
+		Ctx:        ctx,
+		Workers:    runtime.NumCPU(),
+		BinFolders: []string{filepath.Join(pkgInstaller.NodeModulesFolder, ".bin")},
+	}
+
+	for _, result := range runner.Run(scriptPackages) {
+		if result.Error != nil {
+			cmd.PrintErrf("<%d> [ERR]: %s\n", lockfile.ErrorCodeGeneric, result.Message())
+			cmd.PrintErr(string(result.Output))
+			installed = false
+		}
+	}
+
+	return installed
+}
+
 func doExit(exitCode int, flusher chan error) {
-	if flusher == nil {
-		// os.Exit(exitCode)
-	} else {
+	if flusher != nil {
 		<-flusher
-		// os.Exit(exitCode)
+	}
+
+	// A successful run returns normally so main can stop the profiler
+	if exitCode != 0 {
+		if config.Profiler != nil {
+			config.Profiler.Stop()
+		}
+
+		os.Exit(exitCode)
 	}
 }
 
@@ -409,8 +729,20 @@ func init() {
 	clientCmd.Flags().BoolP("write", "w", true, "Write binary version of lockfile to disk")
 	clientCmd.Flags().BoolVarP(&config.Global.Install, "install", "i", true, "Allow installing")
 	clientCmd.Flags().Bool("nuke", false, "Delete node_modules before installing")
+	clientCmd.Flags().BoolVar(&config.Global.Offline, "offline", false, "Only install packages that are already in the cache, and fail if any aren't")
+	clientCmd.Flags().StringVar((*string)(&config.Global.Layout), "layout", string(config.LayoutHoisted), "How node_modules is laid out: \"hoisted\" like npm, or \"isolated\" like pnpm, where packages only see their own dependencies")
+	clientCmd.Flags().BoolVar(&config.Global.FrozenLockfile, "frozen-lockfile", false, "Fail with a diff instead of resolving when package.json doesn't match the lockfile, or the lockfile came from a different registrar or import map host")
 	clientCmd.Flags().BoolVarP(&config.Global.Resolve, "resolve", "r", false, "Write binary version of lockfile to disk")
 	clientCmd.Flags().StringVarP(&config.Global.PackageJSONPath, "package", "p", "./package.json", "Path to package.json file")
+	clientCmd.Flags().Bool("allow-partial", false, "Save the lockfile even when some packages failed to resolve")
+	clientCmd.Flags().Duration("timeout", 0, "Give up after this long, like \"30s\". 0 waits forever")
+	clientCmd.Flags().Bool("strict-peer-deps", false, "Fail instead of warning when a peer dependency resolves to a version outside its range")
+	clientCmd.Flags().BoolVar(&config.Global.Dev, "dev", false, "Also resolve & install devDependencies of the root and workspace packages")
+	clientCmd.Flags().BoolVar(&config.Global.Prod, "prod", false, "Only resolve & install dependencies and optionalDependencies. Turns off --peer")
+	clientCmd.Flags().BoolVar(&config.Global.Peer, "peer", true, "Resolve & install peerDependencies of the root and workspace packages")
+	clientCmd.Flags().StringVar(&config.Global.TargetOS, "os", "", "Install optional dependencies for this OS instead of the host's, like \"linux\" or \"darwin\"")
+	clientCmd.Flags().StringVar(&config.Global.TargetCPU, "cpu", "", "Install optional dependencies for this CPU instead of the host's, like \"x64\" or \"arm64\"")
+	clientCmd.Flags().StringVar(&config.Global.TargetLibc, "libc", "", "Install optional dependencies for this libc on Linux, \"glibc\" or \"musl\"")
 	clientCmd.TraverseChildren = true
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
@@ -59,12 +59,22 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&config.Global.ConfigFile, "config", "", "config file (default is $HOME/.duckenv)")
 	rootCmd.PersistentFlags().StringVarP(&config.Global.Cache, "cache", "c", filepath.Join(os.Getenv("HOME"), ".duck"), "Absolute directory or \"none\"")
 	rootCmd.PersistentFlags().StringVarP((*string)(&config.Global.ImportMapHost), "to", "t", string(config.JSRegistrarFormatterStringNPM), "If its a local file path, download & extract tarballs. If its a remote file path, use an import map.")
-	rootCmd.PersistentFlags().StringVar((*string)(&config.Global.Registrar), "registrar", string(config.JSRegistrarFormatterStringNPM), "Where to load the package.json files from? Can be \"npm\", \"skypack\", \"jspm\", or an absolute URL where the first %s is the package name and the second %s is the version.")
+	rootCmd.PersistentFlags().StringVar((*string)(&config.Global.Registrar), "registrar", string(config.JSRegistrarFormatterStringNPM), "Where packages are resolved & installed from. Can be \"npm\", \"skypack\", \"jspm\", a registry URL, or a local directory laid out like a registry. Defaults to \"registry\" in .npmrc, then npm.")
+	rootCmd.PersistentFlags().StringVar((*string)(&config.Global.ResolutionMode), "resolution-mode", string(config.ResolutionModeHighest), "Which version satisfies a range: \"highest\", \"lowest\", or \"lowest-direct\" (lowest for direct dependencies only).")
+	rootCmd.PersistentFlags().StringVar((*string)(&config.Global.MetadataSource), "metadata", string(config.MetadataSourceJSDelivr), "Where to load the versions of each package from: \"jsdelivr\", or \"registry\" for the registrar's packuments, which needs \"npm\" or a registry URL as the registrar.")
+	rootCmd.PersistentFlags().IntVar(&config.Global.NetworkConcurrency, "network-concurrency", config.DefaultNetworkConcurrency, "How many requests each registry host has in flight at once. 0 is unlimited.")
+	rootCmd.PersistentFlags().Float64Var(&config.Global.NetworkRequestsPerSecond, "network-rps", 0, "How many requests are sent to each registry host per second. 0 is unlimited.")
+	rootCmd.PersistentFlags().IntVar(&config.Global.NetworkRetries, "network-retries", config.DefaultNetworkRetries, "How many times a request is retried after a network error, 429 or 5xx, with backoff & Retry-After.")
 	rootCmd.PersistentFlags().String("profile", "none", "run with profiling enabled (memory, cpu, trace, goroutine, mutex, block or thread)")
 
 	viper.BindPFlag("cache", rootCmd.Flags().Lookup("cache"))
 	viper.BindPFlag("to", rootCmd.Flags().Lookup("to"))
 	viper.BindPFlag("registrar", rootCmd.Flags().Lookup("registrar"))
+	viper.BindPFlag("resolution-mode", rootCmd.Flags().Lookup("resolution-mode"))
+	viper.BindPFlag("metadata", rootCmd.Flags().Lookup("metadata"))
+	viper.BindPFlag("network-concurrency", rootCmd.Flags().Lookup("network-concurrency"))
+	viper.BindPFlag("network-rps", rootCmd.Flags().Lookup("network-rps"))
+	viper.BindPFlag("network-retries", rootCmd.Flags().Lookup("network-retries"))
 	viper.BindEnv("cache", "DUCK_CACHE")
 	viper.BindEnv("registrar", "NPM_PACKAGE_REGISTRAR")
 	rootCmd.TraverseChildren = true
@@ -16,8 +16,11 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+
 	"github.com/jarred-sumner/devserverless/config"
 	"github.com/jarred-sumner/devserverless/resolver/internal/server"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	"github.com/spf13/cobra"
 )
 
@@ -35,19 +38,53 @@ to quickly create a Cobra application.`,
 		port, _ := cmd.Flags().GetUint("port")
 		config.Global.LoadCacheType()
 
-		err := config.Global.NormalizeRegistrar()
+		pwd, err := os.Getwd()
+		if err == nil {
+			err = config.Global.LoadNpmrc(pwd, cmd.Flags().Changed("registrar"))
+		}
+
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		registrar, err := lockfile.NewRegistrar(string(config.Global.Registrar))
+
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		err = config.Global.NormalizeResolutionMode()
 
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
 
+		err = config.Global.NormalizeMetadataSource()
+
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		err = lockfile.ValidateMetadataSource(registrar, config.Global.MetadataSource)
+
+		if err != nil {
+			cmd.PrintErr(err)
+			return
+		}
+
+		err = config.Global.NormalizeNetwork()
+
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
 
-		s := server.Server{}
+		s := server.Server{Registrar: registrar}
+		s.ResolveTimeout, _ = cmd.Flags().GetDuration("timeout")
 		s.Launch(port)
 	},
 }
@@ -60,6 +97,7 @@ func init() {
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
 	serveCmd.Flags().Uint("port", 8087, "A help for foo")
+	serveCmd.Flags().Duration("timeout", 0, "Cancel resolving a request after this long. 0 is no limit. Requests are always cancelled when their client disconnects.")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
@@ -0,0 +1,252 @@
+// Package git resolves git dependencies with the git binary, using bare mirrors kept in a cache folder.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash"
+	"github.com/jarred-sumner/devserverless/resolver/node_semver"
+)
+
+// Binary is the git executable to run.
+var Binary = "git"
+
+const semverPrefix = "semver:"
+
+var ErrNoMatchingTag = errors.New("no tag matches the semver range")
+
+// Show & Archive only take full commit SHAs, since they come from lockfiles & package.json files, and anything else could be read as a flag
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+func validateCommit(commit string) error {
+	if !commitPattern.MatchString(commit) {
+		return fmt.Errorf("invalid git commit %q", commit)
+	}
+
+	return nil
+}
+
+// Repository is a bare mirror of a remote repository.
+type Repository struct {
+	URL string
+	Dir string
+}
+
+// MaxMirrorAge is how long a fetched mirror is used before it's fetched again, so branches & semver ranges pick up new commits in long running processes like "duck serve".
+var MaxMirrorAge = time.Minute
+
+// Each mirror is only cloned or fetched by one caller at a time. fetched has when each was last fetched.
+var mirrorLocks sync.Map
+var fetched sync.Map
+
+var sshEnvOnce sync.Once
+var sshEnv []string
+
+// batchSSHEnv makes ssh fail instead of prompting, unless the user has their own ssh command for custom keys & ports.
+func batchSSHEnv() []string {
+	sshEnvOnce.Do(func() {
+		if _, ok := os.LookupEnv("GIT_SSH_COMMAND"); ok {
+			return
+		} else if _, ok := os.LookupEnv("GIT_SSH"); ok {
+			return
+		} else if sshCommand, _ := exec.Command(Binary, "config", "--get", "core.sshCommand").Output(); len(bytes.TrimSpace(sshCommand)) > 0 {
+			return
+		}
+
+		sshEnv = []string{"GIT_SSH_COMMAND=ssh -o BatchMode=yes"}
+	})
+
+	return sshEnv
+}
+
+func run(ctx context.Context, dir string, stdout io.Writer, args ...string) error {
+	cmd := exec.CommandContext(ctx, Binary, args...)
+	cmd.Dir = dir
+	// Never wait on a password prompt
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), batchSSHEnv()...)
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func output(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := run(ctx, dir, &stdout, args...)
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// MirrorDir is the folder in cacheDir that url is mirrored to.
+func MirrorDir(cacheDir string, url string) string {
+	return filepath.Join(cacheDir, strconv.FormatUint(xxhash.Sum64String(url), 16))
+}
+
+// Open clones url into cacheDir as a bare mirror, or fetches it when it's already there.
+func Open(ctx context.Context, cacheDir string, url string) (*Repository, error) {
+	repo := &Repository{URL: url, Dir: MirrorDir(cacheDir, url)}
+
+	lock, _ := mirrorLocks.LoadOrStore(repo.Dir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if fetchedAt, ok := fetched.Load(repo.Dir); ok && time.Since(fetchedAt.(time.Time)) < MaxMirrorAge {
+		return repo, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(repo.Dir, "HEAD")); err == nil {
+		if err := run(ctx, repo.Dir, nil, "remote", "update", "--prune"); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return nil, err
+		}
+
+		// Clone somewhere else first, so a cancelled clone doesn't leave a broken mirror behind
+		tempDir, err := os.MkdirTemp(cacheDir, ".clone")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tempDir)
+
+		if err := run(ctx, "", nil, "clone", "--mirror", "--quiet", "--", url, tempDir); err != nil {
+			return nil, err
+		}
+
+		if err := os.Rename(tempDir, repo.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	fetched.Store(repo.Dir, time.Now())
+	return repo, nil
+}
+
+// RevParse returns the commit SHA of ref.
+func (r *Repository) RevParse(ctx context.Context, ref string) (string, error) {
+	// Refs can't start with "-", and this stops them from being read as flags
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %s", ref)
+	}
+
+	return output(ctx, r.Dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+// Tags lists every tag in the repository.
+func (r *Repository) Tags(ctx context.Context) ([]string, error) {
+	tags, err := output(ctx, r.Dir, "tag", "--list")
+	if err != nil || tags == "" {
+		return nil, err
+	}
+
+	return strings.Split(tags, "\n"), nil
+}
+
+// SemverTag returns the highest tag that satisfies versionRange. Tags may start with "v".
+func (r *Repository) SemverTag(ctx context.Context, versionRange string) (string, error) {
+	tags, err := r.Tags(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	tokenized := node_semver.Tokenize(versionRange)
+	var best string
+	var bestVersion node_semver.Version
+	for _, tag := range tags {
+		parsed := node_semver.Tokenize(strings.TrimPrefix(tag, "v"))
+		if parsed.Value != node_semver.TokenizeResultValueVersion {
+			continue
+		}
+
+		version := parsed.Version
+		switch tokenized.Value {
+		case node_semver.TokenizeResultValueVersion:
+			{
+				if !tokenized.Version.EQ(*version) {
+					continue
+				}
+			}
+		case node_semver.TokenizeResultValueRange:
+			{
+				if !tokenized.Range(*version) {
+					continue
+				}
+			}
+		default:
+			{
+				continue
+			}
+		}
+
+		// Copied, since Tokenize can reuse its versions
+		if best == "" || version.GT(bestVersion) {
+			best = tag
+			bestVersion = *version
+		}
+	}
+
+	if best == "" {
+		return "", ErrNoMatchingTag
+	}
+
+	return best, nil
+}
+
+// Resolve returns the commit SHA a URL fragment points to. It's a branch, tag or commit, or "semver:<range>".
+// Without a fragment, it's the default branch.
+func (r *Repository) Resolve(ctx context.Context, fragment string) (string, error) {
+	ref := fragment
+	if ref == "" {
+		ref = "HEAD"
+	} else if strings.HasPrefix(fragment, semverPrefix) {
+		tag, err := r.SemverTag(ctx, fragment[len(semverPrefix):])
+		if err != nil {
+			return "", err
+		}
+
+		ref = "refs/tags/" + tag
+	}
+
+	return r.RevParse(ctx, ref)
+}
+
+// Show returns the contents of file at commit, which is a full SHA.
+func (r *Repository) Show(ctx context.Context, commit string, file string) ([]byte, error) {
+	if err := validateCommit(commit); err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	err := run(ctx, r.Dir, &stdout, "show", "--end-of-options", commit+":"+file)
+	return stdout.Bytes(), err
+}
+
+// Archive writes the files at commit, which is a full SHA, to w as a .tar.gz, inside a "package" folder like npm's tarballs.
+func (r *Repository) Archive(ctx context.Context, commit string, w io.Writer) error {
+	if err := validateCommit(commit); err != nil {
+		return err
+	}
+
+	return run(ctx, r.Dir, w, "archive", "--format=tar.gz", "--prefix=package/", "--end-of-options", commit)
+}
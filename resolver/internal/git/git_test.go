@@ -0,0 +1,181 @@
+package git_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, out)
+	}
+
+	return string(bytes.TrimSpace(out))
+}
+
+// newBareRepo makes a bare repository with a commit for each version, tagged "v<version>".
+func newBareRepo(t *testing.T, versions ...string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	work := t.TempDir()
+	runGit(t, work, "init", "--quiet")
+	for _, version := range versions {
+		assert.Nil(t, os.WriteFile(filepath.Join(work, "package.json"), []byte(`{"name": "lib", "version": "`+version+`"}`), 0644))
+		runGit(t, work, "add", "package.json")
+		runGit(t, work, "commit", "--quiet", "-m", version)
+		runGit(t, work, "tag", "v"+version)
+	}
+
+	bare := filepath.Join(t.TempDir(), "lib.git")
+	runGit(t, work, "clone", "--quiet", "--bare", work, bare)
+	return bare
+}
+
+func TestResolve(t *testing.T) {
+	bare := newBareRepo(t, "1.0.0", "1.1.0", "2.0.0")
+	ctx := context.Background()
+
+	repo, err := git.Open(ctx, t.TempDir(), "file://"+bare)
+	assert.Nil(t, err)
+
+	head := runGit(t, bare, "rev-parse", "HEAD")
+	v110 := runGit(t, bare, "rev-parse", "v1.1.0^{commit}")
+
+	commit, err := repo.Resolve(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, head, commit)
+
+	commit, err = repo.Resolve(ctx, "main")
+	assert.Nil(t, err)
+	assert.Equal(t, head, commit)
+
+	commit, err = repo.Resolve(ctx, "semver:^1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, v110, commit)
+
+	commit, err = repo.Resolve(ctx, v110[:10])
+	assert.Nil(t, err)
+	assert.Equal(t, v110, commit)
+
+	_, err = repo.Resolve(ctx, "semver:^3.0.0")
+	assert.ErrorIs(t, err, git.ErrNoMatchingTag)
+
+	_, err = repo.Resolve(ctx, "missing-branch")
+	assert.NotNil(t, err)
+
+	body, err := repo.Show(ctx, v110, "package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name": "lib", "version": "1.1.0"}`, string(body))
+}
+
+func TestOpenReusesMirror(t *testing.T) {
+	bare := newBareRepo(t, "1.0.0")
+	cacheDir := t.TempDir()
+	ctx := context.Background()
+
+	first, err := git.Open(ctx, cacheDir, "file://"+bare)
+	assert.Nil(t, err)
+	second, err := git.Open(ctx, cacheDir, "file://"+bare)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Dir, second.Dir)
+
+	entries, err := os.ReadDir(cacheDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestOpenFetchesStaleMirror(t *testing.T) {
+	bare := newBareRepo(t, "1.0.0")
+	cacheDir := t.TempDir()
+	ctx := context.Background()
+
+	repo, err := git.Open(ctx, cacheDir, "file://"+bare)
+	assert.Nil(t, err)
+	before, err := repo.Resolve(ctx, "main")
+	assert.Nil(t, err)
+
+	// A new commit on main, pushed after the mirror was fetched
+	work := t.TempDir()
+	runGit(t, work, "clone", "--quiet", bare, ".")
+	assert.Nil(t, os.WriteFile(filepath.Join(work, "package.json"), []byte(`{"name": "lib", "version": "1.1.0"}`), 0644))
+	runGit(t, work, "commit", "--quiet", "-am", "1.1.0")
+	runGit(t, work, "push", "--quiet", "origin", "main")
+	after := runGit(t, work, "rev-parse", "HEAD")
+
+	repo, err = git.Open(ctx, cacheDir, "file://"+bare)
+	assert.Nil(t, err)
+	commit, err := repo.Resolve(ctx, "main")
+	assert.Nil(t, err)
+	assert.Equal(t, before, commit)
+
+	maxMirrorAge := git.MaxMirrorAge
+	git.MaxMirrorAge = 0
+	t.Cleanup(func() { git.MaxMirrorAge = maxMirrorAge })
+
+	repo, err = git.Open(ctx, cacheDir, "file://"+bare)
+	assert.Nil(t, err)
+	commit, err = repo.Resolve(ctx, "main")
+	assert.Nil(t, err)
+	assert.Equal(t, after, commit)
+}
+
+func TestArchive(t *testing.T) {
+	bare := newBareRepo(t, "1.0.0")
+	ctx := context.Background()
+
+	repo, err := git.Open(ctx, t.TempDir(), "file://"+bare)
+	assert.Nil(t, err)
+	commit, err := repo.Resolve(ctx, "v1.0.0")
+	assert.Nil(t, err)
+
+	var archive bytes.Buffer
+	assert.Nil(t, repo.Archive(ctx, commit, &archive))
+
+	gz, err := gzip.NewReader(&archive)
+	assert.Nil(t, err)
+	reader := tar.NewReader(gz)
+	names := make([]string, 0, 2)
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+
+	assert.Contains(t, names, "package/package.json")
+}
+
+func TestRejectsInvalidCommits(t *testing.T) {
+	bare := newBareRepo(t, "1.0.0")
+	ctx := context.Background()
+
+	repo, err := git.Open(ctx, t.TempDir(), "file://"+bare)
+	assert.Nil(t, err)
+	commit, err := repo.Resolve(ctx, "v1.0.0")
+	assert.Nil(t, err)
+
+	output := filepath.Join(t.TempDir(), "out")
+	for _, invalid := range []string{"--output=" + output, "--remote=file:///etc", commit[:10], "v1.0.0", "HEAD", ""} {
+		_, err = repo.Show(ctx, invalid, "package.json")
+		assert.NotNil(t, err, invalid)
+		assert.NotNil(t, repo.Archive(ctx, invalid, &bytes.Buffer{}), invalid)
+	}
+
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,300 @@
+// Package httpclient sends registry requests with fasthttp, retrying the ones that fail for a moment and limiting how hard each host is hit.
+package httpclient
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/valyala/fasthttp"
+)
+
+// Policy is how requests are retried and limited.
+type Policy struct {
+	// Retries is how many times a request is sent again after its first attempt fails
+	Retries int
+	// The wait before each retry doubles from MinBackoff up to MaxBackoff, and is jittered so that requests that failed together don't retry together
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetryAfter is the longest Retry-After that's waited for. Responses asking for longer aren't retried.
+	MaxRetryAfter time.Duration
+	// Concurrency is how many requests each host has in flight at once. 0 is unlimited.
+	Concurrency int
+	// RequestsPerSecond is how many requests are started per second for each host. 0 is unlimited.
+	RequestsPerSecond float64
+}
+
+// DefaultPolicy is the policy of --network-concurrency, --network-rps and --network-retries' defaults.
+func DefaultPolicy() Policy {
+	return Policy{
+		Retries:       config.DefaultNetworkRetries,
+		MinBackoff:    250 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		MaxRetryAfter: time.Minute,
+		Concurrency:   config.DefaultNetworkConcurrency,
+	}
+}
+
+// ConfigPolicy is DefaultPolicy with the limits in c.
+func ConfigPolicy(c *config.UserConfig) Policy {
+	policy := DefaultPolicy()
+	policy.Retries = c.NetworkRetries
+	policy.Concurrency = c.NetworkConcurrency
+	policy.RequestsPerSecond = c.NetworkRequestsPerSecond
+	return policy
+}
+
+// Client is shared by every request to the same hosts, since that's what their limits count.
+type Client struct {
+	Policy Policy
+	// hosts has a *host for each host requests are sent to
+	hosts sync.Map
+}
+
+func New(policy Policy) *Client {
+	return &Client{Policy: policy}
+}
+
+type host struct {
+	// slots has a value for each request in flight, or is nil when they're unlimited
+	slots chan struct{}
+
+	mutex sync.Mutex
+	// next is when the next request can start, to keep to RequestsPerSecond
+	next time.Time
+	// pausedUntil is when the host said to come back, after a 429 or a Retry-After
+	pausedUntil time.Time
+}
+
+func (c *Client) host(name string) *host {
+	if value, ok := c.hosts.Load(name); ok {
+		return value.(*host)
+	}
+
+	h := &host{}
+	if c.Policy.Concurrency > 0 {
+		h.slots = make(chan struct{}, c.Policy.Concurrency)
+	}
+
+	value, _ := c.hosts.LoadOrStore(name, h)
+	return value.(*host)
+}
+
+// acquire waits for a slot and for the host's rate limit.
+func (h *host) acquire(ctx context.Context, requestsPerSecond float64) error {
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	h.mutex.Lock()
+	start := time.Now()
+	if h.next.After(start) {
+		start = h.next
+	}
+	if h.pausedUntil.After(start) {
+		start = h.pausedUntil
+	}
+	if requestsPerSecond > 0 {
+		h.next = start.Add(time.Duration(float64(time.Second) / requestsPerSecond))
+	}
+	h.mutex.Unlock()
+
+	if err := sleep(ctx, time.Until(start)); err != nil {
+		h.release()
+		return err
+	}
+
+	return nil
+}
+
+func (h *host) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+func (h *host) pause(until time.Time) {
+	h.mutex.Lock()
+	if until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+	h.mutex.Unlock()
+}
+
+// Deadline is a minute from now, or sooner if ctx has an earlier deadline.
+func Deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(time.Minute)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+
+	return deadline
+}
+
+// Retryable is whether a response with statusCode might succeed if it's sent again.
+// 501 & 505 are about what the request is, so they won't.
+func Retryable(statusCode int) bool {
+	return statusCode == fasthttp.StatusTooManyRequests ||
+		(statusCode >= 500 && statusCode != fasthttp.StatusNotImplemented && statusCode != fasthttp.StatusHTTPVersionNotSupported)
+}
+
+// RetryAfter is how long resp asks to wait before sending its request again. It's false when resp doesn't say.
+func RetryAfter(resp *fasthttp.Response, now time.Time) (time.Duration, bool) {
+	return parseRetryAfter(string(resp.Header.Peek(fasthttp.HeaderRetryAfter)), now)
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	// It's either a number of seconds, or an HTTP date
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := fasthttp.ParseHTTPDate([]byte(value)); err == nil {
+		wait = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if wait < 0 {
+		return 0, true
+	}
+
+	return wait, true
+}
+
+// backoff is the jittered wait before retry number attempt, starting at 0.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.Policy.MinBackoff
+	for i := 0; i < attempt && wait < c.Policy.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > c.Policy.MaxBackoff {
+		wait = c.Policy.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// Half of it is fixed, so retries still back off when the jitter is small
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Do sends req with client, waiting for the limits of req's host.
+// Network errors, 429s and 5xxs are retried until Policy.Retries runs out or ctx is done. After that, resp is the last attempt's response, which the caller checks like any other.
+// A nil Client sends req once, without limits.
+func (c *Client) Do(ctx context.Context, client *fasthttp.Client, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if c == nil {
+		return client.DoDeadline(req, resp, Deadline(ctx))
+	}
+
+	return c.retry(ctx, string(req.URI().Host()), func() (int, string, error) {
+		err := client.DoDeadline(req, resp, Deadline(ctx))
+		return resp.StatusCode(), string(resp.Header.Peek(fasthttp.HeaderRetryAfter)), err
+	}, resp.Reset)
+}
+
+// DoHTTP is Do for net/http, for downloads that are streamed instead of read into memory. req can't have a body.
+// The caller closes the response's body, like with client.Do.
+func (c *Client) DoHTTP(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	if c == nil {
+		return client.Do(req)
+	}
+
+	var response *http.Response
+	err := c.retry(ctx, req.URL.Host, func() (int, string, error) {
+		var err error
+		response, err = client.Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+
+		return response.StatusCode, response.Header.Get(fasthttp.HeaderRetryAfter), nil
+	}, func() {
+		if response != nil {
+			response.Body.Close()
+		}
+	})
+
+	// ctx was done while waiting to retry
+	if err != nil && response != nil {
+		response.Body.Close()
+		return nil, err
+	}
+
+	return response, err
+}
+
+// retry calls send until it succeeds, or until it fails in a way that isn't retried. reset discards a response that's retried.
+func (c *Client) retry(ctx context.Context, hostName string, send func() (statusCode int, retryAfter string, err error), reset func()) error {
+	h := c.host(hostName)
+	for attempt := 0; ; attempt++ {
+		if err := h.acquire(ctx, c.Policy.RequestsPerSecond); err != nil {
+			return err
+		}
+
+		statusCode, retryAfterValue, err := send()
+		h.release()
+
+		if attempt >= c.Policy.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		if err == nil && !Retryable(statusCode) {
+			return nil
+		}
+
+		wait := c.backoff(attempt)
+		if err == nil {
+			retryAfter, ok := parseRetryAfter(retryAfterValue, time.Now())
+			if ok && retryAfter > c.Policy.MaxRetryAfter {
+				return nil
+			} else if ok {
+				wait = retryAfter
+			}
+
+			// Everything else sent to a host that's rate limiting would be too
+			if ok || statusCode == fasthttp.StatusTooManyRequests {
+				h.pause(time.Now().Add(wait))
+			}
+		}
+
+		// Waiting past the deadline would only fail anyway, so the caller gets this attempt
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		if sleepErr := sleep(ctx, wait); sleepErr != nil {
+			return sleepErr
+		}
+
+		reset()
+	}
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,239 @@
+package httpclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func testPolicy() httpclient.Policy {
+	return httpclient.Policy{
+		Retries:       3,
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    10 * time.Millisecond,
+		MaxRetryAfter: 5 * time.Second,
+	}
+}
+
+// get sends one request through client, returning its status code and how many requests the server saw.
+func get(t *testing.T, client *httpclient.Client, handler func(w http.ResponseWriter, attempt int)) (int, int, error) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, int(atomic.AddInt32(&requests, 1)))
+	}))
+	t.Cleanup(server.Close)
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(server.URL)
+	err := client.Do(context.Background(), &fasthttp.Client{}, req, resp)
+	return resp.StatusCode(), int(atomic.LoadInt32(&requests)), err
+}
+
+func TestDoRetries(t *testing.T) {
+	client := httpclient.New(testPolicy())
+
+	statusCode, requests, err := get(t, client, func(w http.ResponseWriter, attempt int) {
+		if attempt < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, statusCode)
+	assert.Equal(t, 3, requests)
+
+	// The caller gets the last response once retries run out
+	statusCode, requests, err = get(t, client, func(w http.ResponseWriter, attempt int) {
+		w.WriteHeader(500)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 500, statusCode)
+	assert.Equal(t, 4, requests)
+
+	for _, code := range []int{404, 403, 501} {
+		statusCode, requests, err = get(t, client, func(w http.ResponseWriter, attempt int) {
+			w.WriteHeader(code)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, code, statusCode)
+		assert.Equal(t, 1, requests, code)
+	}
+
+	// A nil client sends it once
+	statusCode, requests, err = get(t, nil, func(w http.ResponseWriter, attempt int) {
+		w.WriteHeader(503)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 503, statusCode)
+	assert.Equal(t, 1, requests)
+}
+
+func TestDoRetryAfter(t *testing.T) {
+	client := httpclient.New(testPolicy())
+
+	start := time.Now()
+	statusCode, requests, err := get(t, client, func(w http.ResponseWriter, attempt int) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(429)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, statusCode)
+	assert.Equal(t, 2, requests)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+
+	// Waiting longer than MaxRetryAfter isn't worth it
+	statusCode, requests, err = get(t, client, func(w http.ResponseWriter, attempt int) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(503)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 503, statusCode)
+	assert.Equal(t, 1, requests)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	_, ok := httpclient.RetryAfter(resp, now)
+	assert.False(t, ok)
+
+	resp.Header.Set("Retry-After", "120")
+	wait, ok := httpclient.RetryAfter(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, wait)
+
+	resp.Header.Set("Retry-After", "Mon, 01 Mar 2021 12:00:30 GMT")
+	wait, ok = httpclient.RetryAfter(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+
+	resp.Header.Set("Retry-After", "Mon, 01 Mar 2021 11:00:00 GMT")
+	wait, ok = httpclient.RetryAfter(resp, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), wait)
+
+	resp.Header.Set("Retry-After", "soon")
+	_, ok = httpclient.RetryAfter(resp, now)
+	assert.False(t, ok)
+}
+
+func TestDoCancelledWhileBackingOff(t *testing.T) {
+	policy := testPolicy()
+	policy.MinBackoff = time.Minute
+	policy.MaxBackoff = time.Minute
+	client := httpclient.New(policy)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(502)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := client.Do(ctx, &fasthttp.Client{}, req, resp)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+}
+
+func TestDoHostLimits(t *testing.T) {
+	policy := testPolicy()
+	policy.Concurrency = 2
+	policy.RequestsPerSecond = 50
+	client := httpclient.New(policy)
+
+	var lock sync.Mutex
+	var inFlight, maxInFlight int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	httpClient := &fasthttp.Client{}
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+
+			req.SetRequestURI(server.URL)
+			assert.Nil(t, client.Do(context.Background(), httpClient, req, resp))
+			assert.Equal(t, 200, resp.StatusCode())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, maxInFlight)
+	// 10 requests at 50 per second are 9 gaps of 20ms
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(180*time.Millisecond))
+}
+
+func TestDoHTTP(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(502)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	assert.Nil(t, err)
+
+	response, err := httpclient.New(testPolicy()).DoHTTP(context.Background(), &http.Client{}, req)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,57 @@
+package installer
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+)
+
+// binEntries returns the name & file of each bin of the package installed at packagePath.
+// Without a "bin" field, every file in directories.bin is one. Names that aren't a plain file name, and files outside the package, are left out.
+func binEntries(manifest *lockfile.JavascriptPackageManifestPartial, packagePath string) ([]string, []string) {
+	names, files := manifest.BinKeys, manifest.BinValues
+	if len(names) == 0 && len(manifest.BinDirectory) > 0 {
+		entries, _ := os.ReadDir(filepath.Join(packagePath, filepath.FromSlash(manifest.BinDirectory)))
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+				files = append(files, path.Join(manifest.BinDirectory, entry.Name()))
+			}
+		}
+	}
+
+	binNames := make([]string, 0, len(names))
+	binFiles := make([]string, 0, len(names))
+	for i, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			continue
+		}
+
+		file := filepath.Join(packagePath, filepath.FromSlash(files[i]))
+		if !strings.HasPrefix(file, packagePath+string(filepath.Separator)) {
+			continue
+		}
+
+		binNames = append(binNames, name)
+		binFiles = append(binFiles, file)
+	}
+
+	return binNames, binFiles
+}
+
+// linkBin makes file executable, and symlinks binPath to it.
+func linkBin(file string, binPath string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Chmod(file, info.Mode()|0111); err != nil {
+		return err
+	}
+
+	return symlinkPackage(file, binPath)
+}
@@ -0,0 +1,71 @@
+package installer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallLinksBins(t *testing.T) {
+	i, root := newInstaller(t)
+
+	writeFile(t, root, "libs/single/package.json", `{"name": "@scope/single", "version": "1.0.0", "bin": "./cli.js"}`)
+	writeFile(t, root, "libs/single/cli.js", "#!/usr/bin/env node\n")
+	writeFile(t, root, "libs/folder/package.json", `{"name": "folder", "version": "1.0.0", "directories": {"bin": "./scripts"}}`)
+	writeFile(t, root, "libs/folder/scripts/one", "#!/bin/sh\n")
+	writeFile(t, root, "libs/folder/scripts/two", "#!/bin/sh\n")
+	writeFile(t, root, "libs/evil/package.json", `{"name": "evil", "version": "1.0.0", "bin": {"../x": "cli.js", "nested/name": "cli.js", "outside": "../../../outside.js", "good": "cli.js"}}`)
+	writeFile(t, root, "libs/evil/cli.js", "#!/usr/bin/env node\n")
+	writeFile(t, root, "outside.js", "#!/usr/bin/env node\n")
+
+	manifest := lockfile.JavascriptPackageManifest{
+		Name:             []string{"@scope/single", "evil", "folder"},
+		Version:          []string{"file:libs/single", "file:libs/evil", "file:libs/folder"},
+		Count:            3,
+		DependencyIndex:  []uint{0, 0, 0},
+		RootDependencies: []uint{0, 1, 2},
+		Integrity:        []string{"", "", ""},
+		Os:               []string{"", "", ""},
+		Cpu:              []string{"", "", ""},
+		Libc:             []string{"", "", ""},
+	}
+
+	i.EnqueueLockfile(&manifest)
+	i.Waiter.Wait()
+	i.Install(&manifest)
+
+	binFolder := filepath.Join(i.NodeModulesFolder, ".bin")
+	for name, file := range map[string]string{
+		"single": filepath.Join(i.NodeModulesFolder, "@scope", "single", "cli.js"),
+		"one":    filepath.Join(i.NodeModulesFolder, "folder", "scripts", "one"),
+		"two":    filepath.Join(i.NodeModulesFolder, "folder", "scripts", "two"),
+		"good":   filepath.Join(i.NodeModulesFolder, "evil", "cli.js"),
+	} {
+		target, err := filepath.EvalSymlinks(filepath.Join(binFolder, name))
+		if assert.Nil(t, err, name) {
+			expected, _ := filepath.EvalSymlinks(file)
+			assert.Equal(t, expected, target, name)
+		}
+
+		info, err := os.Stat(file)
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, os.FileMode(0111), info.Mode().Perm()&0111, name)
+		}
+	}
+
+	// Bin names can't leave .bin, and files can't leave the package
+	assert.NoFileExists(t, filepath.Join(i.NodeModulesFolder, "x"))
+	assert.NoDirExists(t, filepath.Join(binFolder, "nested"))
+	assert.NoFileExists(t, filepath.Join(binFolder, "outside"))
+
+	entries, err := os.ReadDir(binFolder)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 4)
+
+	info, err := os.Stat(filepath.Join(root, "outside.js"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
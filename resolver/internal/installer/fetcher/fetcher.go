@@ -5,10 +5,15 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/git"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/internal/job"
+	"github.com/jarred-sumner/devserverless/resolver/internal/tarball"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	"github.com/mholt/archiver/v3"
 )
@@ -19,6 +24,16 @@ type PackageArchive struct {
 	Source     string
 	Target     string
 	SourceType lockfile.PackageProvider
+	// GitCacheDir is where git sources are mirrored
+	GitCacheDir string
+	// TarballCacheDir is where .tgz sources are downloaded
+	TarballCacheDir string
+	// Integrity is the Subresource Integrity the tarball must match. Empty skips checking it.
+	Integrity string
+	// Authorization is sent with the download, from .npmrc
+	Authorization string
+	// HTTP retries failed downloads. It can be nil.
+	HTTP *httpclient.Client
 }
 
 type PackageArchiveJob struct {
@@ -29,24 +44,55 @@ type PackageArchiveJob struct {
 	Ctx *context.Context
 }
 
-func NewPackageArchive(manifest *lockfile.JavascriptPackageManifestPartial, target string) PackageArchive {
+// NewPackageArchive downloads npm packages from registrar, or their scope's registry in npmrc. Both can be nil.
+func NewPackageArchive(manifest *lockfile.JavascriptPackageManifestPartial, target string, registrar lockfile.Registrar, npmrc *config.Npmrc) PackageArchive {
+	// npm aliases download the package they point to
+	name, version := lockfile.NpmAliasTarget(manifest.Name, manifest.Version.Tag)
+	if manifest.Provider == lockfile.PackageProviderGit || manifest.Provider == lockfile.PackageProviderTgz {
+		// Git sources are archived from their pinned commit, and tarballs are already a URL
+		return PackageArchive{
+			Source:        manifest.Version.Tag,
+			Target:        target,
+			SourceType:    manifest.Provider,
+			Integrity:     manifest.Integrity,
+			Authorization: npmrc.Authorization(manifest.Version.Tag, ""),
+		}
+	}
+
+	registrar = lockfile.ScopeRegistrar(registrar, npmrc, name)
+	source := registrar.TarballURL(name, version)
 	return PackageArchive{
-		Source:     manifest.Provider.ToArchiveURL(manifest.Name, manifest.Version.Tag),
-		Target:     target,
-		SourceType: manifest.Provider,
+		Source:        source,
+		Target:        target,
+		SourceType:    manifest.Provider,
+		Integrity:     manifest.Integrity,
+		Authorization: npmrc.Authorization(source, registrar.String()),
 	}
 }
 
 func (p *PackageArchiveJob) Fetch() error {
 	switch p.Input.SourceType {
-	case lockfile.PackageProviderTgz, lockfile.PackageProviderNpm:
+	case lockfile.PackageProviderTgz:
 		{
+			return p.fetchCachedTGZ()
+		}
+	case lockfile.PackageProviderNpm:
+		{
+			// A local directory registrar's tarballs
+			if path, ok := lockfile.FilePath(p.Input.Source); ok {
+				return p.extractFile(path)
+			}
+
 			if client == nil {
-				client = &http.Client{}
+				client = tarball.DefaultClient
 			}
 
 			return p.fetchTGZ()
 		}
+	case lockfile.PackageProviderGit:
+		{
+			return p.fetchGit()
+		}
 	}
 
 	return errors.New("package source is not implemented yet")
@@ -74,14 +120,85 @@ func (p *PackageArchiveJob) fetchTGZ() error {
 		return err
 	}
 
-	response, err := client.Do(request)
+	if len(p.Input.Authorization) > 0 {
+		request.Header.Set("Authorization", p.Input.Authorization)
+	}
+
+	response, err := p.Input.HTTP.DoHTTP(*p.Ctx, client, request)
 
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
+
+	// Otherwise a registry's error page would be extracted, like a 401 from a private registry without credentials
+	if response.StatusCode != http.StatusOK {
+		return &tarball.HTTPError{URL: p.Input.Source, StatusCode: response.StatusCode}
+	}
+
+	return p.extractVerifiedTGZ(response.Body)
+}
+
+// fetchCachedTGZ extracts a tarball dependency from the tarball cache, which resolving it already downloaded to.
+func (p *PackageArchiveJob) fetchCachedTGZ() error {
+	cache := tarball.Cache{Dir: p.Input.TarballCacheDir, HTTP: p.Input.HTTP, Authorization: p.Input.Authorization}
+	tarballPath, _, err := cache.Fetch(*p.Ctx, p.Input.Source)
+	if err != nil {
+		return err
+	}
+
+	return p.extractFile(tarballPath)
+}
+
+func (p *PackageArchiveJob) extractFile(tarballPath string) error {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return p.extractVerifiedTGZ(file)
+}
+
+func (p *PackageArchiveJob) fetchGit() error {
+	url, commit := lockfile.SplitGitURL(p.Input.Source)
+	repo, err := git.Open(*p.Ctx, p.Input.GitCacheDir, url)
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(repo.Archive(*p.Ctx, commit, writer))
+	}()
+	defer reader.Close()
+
+	return p.extractTGZ(reader)
+}
+
+// extractVerifiedTGZ is extractTGZ, checking body against Input.Integrity while it's extracted.
+// On a mismatch, it returns a *tarball.IntegrityError and whatever was extracted to Target must not be used.
+func (p *PackageArchiveJob) extractVerifiedTGZ(body io.Reader) error {
+	if len(p.Input.Integrity) == 0 {
+		return p.extractTGZ(body)
+	}
+
+	verifier, err := tarball.NewVerifier(body, p.Input.Integrity)
+	if err != nil {
+		return err
+	}
+
+	if err := p.extractTGZ(verifier); err != nil {
+		return err
+	}
+
+	return verifier.Verify(p.Input.Source)
+}
 
+func (p *PackageArchiveJob) extractTGZ(body io.Reader) error {
+	var err error
 	tar := archiver.NewTarGz()
-	err = tar.Open(response.Body, 0)
+	err = tar.Open(body, 0)
 	if err != nil {
 		return err
 	}
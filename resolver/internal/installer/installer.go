@@ -2,15 +2,21 @@ package installer
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/gammazero/workerpool"
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/internal/installer/copier"
 	"github.com/jarred-sumner/devserverless/resolver/internal/installer/fetcher"
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer/layout"
 	"github.com/jarred-sumner/devserverless/resolver/internal/job"
+	"github.com/jarred-sumner/devserverless/resolver/internal/tarball"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 )
 
@@ -43,6 +49,7 @@ FailExtractionError
 FailPermissionError
 SuccessAlreadyExists
 SuccessComplete
+FailIntegrityError
 )
 */
 type InstallPackageStatusReason byte
@@ -65,28 +72,52 @@ type InstallPackageJob struct {
 
 	Fetcher *fetcher.PackageArchiveJob
 	Copier  *copier.CopyJob
+
+	// fetched is closed once the package is in SourcePath, or failed to get there
+	fetched chan struct{}
+	// fromLockfile is whether Manifest was rebuilt from a lockfile, without its bins & scripts
+	fromLockfile bool
 }
 
 type PackageInstaller struct {
-	Jobs []InstallPackageJob
-
-	NodeModulesFolder string
-	CacheFolder       string
-	TempFolder        string
-	Keys              *lockfile.PackageKeysMap
+	Jobs []*InstallPackageJob
+
+	NodeModulesFolder  string
+	CacheFolder        string
+	TempFolder         string
+	GitCacheFolder     string
+	TarballCacheFolder string
+	Keys               *lockfile.PackageKeysMap
+	// Packages for other platforms are skipped. The lockfile keeps them, so it's the same on every platform.
+	Platform lockfile.Platform
+	// Empty is config.LayoutHoisted
+	Layout config.Layout
+	// Plan is where Install put every package
+	Plan layout.Plan
+	// Offline fails packages that aren't in CacheFolder instead of downloading them
+	Offline bool
+	// Npmrc has the registry of each scope, and the credentials for downloading from them. It can be nil.
+	Npmrc *config.Npmrc
+	// Registrar is where packages are downloaded from, unless their scope has its own registry in Npmrc. Nil is npm's registry.
+	Registrar lockfile.Registrar
+	// HTTP retries failed downloads and limits how many each host gets. It can be nil.
+	HTTP *httpclient.Client
 
 	Ctx *context.Context
 
 	DownloadWorkers *workerpool.WorkerPool
 	CopyWorkers     *workerpool.WorkerPool
 	Waiter          *sync.WaitGroup
+
+	// fetches has the job fetching each enqueued package, by key
+	fetches *sync.Map
 }
 
 func NewPackageInstaller(BaseFolder string, cacheFolder string, ctx *context.Context, waiter *sync.WaitGroup) (PackageInstaller, error) {
 	TempFolder, err := ioutil.TempDir(os.TempDir(), "duckpkgs")
 
 	installer := PackageInstaller{
-		Jobs:              make([]InstallPackageJob, 0, 100),
+		Jobs:              make([]*InstallPackageJob, 0, 100),
 		TempFolder:        TempFolder,
 		NodeModulesFolder: filepath.Join(BaseFolder, "node_modules"),
 		CacheFolder:       cacheFolder,
@@ -95,6 +126,7 @@ func NewPackageInstaller(BaseFolder string, cacheFolder string, ctx *context.Con
 		Waiter:            waiter,
 		DownloadWorkers:   workerpool.New(10),
 		CopyWorkers:       workerpool.New(10),
+		fetches:           &sync.Map{},
 	}
 
 	os.MkdirAll(installer.NodeModulesFolder, 0700)
@@ -106,17 +138,23 @@ func NewPackageInstaller(BaseFolder string, cacheFolder string, ctx *context.Con
 	return installer, err
 }
 
+// Enqueue starts fetching a package into the cache while the rest of the tree resolves. Install puts it in node_modules.
 func (i *PackageInstaller) Enqueue(manifest *lockfile.JavascriptPackageManifestPartial) {
+	if !i.Platform.Supports(manifest) {
+		return
+	}
+
 	key := lockfile.NewPackageManifestKey(manifest.Name, manifest.Version.Tag)
 	if _, exists := i.Keys.Load(key); exists {
 		return
 	}
 	i.Keys.Store(key, true)
-	i.enqueue(manifest, key)
+	i.enqueue(manifest, key, false)
 }
 
-func (i *PackageInstaller) DestinationPathForManifest(manifest *lockfile.JavascriptPackageManifestPartial) string {
-	s, _ := filepath.Abs(filepath.Join(i.NodeModulesFolder, manifest.Name))
+// DestinationPathForPlacement is where a package goes, given its path from the layout.
+func (i *PackageInstaller) DestinationPathForPlacement(placementPath string) string {
+	s, _ := filepath.Abs(filepath.Join(filepath.Dir(i.NodeModulesFolder), filepath.FromSlash(placementPath)))
 	return s
 }
 
@@ -135,6 +173,19 @@ func (i *PackageInstaller) IsPackageSourced(sourcePath string) bool {
 	return !os.IsNotExist(e)
 }
 
+// Each package in the cache has a file beside it with the integrity it was verified against when it was downloaded
+const integritySuffix = ".integrity"
+
+// isSourceVerified is whether the package cached at sourcePath was verified against integrity. Without an integrity, there's nothing to check.
+func isSourceVerified(sourcePath string, integrity string) bool {
+	if len(integrity) == 0 {
+		return true
+	}
+
+	verified, err := os.ReadFile(sourcePath + integritySuffix)
+	return err == nil && string(verified) == integrity
+}
+
 func (i *PackageInstaller) enqueueInstall(job *InstallPackageJob) {
 	job.Copier = &copier.CopyJob{}
 	// sourcePath := job.SourcePath
@@ -155,11 +206,14 @@ func (i *PackageInstaller) enqueueInstall(job *InstallPackageJob) {
 }
 func (i *PackageInstaller) enqueueFetch(installer *InstallPackageJob) {
 	installer.Fetcher = &fetcher.PackageArchiveJob{
-		Input:  fetcher.NewPackageArchive(installer.Manifest, installer.TempPath),
+		Input:  fetcher.NewPackageArchive(installer.Manifest, installer.TempPath, i.Registrar, i.Npmrc),
 		Status: job.StatusQueued,
 		Error:  nil,
 		Ctx:    i.Ctx,
 	}
+	installer.Fetcher.Input.GitCacheDir = i.GitCacheFolder
+	installer.Fetcher.Input.TarballCacheDir = i.TarballCacheFolder
+	installer.Fetcher.Input.HTTP = i.HTTP
 
 	i.DownloadWorkers.Submit(func() {
 		installer := installer
@@ -171,9 +225,35 @@ func (i *PackageInstaller) enqueueFetch(installer *InstallPackageJob) {
 
 	if err != nil {
 		installer.Status = InstallPackageStatusFail
+		var integrityError *tarball.IntegrityError
+		if errors.As(err, &integrityError) {
+			installer.StatusReason = InstallPackageStatusReasonFailIntegrityError
+		}
+
+		// Never let a partial or tampered download into the cache
+		os.RemoveAll(installer.TempPath)
 		return
 	}
 
+	// Moved into the cache here instead of while copying, since a package can be copied to several places at once.
+	// An npm alias and the package it points to can both fetch it, so it's fine if the other one got there first.
+	// Tarball URLs have slashes in their key, so their folder can be nested
+	err = os.MkdirAll(filepath.Dir(installer.SourcePath), 0755)
+	// A cached copy that wasn't verified against this integrity is replaced
+	if err == nil && i.IsPackageSourced(installer.SourcePath) && !isSourceVerified(installer.SourcePath, installer.Manifest.Integrity) {
+		err = os.RemoveAll(installer.SourcePath)
+	}
+	if err == nil {
+		err = os.Rename(installer.TempPath, installer.SourcePath)
+	}
+	if err == nil && len(installer.Manifest.Integrity) > 0 {
+		err = os.WriteFile(installer.SourcePath+integritySuffix, []byte(installer.Manifest.Integrity), 0644)
+	}
+
+	if err != nil && !i.IsPackageSourced(installer.SourcePath) {
+		installer.Error = err
+		installer.Status = InstallPackageStatusFail
+	}
 }
 
 type PackageInstallerBox struct {
@@ -184,47 +264,260 @@ func (b PackageInstallerBox) Enqueue(manifest *lockfile.JavascriptPackageManifes
 	b.Installer.Enqueue(manifest)
 }
 
-func (i *PackageInstaller) enqueue(manifest *lockfile.JavascriptPackageManifestPartial, key string) {
-	sourcePath := i.SourcePathForManifest(key)
-	destinationPath := i.DestinationPathForManifest(manifest)
-	installJob := InstallPackageJob{
-		Manifest:        manifest,
-		DestinationPath: destinationPath,
-		TempPath:        i.TempPathForManifest(key),
-		SourcePath:      sourcePath,
-		Step:            InstallPackageStepFetchQueued,
-		Status:          InstallPackageStatusWaiting,
-		StatusReason:    InstallPackageStatusReasonWaiting,
-	}
-
-	// 1. Determine what step is necessary.
-	//    If it exists in the foler cache, then we don't need to download it.
-	if i.IsPackageSourced(sourcePath) {
-		installJob.Step = InstallPackageStepCopyQueued
-		installJob.CopyChan = make(chan error)
+// Install puts every package enqueued while resolving where Layout places it, links their bins, and waits for them.
+// Packages nested in another package are installed after it, since installing a package replaces its folder.
+func (i *PackageInstaller) Install(manifest *lockfile.JavascriptPackageManifest) {
+	plan := layout.Hoisted(manifest)
+	if i.Layout == config.LayoutIsolated {
+		plan = layout.Isolated(manifest)
+	}
+	i.Plan = plan
+
+	level := &sync.WaitGroup{}
+	depth := 0
+
+	for _, placement := range plan.Placements {
+		if placement.Depth != depth {
+			level.Wait()
+			depth = placement.Depth
+		}
+
+		// Workspace packages & packages for other platforms are never enqueued
+		value, ok := i.fetches.Load(lockfile.NewPackageManifestKey(manifest.Name[placement.Index], manifest.Version[placement.Index]))
+		if !ok {
+			continue
+		}
+
+		fetch := value.(*InstallPackageJob)
+		installJob := &InstallPackageJob{
+			Manifest:        fetch.Manifest,
+			DestinationPath: i.DestinationPathForPlacement(placement.Path),
+			SourcePath:      fetch.SourcePath,
+			Step:            InstallPackageStepCopyQueued,
+			Status:          InstallPackageStatusWaiting,
+			StatusReason:    InstallPackageStatusReasonWaiting,
+			CopyChan:        make(chan error),
+		}
 		i.Jobs = append(i.Jobs, installJob)
-		i.Waiter.Add(1)
-		go func(i *PackageInstaller, installJob *InstallPackageJob) {
-			i.enqueueInstall(installJob)
-			i.Waiter.Done()
-		}(i, &installJob)
-	} else {
-		installJob.Step = InstallPackageStepFetchQueued
-		installJob.FetchChan = make(chan error)
+
+		level.Add(1)
+		go func(fetch *InstallPackageJob, installJob *InstallPackageJob) {
+			defer level.Done()
+			<-fetch.fetched
+
+			if fetch.Error != nil {
+				installJob.Error = fetch.Error
+				installJob.Status = InstallPackageStatusFail
+				installJob.StatusReason = fetch.StatusReason
+				close(installJob.CopyChan)
+				return
+			}
+
+			i.install(installJob)
+		}(fetch, installJob)
+	}
+
+	level.Wait()
+
+	for _, link := range plan.Links {
+		installJob := &InstallPackageJob{
+			DestinationPath: i.DestinationPathForPlacement(link.Path),
+			SourcePath:      i.DestinationPathForPlacement(link.Target),
+			Step:            InstallPackageStepCopyQueued,
+			Status:          InstallPackageStatusWaiting,
+			StatusReason:    InstallPackageStatusReasonWaiting,
+		}
+
+		name, version := manifest.Name[link.Index], manifest.Version[link.Index]
+		if value, ok := i.fetches.Load(lockfile.NewPackageManifestKey(name, version)); ok {
+			installJob.Manifest = value.(*InstallPackageJob).Manifest
+		} else if _, ok := lockfile.WorkspacePath(version); ok {
+			installJob.Manifest = &lockfile.JavascriptPackageManifestPartial{Name: name, Provider: lockfile.PackageProviderWorkspace}
+		} else {
+			// Packages for other platforms aren't there to link to
+			continue
+		}
 		i.Jobs = append(i.Jobs, installJob)
-		i.Waiter.Add(1)
-		go func(i *PackageInstaller, installJob *InstallPackageJob) {
-			i.enqueueFetch(installJob)
-
-			if installJob.Error == nil {
-				if installJob.CopyChan == nil {
-					installJob.CopyChan = make(chan error)
-				}
-				i.enqueueInstall(installJob)
+
+		level.Add(1)
+		go func(installJob *InstallPackageJob) {
+			defer level.Done()
+			installJob.Error = symlinkPackage(installJob.SourcePath, installJob.DestinationPath)
+			if installJob.Error != nil {
+				installJob.Status = InstallPackageStatusFail
+			} else {
+				installJob.Status = InstallPackageStatusSuccess
+			}
+		}(installJob)
+	}
+
+	level.Wait()
+
+	// One at a time, so when two packages have a bin with the same name, the same one always wins
+	for _, bin := range plan.Bins {
+		value, ok := i.fetches.Load(lockfile.NewPackageManifestKey(manifest.Name[bin.Index], manifest.Version[bin.Index]))
+		if !ok {
+			continue
+		}
+
+		fetch := value.(*InstallPackageJob)
+		names, files := binEntries(fetch.Manifest, i.DestinationPathForPlacement(bin.Package))
+		for j, name := range names {
+			installJob := &InstallPackageJob{
+				Manifest:        fetch.Manifest,
+				DestinationPath: filepath.Join(i.DestinationPathForPlacement(bin.Folder), name),
+				SourcePath:      files[j],
+				Step:            InstallPackageStepCopyQueued,
+				Status:          InstallPackageStatusSuccess,
+				StatusReason:    InstallPackageStatusReasonWaiting,
 			}
-			i.Waiter.Done()
-		}(i, &installJob)
+			i.Jobs = append(i.Jobs, installJob)
 
+			if installJob.Error = linkBin(installJob.SourcePath, installJob.DestinationPath); installJob.Error != nil {
+				installJob.Status = InstallPackageStatusFail
+			}
+		}
 	}
+}
 
+// install puts a fetched package at its DestinationPath. "link:" dependencies are symlinked, and everything else is copied.
+func (i *PackageInstaller) install(installJob *InstallPackageJob) {
+	if installJob.Manifest.Provider == lockfile.PackageProviderDisk {
+		if _, link := lockfile.ParseLocalPath(installJob.Manifest.Version.Tag); link {
+			installJob.Error = symlinkPackage(installJob.SourcePath, installJob.DestinationPath)
+			close(installJob.CopyChan)
+			if installJob.Error != nil {
+				installJob.Status = InstallPackageStatusFail
+			} else {
+				installJob.Status = InstallPackageStatusSuccess
+			}
+			return
+		}
+	}
+
+	if err := freeDestination(installJob.DestinationPath); err != nil {
+		installJob.Error = err
+		installJob.Status = InstallPackageStatusFail
+		close(installJob.CopyChan)
+		return
+	}
+
+	i.enqueueInstall(installJob)
+}
+
+// freeDestination removes whatever was installed at destinationPath before, and makes sure its parent folder exists.
+func freeDestination(destinationPath string) error {
+	if err := os.RemoveAll(destinationPath); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(filepath.Dir(destinationPath), 0755)
+}
+
+// symlinkPackage replaces destinationPath with a relative symlink to sourcePath, so edits to sourcePath show up right away.
+func symlinkPackage(sourcePath string, destinationPath string) error {
+	if err := freeDestination(destinationPath); err != nil {
+		return err
+	}
+
+	target, err := filepath.Rel(filepath.Dir(destinationPath), sourcePath)
+	if err != nil {
+		target = sourcePath
+	}
+
+	return os.Symlink(target, destinationPath)
+}
+
+// enqueue starts getting a package into SourcePath. Local packages are already there.
+func (i *PackageInstaller) enqueue(manifest *lockfile.JavascriptPackageManifestPartial, key string, fromLockfile bool) {
+	fetch := &InstallPackageJob{
+		Manifest:     manifest,
+		Step:         InstallPackageStepCopyQueued,
+		Status:       InstallPackageStatusWaiting,
+		StatusReason: InstallPackageStatusReasonWaiting,
+		fetched:      make(chan struct{}),
+		fromLockfile: fromLockfile,
+	}
+	i.fetches.Store(key, fetch)
+
+	if manifest.Provider == lockfile.PackageProviderDisk {
+		localPath, _ := lockfile.ParseLocalPath(manifest.Version.Tag)
+		fetch.SourcePath, _ = filepath.Abs(filepath.Join(filepath.Dir(i.NodeModulesFolder), filepath.FromSlash(localPath)))
+		i.fetchDone(fetch)
+		return
+	}
+
+	// An npm alias shares the cached download of the package it points to
+	archiveKey := lockfile.NewPackageManifestKey(lockfile.NpmAliasTarget(manifest.Name, manifest.Version.Tag))
+	fetch.SourcePath = i.SourcePathForManifest(archiveKey)
+
+	// If it exists in the foler cache, then we don't need to download it.
+	// Unless it was verified against a different integrity than the lockfile has, since then it might not be the same package.
+	sourced := i.IsPackageSourced(fetch.SourcePath)
+	if sourced && isSourceVerified(fetch.SourcePath, manifest.Integrity) {
+		i.fetchDone(fetch)
+		return
+	}
+
+	if i.Offline && sourced {
+		fetch.Error = fmt.Errorf("%s in the cache at %s wasn't verified against its integrity %s, and --offline is set", key, i.CacheFolder, manifest.Integrity)
+		fetch.Status = InstallPackageStatusFail
+		fetch.StatusReason = InstallPackageStatusReasonFailIntegrityError
+		i.fetchDone(fetch)
+		return
+	} else if i.Offline {
+		fetch.Error = fmt.Errorf("%s isn't in the cache at %s, and --offline is set", key, i.CacheFolder)
+		fetch.Status = InstallPackageStatusFail
+		i.fetchDone(fetch)
+		return
+	}
+
+	fetch.Step = InstallPackageStepFetchQueued
+	fetch.TempPath = i.TempPathForManifest(key)
+	fetch.FetchChan = make(chan error)
+	i.Waiter.Add(1)
+	go func(i *PackageInstaller, fetch *InstallPackageJob) {
+		i.enqueueFetch(fetch)
+		i.fetchDone(fetch)
+		i.Waiter.Done()
+	}(i, fetch)
+}
+
+// fetchDone is called once a package is in SourcePath, or failed to get there.
+// Bins & install scripts aren't in the lockfile, so packages from it read them from their package.json.
+// So do packages resolved from a packument that has an install script, since packuments leave out scripts.
+func (i *PackageInstaller) fetchDone(fetch *InstallPackageJob) {
+	missingScripts := fetch.Manifest.HasPostInstall && len(fetch.Manifest.ScriptKeys) == 0
+	if (fetch.fromLockfile || missingScripts) && fetch.Error == nil {
+		if body, err := os.ReadFile(filepath.Join(fetch.SourcePath, "package.json")); err == nil {
+			if packageJSON, err := lockfile.NewJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, false); err == nil {
+				fetch.Manifest.BinKeys = packageJSON.BinKeys
+				fetch.Manifest.BinValues = packageJSON.BinValues
+				fetch.Manifest.BinDirectory = packageJSON.BinDirectory
+				fetch.Manifest.ScriptKeys = packageJSON.ScriptKeys
+				fetch.Manifest.ScriptValues = packageJSON.ScriptValues
+				fetch.Manifest.HasPostInstall = packageJSON.HasPostInstall
+			}
+		}
+	}
+
+	close(fetch.fetched)
+}
+
+// EnqueueLockfile fetches every package in a lockfile without resolving anything, so Install can install it as is.
+func (i *PackageInstaller) EnqueueLockfile(manifest *lockfile.JavascriptPackageManifest) {
+	packages := manifest.Packages()
+	for index := range packages {
+		// Workspace packages are already in their folder
+		if packages[index].Provider == lockfile.PackageProviderWorkspace || !i.Platform.Supports(&packages[index]) {
+			continue
+		}
+
+		key := lockfile.NewPackageManifestKey(packages[index].Name, packages[index].Version.Tag)
+		if _, exists := i.Keys.Load(key); exists {
+			continue
+		}
+		i.Keys.Store(key, true)
+		i.enqueue(&packages[index], key, true)
+	}
 }
@@ -73,9 +73,11 @@ const (
 	InstallPackageStatusReasonSuccessAlreadyExists
 	// InstallPackageStatusReasonSuccessComplete is a InstallPackageStatusReason of type SuccessComplete.
 	InstallPackageStatusReasonSuccessComplete
+	// InstallPackageStatusReasonFailIntegrityError is a InstallPackageStatusReason of type FailIntegrityError.
+	InstallPackageStatusReasonFailIntegrityError
 )
 
-const _InstallPackageStatusReasonName = "WaitingFailHTTPError404FailHTTPError4xxFailHTTPError5xxFailHTTPErrorFailExtractionErrorFailPermissionErrorSuccessAlreadyExistsSuccessComplete"
+const _InstallPackageStatusReasonName = "WaitingFailHTTPError404FailHTTPError4xxFailHTTPError5xxFailHTTPErrorFailExtractionErrorFailPermissionErrorSuccessAlreadyExistsSuccessCompleteFailIntegrityError"
 
 var _InstallPackageStatusReasonMap = map[InstallPackageStatusReason]string{
 	0: _InstallPackageStatusReasonName[0:7],
@@ -87,6 +89,7 @@ var _InstallPackageStatusReasonMap = map[InstallPackageStatusReason]string{
 	6: _InstallPackageStatusReasonName[87:106],
 	7: _InstallPackageStatusReasonName[106:126],
 	8: _InstallPackageStatusReasonName[126:141],
+	9: _InstallPackageStatusReasonName[141:159],
 }
 
 // String implements the Stringer interface.
@@ -107,6 +110,7 @@ var _InstallPackageStatusReasonValue = map[string]InstallPackageStatusReason{
 	_InstallPackageStatusReasonName[87:106]:  6,
 	_InstallPackageStatusReasonName[106:126]: 7,
 	_InstallPackageStatusReasonName[126:141]: 8,
+	_InstallPackageStatusReasonName[141:159]: 9,
 }
 
 // ParseInstallPackageStatusReason attempts to convert a string to a InstallPackageStatusReason
@@ -0,0 +1,99 @@
+package installer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+// newInstaller returns an installer for a project in a temporary folder, and that folder.
+func newInstaller(t *testing.T) (*installer.PackageInstaller, string) {
+	root := t.TempDir()
+	ctx := context.Background()
+	i, err := installer.NewPackageInstaller(root, filepath.Join(root, "cache"), &ctx, &sync.WaitGroup{})
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(i.TempFolder) })
+
+	return &i, root
+}
+
+// writeFile writes body to path in root, creating its folders.
+func writeFile(t *testing.T, root string, path string, body string) {
+	path = filepath.Join(root, filepath.FromSlash(path))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(body), 0644))
+}
+
+func TestEnqueueLockfileSkipsOtherPlatforms(t *testing.T) {
+	i, root := newInstaller(t)
+	i.Platform = lockfile.Platform{OS: "linux", CPU: "x64", Libc: "glibc"}
+
+	writeFile(t, root, "libs/native-linux/package.json", `{"name": "native-linux", "version": "1.0.0", "os": ["linux"]}`)
+	writeFile(t, root, "libs/native-win32/package.json", `{"name": "native-win32", "version": "1.0.0", "os": ["win32"]}`)
+	writeFile(t, root, "libs/native-arm64/package.json", `{"name": "native-arm64", "version": "1.0.0", "cpu": ["arm64"]}`)
+
+	// The lockfile has every platform's packages
+	manifest := lockfile.JavascriptPackageManifest{
+		Name:             []string{"native-arm64", "native-linux", "native-win32"},
+		Version:          []string{"file:libs/native-arm64", "file:libs/native-linux", "file:libs/native-win32"},
+		Count:            3,
+		DependencyIndex:  []uint{0, 0, 0},
+		RootDependencies: []uint{0, 1, 2},
+		Integrity:        []string{"", "", ""},
+		Os:               []string{"", "linux", "win32"},
+		Cpu:              []string{"arm64", "", ""},
+		Libc:             []string{"", "", ""},
+	}
+
+	i.EnqueueLockfile(&manifest)
+	i.Waiter.Wait()
+	i.Install(&manifest)
+
+	assert.FileExists(t, filepath.Join(i.NodeModulesFolder, "native-linux", "package.json"))
+	assert.NoDirExists(t, filepath.Join(i.NodeModulesFolder, "native-win32"))
+	assert.NoDirExists(t, filepath.Join(i.NodeModulesFolder, "native-arm64"))
+}
+
+func TestEnqueueLockfileChecksCachedIntegrity(t *testing.T) {
+	manifest := lockfile.JavascriptPackageManifest{
+		Name:             []string{"cached", "unverified"},
+		Version:          []string{"1.0.0", "1.0.0"},
+		Count:            2,
+		DependencyIndex:  []uint{0, 0},
+		RootDependencies: []uint{0, 1},
+		Integrity:        []string{"sha512-cached", "sha512-unverified"},
+		Os:               []string{"", ""},
+		Cpu:              []string{"", ""},
+		Libc:             []string{"", ""},
+	}
+
+	i, _ := newInstaller(t)
+	i.Offline = true
+
+	cached := i.SourcePathForManifest(lockfile.NewPackageManifestKey("cached", "1.0.0"))
+	writeFile(t, cached, "package.json", `{"name": "cached", "version": "1.0.0"}`)
+	assert.Nil(t, os.WriteFile(cached+".integrity", []byte("sha512-cached"), 0644))
+
+	// Verified against a different integrity than the lockfile has, so it could be a different package
+	unverified := i.SourcePathForManifest(lockfile.NewPackageManifestKey("unverified", "1.0.0"))
+	writeFile(t, unverified, "package.json", `{"name": "unverified", "version": "1.0.0"}`)
+	assert.Nil(t, os.WriteFile(unverified+".integrity", []byte("sha512-other"), 0644))
+
+	i.EnqueueLockfile(&manifest)
+	i.Waiter.Wait()
+	i.Install(&manifest)
+
+	assert.FileExists(t, filepath.Join(i.NodeModulesFolder, "cached", "package.json"))
+	assert.NoDirExists(t, filepath.Join(i.NodeModulesFolder, "unverified"))
+	for _, job := range i.Jobs {
+		if job.Manifest.Name == "unverified" {
+			assert.Equal(t, installer.InstallPackageStatusReasonFailIntegrityError, job.StatusReason)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package layout
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+)
+
+// Placement is one copy of a package in node_modules.
+type Placement struct {
+	// Index is the package's index in the lockfile
+	Index uint
+	// Path is where it goes, relative to the folder with the root package.json, like "node_modules/a/node_modules/b"
+	Path string
+	// Depth is how many packages it's nested in. Top-level packages are 0.
+	Depth int
+}
+
+// Link is a symlink at Path to the package placed at Target. Both are relative like Placement.Path.
+type Link struct {
+	// Index is the linked package's index in the lockfile
+	Index  uint
+	Path   string
+	Target string
+}
+
+// Bin is a package whose bins are linked into a .bin folder.
+type Bin struct {
+	// Index is the package's index in the lockfile
+	Index uint
+	// Package is where the package is, like "node_modules/a"
+	Package string
+	// Folder is where its bins go, like "node_modules/.bin"
+	Folder string
+}
+
+// Plan is where every package in a lockfile goes. Packages are always after the packages they're nested in.
+type Plan struct {
+	Placements []Placement
+	// Links are made once every package is placed
+	Links []Link
+	// Bins are linked last
+	Bins []Bin
+}
+
+// StoreFolder is the folder in node_modules the isolated layout keeps every package in.
+const StoreFolder = ".duck"
+
+var storeNameReplacer = strings.NewReplacer("/", "+", "\\", "+", ":", "+")
+
+type folder struct {
+	parent   int
+	index    uint
+	path     string
+	children map[string]uint
+}
+
+// Hoisted plans an npm-style node_modules, where each package sees the versions it resolved to.
+// The top level gets the version from TopLevelIndices, and any package that resolved to another version gets its own copy in node_modules/<parent>/node_modules.
+// Workspace packages stay in their own folder, with a symlink to it in node_modules, and their conflicting dependencies go in its node_modules.
+// Bins of the root & workspace packages' dependencies go in node_modules/.bin, and nested packages' bins go in the .bin folder next to them.
+func Hoisted(manifest *lockfile.JavascriptPackageManifest) Plan {
+	topLevel := manifest.TopLevelIndices()
+	names := make([]string, 0, len(topLevel))
+	for name := range topLevel {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	folders := make([]folder, 1, len(manifest.Name)+1)
+	folders[0] = folder{parent: -1, children: topLevel}
+
+	plan := Plan{Placements: make([]Placement, 0, len(manifest.Name))}
+	for _, name := range names {
+		index := topLevel[name]
+		placementPath, ok := lockfile.WorkspacePath(manifest.Version[index])
+		if ok {
+			// Like any top-level package, other packages load it from node_modules
+			plan.Links = append(plan.Links, Link{Index: index, Path: path.Join("node_modules", name), Target: placementPath})
+		} else {
+			placementPath = path.Join("node_modules", name)
+		}
+
+		folders = append(folders, folder{parent: 0, index: index, path: placementPath})
+		plan.Placements = append(plan.Placements, Placement{Index: index, Path: placementPath})
+	}
+
+	lists := manifest.DependencyLists()
+	// Breadth-first, so packages are placed before anything nested in them
+	for current := 1; current < len(folders); current++ {
+		for _, dependency := range lists[folders[current].index] {
+			name := manifest.Name[dependency]
+			if found, ok := lookup(folders, current, name); ok && found == dependency {
+				continue
+			}
+
+			// A cycle of conflicting versions would nest forever, so the copy above is used instead
+			if isAncestor(folders, current, dependency) {
+				continue
+			}
+
+			if folders[current].children == nil {
+				folders[current].children = make(map[string]uint)
+			}
+			folders[current].children[name] = dependency
+
+			placementPath := path.Join(folders[current].path, "node_modules", name)
+			folders = append(folders, folder{parent: current, index: dependency, path: placementPath})
+			plan.Placements = append(plan.Placements, Placement{
+				Index: dependency,
+				Path:  placementPath,
+				Depth: depth(folders, current) + 1,
+			})
+		}
+	}
+
+	direct := directIndices(manifest)
+	for _, placed := range folders[1:] {
+		if _, ok := lockfile.WorkspacePath(manifest.Version[placed.index]); ok {
+			continue
+		}
+
+		binFolder := path.Join("node_modules", ".bin")
+		if placed.parent > 0 {
+			binFolder = path.Join(folders[placed.parent].path, "node_modules", ".bin")
+		} else if !direct[placed.index] {
+			continue
+		}
+
+		plan.Bins = append(plan.Bins, Bin{Index: placed.index, Package: placed.path, Folder: binFolder})
+	}
+
+	return plan
+}
+
+// directIndices is the root & workspace packages' dependencies.
+func directIndices(manifest *lockfile.JavascriptPackageManifest) map[uint]bool {
+	direct := make(map[uint]bool, len(manifest.RootDependencies))
+	for _, index := range manifest.RootDependencies {
+		direct[index] = true
+	}
+
+	for index, dependencies := range manifest.DependencyLists() {
+		if _, ok := lockfile.WorkspacePath(manifest.Version[index]); !ok {
+			continue
+		}
+
+		for _, dependency := range dependencies {
+			direct[dependency] = true
+		}
+	}
+
+	return direct
+}
+
+// lookup finds the package Node would load for name from the package in folders[current], by checking each node_modules going up.
+func lookup(folders []folder, current int, name string) (uint, bool) {
+	for ; current != -1; current = folders[current].parent {
+		if index, ok := folders[current].children[name]; ok {
+			return index, true
+		}
+	}
+
+	return 0, false
+}
+
+func isAncestor(folders []folder, current int, index uint) bool {
+	for ; current > 0; current = folders[current].parent {
+		if folders[current].index == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+func depth(folders []folder, current int) int {
+	d := 0
+	for current = folders[current].parent; current > 0; current = folders[current].parent {
+		d++
+	}
+
+	return d
+}
+
+// Isolated plans a pnpm-style node_modules, where each package can only load its own dependencies.
+// Every package is placed once, in node_modules/.duck/<name>@<version>/node_modules/<name>, with its dependencies symlinked next to it.
+// The top level only has symlinks to the root package's dependencies. Workspace packages stay in their own folder, and their dependencies are symlinked into its node_modules.
+// Only the root & workspace packages' dependencies have their bins linked.
+func Isolated(manifest *lockfile.JavascriptPackageManifest) Plan {
+	plan := Plan{Placements: make([]Placement, len(manifest.Name))}
+	modules := make([]string, len(manifest.Name))
+	workspaces := make([]bool, len(manifest.Name))
+
+	for index, name := range manifest.Name {
+		version := manifest.Version[index]
+		placementPath, ok := lockfile.WorkspacePath(version)
+		if ok {
+			modules[index] = path.Join(placementPath, "node_modules")
+			workspaces[index] = true
+		} else {
+			modules[index] = path.Join("node_modules", StoreFolder, storeNameReplacer.Replace(lockfile.NewPackageManifestKey(name, version)), "node_modules")
+			placementPath = path.Join(modules[index], name)
+		}
+
+		plan.Placements[index] = Placement{Index: uint(index), Path: placementPath}
+	}
+
+	for index, dependencies := range manifest.DependencyLists() {
+		for _, dependency := range dependencies {
+			linkPath := path.Join(modules[index], manifest.Name[dependency])
+			// A package that depends on another version of itself would replace itself, so it gets its own version instead
+			if linkPath == plan.Placements[index].Path {
+				continue
+			}
+
+			plan.Links = append(plan.Links, Link{Index: dependency, Path: linkPath, Target: plan.Placements[dependency].Path})
+			if workspaces[index] && !workspaces[dependency] {
+				plan.Bins = append(plan.Bins, Bin{Index: dependency, Package: linkPath, Folder: path.Join(modules[index], ".bin")})
+			}
+		}
+	}
+
+	for _, dependency := range manifest.RootDependencies {
+		linkPath := path.Join("node_modules", manifest.Name[dependency])
+		plan.Links = append(plan.Links, Link{
+			Index:  dependency,
+			Path:   linkPath,
+			Target: plan.Placements[dependency].Path,
+		})
+
+		if !workspaces[dependency] {
+			plan.Bins = append(plan.Bins, Bin{Index: dependency, Package: linkPath, Folder: path.Join("node_modules", ".bin")})
+		}
+	}
+
+	return plan
+}
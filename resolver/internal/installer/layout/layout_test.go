@@ -0,0 +1,155 @@
+package layout_test
+
+import (
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer/layout"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func newManifest(names []string, versions []string, lists [][]uint, roots []uint) lockfile.JavascriptPackageManifest {
+	manifest := lockfile.JavascriptPackageManifest{
+		Name:             names,
+		Version:          versions,
+		RootDependencies: roots,
+		Count:            uint(len(names)),
+	}
+
+	for _, list := range lists {
+		manifest.Dependencies = append(manifest.Dependencies, list...)
+		manifest.DependencyIndex = append(manifest.DependencyIndex, uint(len(list)))
+	}
+
+	return manifest
+}
+
+func paths(plan layout.Plan, manifest *lockfile.JavascriptPackageManifest) map[string]string {
+	placed := make(map[string]string, len(plan.Placements))
+	for _, placement := range plan.Placements {
+		placed[placement.Path] = manifest.Version[placement.Index]
+	}
+
+	return placed
+}
+
+func bins(plan layout.Plan) map[string]string {
+	folders := make(map[string]string, len(plan.Bins))
+	for _, bin := range plan.Bins {
+		folders[bin.Package] = bin.Folder
+	}
+
+	return folders
+}
+
+func TestHoisted(t *testing.T) {
+	// express & send use debug@2, so app's debug@4 and its ms@2.1 are nested
+	manifest := newManifest(
+		[]string{"app", "debug", "express", "ms", "debug", "ms", "send"},
+		[]string{"1.0.0", "2.6.9", "4.17.1", "2.0.0", "4.3.1", "2.1.2", "0.17.1"},
+		[][]uint{{4}, {3}, {1, 6}, {}, {5}, {}, {1, 3}},
+		[]uint{0, 2},
+	)
+
+	plan := layout.Hoisted(&manifest)
+	assert.Equal(t, map[string]string{
+		"node_modules/app":                                    "1.0.0",
+		"node_modules/debug":                                  "2.6.9",
+		"node_modules/express":                                "4.17.1",
+		"node_modules/ms":                                     "2.0.0",
+		"node_modules/send":                                   "0.17.1",
+		"node_modules/app/node_modules/debug":                 "4.3.1",
+		"node_modules/app/node_modules/debug/node_modules/ms": "2.1.2",
+	}, paths(plan, &manifest))
+
+	// Packages come after the package they're nested in
+	for i, placement := range plan.Placements {
+		if i > 0 {
+			assert.GreaterOrEqual(t, placement.Depth, plan.Placements[i-1].Depth)
+		}
+	}
+	assert.Equal(t, 2, plan.Placements[len(plan.Placements)-1].Depth)
+
+	// debug@2.6.9 & ms@2.0.0 aren't direct dependencies, so their bins aren't linked
+	assert.Equal(t, map[string]string{
+		"node_modules/app":                                    "node_modules/.bin",
+		"node_modules/express":                                "node_modules/.bin",
+		"node_modules/app/node_modules/debug":                 "node_modules/app/node_modules/.bin",
+		"node_modules/app/node_modules/debug/node_modules/ms": "node_modules/app/node_modules/debug/node_modules/.bin",
+	}, bins(plan))
+}
+
+func TestHoistedCycle(t *testing.T) {
+	// a@1 -> b@1 -> a@2 -> b@2 -> a@1
+	manifest := newManifest(
+		[]string{"a", "b", "a", "b"},
+		[]string{"1.0.0", "1.0.0", "2.0.0", "2.0.0"},
+		[][]uint{{1}, {2}, {3}, {0}},
+		[]uint{0, 1},
+	)
+
+	plan := layout.Hoisted(&manifest)
+	assert.Equal(t, map[string]string{
+		"node_modules/a":                                              "1.0.0",
+		"node_modules/b":                                              "1.0.0",
+		"node_modules/b/node_modules/a":                               "2.0.0",
+		"node_modules/b/node_modules/a/node_modules/b":                "2.0.0",
+		"node_modules/b/node_modules/a/node_modules/b/node_modules/a": "1.0.0",
+	}, paths(plan, &manifest))
+}
+
+func TestHoistedWorkspaces(t *testing.T) {
+	manifest := newManifest(
+		[]string{"ui", "react", "react"},
+		[]string{lockfile.NewWorkspaceVersion("packages/ui"), "18.0.0", "17.0.2"},
+		[][]uint{{2}, {}, {}},
+		[]uint{0, 1},
+	)
+
+	plan := layout.Hoisted(&manifest)
+	assert.Equal(t, map[string]string{
+		"packages/ui":                    lockfile.NewWorkspaceVersion("packages/ui"),
+		"node_modules/react":             "18.0.0",
+		"packages/ui/node_modules/react": "17.0.2",
+	}, paths(plan, &manifest))
+
+	assert.Equal(t, []layout.Link{{Index: 0, Path: "node_modules/ui", Target: "packages/ui"}}, plan.Links)
+}
+
+func TestIsolated(t *testing.T) {
+	manifest := newManifest(
+		[]string{"@babel/core", "debug", "ms", "ui", "debug"},
+		[]string{"7.0.0", "4.3.1", "2.1.2", lockfile.NewWorkspaceVersion("packages/ui"), "2.6.9"},
+		[][]uint{{1}, {2}, {}, {4}, {2}},
+		[]uint{0, 3},
+	)
+
+	plan := layout.Isolated(&manifest)
+	assert.Equal(t, map[string]string{
+		"node_modules/.duck/@babel+core@7.0.0/node_modules/@babel/core": "7.0.0",
+		"node_modules/.duck/debug@4.3.1/node_modules/debug":             "4.3.1",
+		"node_modules/.duck/debug@2.6.9/node_modules/debug":             "2.6.9",
+		"node_modules/.duck/ms@2.1.2/node_modules/ms":                   "2.1.2",
+		"packages/ui": lockfile.NewWorkspaceVersion("packages/ui"),
+	}, paths(plan, &manifest))
+
+	links := make(map[string]string, len(plan.Links))
+	for _, link := range plan.Links {
+		links[link.Path] = link.Target
+	}
+
+	// Only direct dependencies are at the top level
+	assert.Equal(t, map[string]string{
+		"node_modules/.duck/@babel+core@7.0.0/node_modules/debug": "node_modules/.duck/debug@4.3.1/node_modules/debug",
+		"node_modules/.duck/debug@4.3.1/node_modules/ms":          "node_modules/.duck/ms@2.1.2/node_modules/ms",
+		"node_modules/.duck/debug@2.6.9/node_modules/ms":          "node_modules/.duck/ms@2.1.2/node_modules/ms",
+		"packages/ui/node_modules/debug":                          "node_modules/.duck/debug@2.6.9/node_modules/debug",
+		"node_modules/@babel/core":                                "node_modules/.duck/@babel+core@7.0.0/node_modules/@babel/core",
+		"node_modules/ui":                                         "packages/ui",
+	}, links)
+
+	assert.Equal(t, map[string]string{
+		"node_modules/@babel/core":       "node_modules/.bin",
+		"packages/ui/node_modules/debug": "packages/ui/node_modules/.bin",
+	}, bins(plan))
+}
@@ -0,0 +1,184 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gammazero/workerpool"
+)
+
+// InstallScripts run when a package is installed, in this order.
+var InstallScripts = []string{"preinstall", "install", "postinstall"}
+
+// RootScripts run for the root package, after every dependency's.
+var RootScripts = []string{"preinstall", "install", "postinstall", "prepare"}
+
+// Package is one folder whose scripts run.
+type Package struct {
+	Name    string
+	Version string
+	Dir     string
+	// ScriptKeys are the names of the scripts to run, in order, and ScriptValues are their commands
+	ScriptKeys   []string
+	ScriptValues []string
+	// Dependencies are the indexes of the packages whose scripts have to finish first
+	Dependencies []int
+}
+
+// Result is what running a package's scripts did.
+type Result struct {
+	Package *Package
+	// Script is the last script that ran, which is the one that failed when there's an Error
+	Script string
+	// Output is stdout & stderr of every script that ran
+	Output []byte
+	Error  error
+}
+
+// Message describes a failed script, like "esbuild@0.14.0 postinstall: exit status 1".
+func (r *Result) Message() string {
+	return fmt.Sprintf("%s@%s %s: %s", r.Package.Name, r.Package.Version, r.Script, r.Error)
+}
+
+// Runner runs packages' scripts, with dependencies' scripts before their dependents'.
+type Runner struct {
+	Ctx     context.Context
+	Workers int
+	// BinFolders are added to PATH, after the package's own node_modules/.bin
+	BinFolders []string
+}
+
+// Run runs every package's scripts and returns what each did, in the same order as packages.
+// Packages whose dependencies are done run in parallel, up to Workers at a time. Packages in a dependency cycle, and the packages depending on them, run last.
+// Like npm, nothing else runs once a script fails, so the packages after it have a Result without a Script.
+func (r *Runner) Run(packages []Package) []Result {
+	results := make([]Result, len(packages))
+	for index := range packages {
+		results[index].Package = &packages[index]
+	}
+
+	pool := workerpool.New(r.Workers)
+	defer pool.Stop()
+
+	for _, level := range levels(packages) {
+		var wg sync.WaitGroup
+		for _, index := range level {
+			index := index
+			wg.Add(1)
+			pool.Submit(func() {
+				defer wg.Done()
+				results[index] = r.runPackage(&packages[index])
+			})
+		}
+		wg.Wait()
+
+		for _, index := range level {
+			if results[index].Error != nil {
+				return results
+			}
+		}
+	}
+
+	return results
+}
+
+// levels groups packages so that each one is after everything it depends on, unless it's in a cycle.
+func levels(packages []Package) [][]int {
+	remaining := make([]int, len(packages))
+	dependents := make([][]int, len(packages))
+	for index := range packages {
+		for _, dependency := range packages[index].Dependencies {
+			if dependency != index {
+				remaining[index]++
+				dependents[dependency] = append(dependents[dependency], index)
+			}
+		}
+	}
+
+	done := make([]bool, len(packages))
+	var levels [][]int
+	next := make([]int, 0, len(packages))
+	for index := range packages {
+		if remaining[index] == 0 {
+			next = append(next, index)
+		}
+	}
+
+	for len(next) > 0 {
+		levels = append(levels, next)
+		current := next
+		next = nil
+		for _, index := range current {
+			done[index] = true
+			for _, dependent := range dependents[index] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+	}
+
+	var cycle []int
+	for index := range packages {
+		if !done[index] {
+			cycle = append(cycle, index)
+		}
+	}
+
+	if len(cycle) > 0 {
+		levels = append(levels, cycle)
+	}
+
+	return levels
+}
+
+func (r *Runner) runPackage(pkg *Package) Result {
+	result := Result{Package: pkg}
+	var output bytes.Buffer
+
+	for i, script := range pkg.ScriptKeys {
+		result.Script = script
+
+		command := shellCommand(r.Ctx, pkg.ScriptValues[i])
+		command.Dir = pkg.Dir
+		command.Env = r.env(pkg, script)
+		command.Stdout = &output
+		command.Stderr = &output
+
+		if result.Error = command.Run(); result.Error != nil {
+			break
+		}
+	}
+
+	result.Output = output.Bytes()
+	return result
+}
+
+func shellCommand(ctx context.Context, script string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/d", "/s", "/c", script)
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", script)
+}
+
+// env is the environment npm gives scripts, with the package's bins first in PATH.
+func (r *Runner) env(pkg *Package, script string) []string {
+	path := append([]string{filepath.Join(pkg.Dir, "node_modules", ".bin")}, r.BinFolders...)
+	path = append(path, os.Getenv("PATH"))
+
+	return append(os.Environ(),
+		"PATH="+strings.Join(path, string(os.PathListSeparator)),
+		"npm_lifecycle_event="+script,
+		"npm_package_name="+pkg.Name,
+		"npm_package_version="+pkg.Version,
+	)
+}
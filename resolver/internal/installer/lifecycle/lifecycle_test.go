@@ -0,0 +1,60 @@
+package lifecycle_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer/lifecycle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts use sh")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := `echo "$npm_package_name $npm_lifecycle_event" >> ` + log
+
+	packages := []lifecycle.Package{
+		{Name: "app", Version: "1.0.0", Dir: dir, ScriptKeys: []string{"postinstall"}, ScriptValues: []string{script}, Dependencies: []int{1}},
+		{Name: "native", Version: "2.0.0", Dir: dir, ScriptKeys: []string{"preinstall", "install"}, ScriptValues: []string{script, script + " && echo built"}, Dependencies: []int{2}},
+		{Name: "base", Version: "3.0.0", Dir: dir, ScriptKeys: []string{"install"}, ScriptValues: []string{script}},
+	}
+
+	runner := lifecycle.Runner{Ctx: context.Background(), Workers: 2}
+	results := runner.Run(packages)
+	for _, result := range results {
+		assert.Nil(t, result.Error)
+	}
+	assert.Equal(t, "built\n", string(results[1].Output))
+
+	body, err := os.ReadFile(log)
+	assert.Nil(t, err)
+	assert.Equal(t, "base install\nnative preinstall\nnative install\napp postinstall\n", string(body))
+}
+
+func TestRunnerFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts use sh")
+	}
+
+	dir := t.TempDir()
+	packages := []lifecycle.Package{
+		{Name: "broken", Version: "1.0.0", Dir: dir, ScriptKeys: []string{"preinstall", "postinstall"}, ScriptValues: []string{"echo oops && exit 3", "touch ran"}},
+		{Name: "app", Version: "1.0.0", Dir: dir, ScriptKeys: []string{"postinstall"}, ScriptValues: []string{"touch ran"}, Dependencies: []int{0}},
+	}
+
+	runner := lifecycle.Runner{Ctx: context.Background(), Workers: 2}
+	results := runner.Run(packages)
+
+	assert.Equal(t, "broken@1.0.0 preinstall: exit status 3", results[0].Message())
+	assert.Equal(t, "oops\n", string(results[0].Output))
+	// Nothing runs after a failure
+	assert.Empty(t, results[1].Script)
+	assert.NoFileExists(t, filepath.Join(dir, "ran"))
+}
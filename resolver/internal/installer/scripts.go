@@ -0,0 +1,124 @@
+package installer
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/installer/lifecycle"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+)
+
+// ScriptPackages lists every installed copy of a package with install scripts, for lifecycle.Runner.
+// Only packages named in allow are listed, and the names of the rest are returned. root's scripts run last, in the folder with node_modules.
+func (i *PackageInstaller) ScriptPackages(root *lockfile.JavascriptPackageManifestPartial, manifest *lockfile.JavascriptPackageManifest, allow []string) ([]lifecycle.Package, []string) {
+	allowed := make(map[string]bool, len(allow))
+	for _, name := range allow {
+		allowed[name] = true
+	}
+
+	var packages []lifecycle.Package
+	var skipped []string
+	skippedNames := map[string]bool{}
+	skip := func(name string) {
+		if !skippedNames[name] {
+			skippedNames[name] = true
+			skipped = append(skipped, name)
+		}
+	}
+
+	// Where each package in the lockfile is in packages
+	copies := make(map[uint][]int)
+	for _, placement := range i.Plan.Placements {
+		value, ok := i.fetches.Load(lockfile.NewPackageManifestKey(manifest.Name[placement.Index], manifest.Version[placement.Index]))
+		if !ok {
+			continue
+		}
+
+		fetch := value.(*InstallPackageJob)
+		keys, values := scriptsIn(fetch.Manifest, lifecycle.InstallScripts)
+		if len(keys) == 0 {
+			continue
+		}
+
+		if !allowed[fetch.Manifest.Name] {
+			skip(fetch.Manifest.Name)
+			continue
+		}
+
+		copies[placement.Index] = append(copies[placement.Index], len(packages))
+		packages = append(packages, lifecycle.Package{
+			Name:         manifest.Name[placement.Index],
+			Version:      manifest.Version[placement.Index],
+			Dir:          i.DestinationPathForPlacement(placement.Path),
+			ScriptKeys:   keys,
+			ScriptValues: values,
+		})
+	}
+
+	lists := manifest.DependencyLists()
+	for index, indexes := range copies {
+		dependencies := dependenciesWithScripts(lists, index, copies)
+		for _, copyIndex := range indexes {
+			packages[copyIndex].Dependencies = dependencies
+		}
+	}
+
+	if keys, values := scriptsIn(root, lifecycle.RootScripts); len(keys) > 0 {
+		if !allowed[root.Name] {
+			skip(root.Name)
+		} else {
+			dependencies := make([]int, len(packages))
+			for copyIndex := range packages {
+				dependencies[copyIndex] = copyIndex
+			}
+
+			packages = append(packages, lifecycle.Package{
+				Name:         root.Name,
+				Version:      root.Version.Tag,
+				Dir:          filepath.Dir(i.NodeModulesFolder),
+				ScriptKeys:   keys,
+				ScriptValues: values,
+				Dependencies: dependencies,
+			})
+		}
+	}
+
+	return packages, skipped
+}
+
+// dependenciesWithScripts finds the packages in copies that index depends on, including through packages without scripts.
+func dependenciesWithScripts(lists [][]uint, index uint, copies map[uint][]int) []int {
+	var dependencies []int
+	visited := map[uint]bool{index: true}
+	queue := append([]uint{}, lists[index]...)
+
+	for len(queue) > 0 {
+		dependency := queue[0]
+		queue = queue[1:]
+		if visited[dependency] {
+			continue
+		}
+		visited[dependency] = true
+
+		dependencies = append(dependencies, copies[dependency]...)
+		queue = append(queue, lists[dependency]...)
+	}
+
+	sort.Ints(dependencies)
+	return dependencies
+}
+
+// scriptsIn returns manifest's scripts that are in names, in the same order.
+func scriptsIn(manifest *lockfile.JavascriptPackageManifestPartial, names []string) ([]string, []string) {
+	var keys, values []string
+	for _, name := range names {
+		for i, key := range manifest.ScriptKeys {
+			if key == name && len(manifest.ScriptValues[i]) > 0 {
+				keys = append(keys, key)
+				values = append(values, manifest.ScriptValues[i])
+			}
+		}
+	}
+
+	return keys, values
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// WatchDisconnect calls cancel once the client on conn hangs up, until stop is called.
+// fasthttp only notices a client is gone when it writes the response, so without this an abandoned resolution runs to the end.
+// It peeks at conn instead of reading it, so a request the client pipelined behind this one is still there for fasthttp.
+func WatchDisconnect(conn net.Conn, cancel func()) (stop func()) {
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return func() {}
+	}
+
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if waitForHangUp(rawConn) {
+			cancel()
+		}
+	}()
+
+	return func() {
+		// A deadline in the past wakes the peek up, and it's cleared again for fasthttp's next read
+		conn.SetReadDeadline(time.Now())
+		<-done
+		conn.SetReadDeadline(time.Time{})
+	}
+}
@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// connect returns both ends of a TCP connection.
+func connect(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+		conn.Close()
+	})
+	return conn, client
+}
+
+func TestWatchDisconnect(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("disconnects aren't watched for on Windows")
+	}
+
+	conn, client := connect(t)
+	cancelled := make(chan struct{})
+	stop := server.WatchDisconnect(conn, func() { close(cancelled) })
+	defer stop()
+
+	client.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a disconnect to cancel")
+	}
+}
+
+func TestWatchDisconnectStop(t *testing.T) {
+	conn, client := connect(t)
+	stop := server.WatchDisconnect(conn, func() { t.Error("expected no disconnect") })
+
+	// A pipelined request is only peeked at
+	_, err := client.Write([]byte("GET"))
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	buf := make([]byte, 3)
+	_, err = conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", string(buf))
+
+	// Stopping while the client is idle leaves conn readable
+	stop = server.WatchDisconnect(conn, func() { t.Error("expected no disconnect") })
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	_, err = client.Write([]byte("!"))
+	assert.Nil(t, err)
+	_, err = conn.Read(buf[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, "!", string(buf[:1]))
+}
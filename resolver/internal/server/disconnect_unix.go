@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import "syscall"
+
+// waitForHangUp blocks until conn is readable, and is true when that's because the client closed it.
+// It's false when the client sent more data, or conn's read deadline passed.
+func waitForHangUp(conn syscall.RawConn) bool {
+	var hungUp bool
+	buf := make([]byte, 1)
+	err := conn.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			return false
+		}
+
+		// Reading nothing is the client closing its end, and an error is it resetting the connection
+		hungUp = err != nil || n == 0
+		return true
+	})
+
+	return err == nil && hungUp
+}
@@ -0,0 +1,8 @@
+package server
+
+import "syscall"
+
+// Windows can't peek at a socket through syscall, so resolutions there only stop at --timeout.
+func waitForHangUp(conn syscall.RawConn) bool {
+	return false
+}
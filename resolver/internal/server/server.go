@@ -2,10 +2,12 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/savsgio/atreugo/v11"
@@ -15,6 +17,7 @@ import (
 	"github.com/atreugo/cors"
 	"github.com/jarred-sumner/devserverless/config"
 	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
 	"github.com/jarred-sumner/peechy/buffer"
 )
@@ -27,6 +30,37 @@ type Server struct {
 	LocalStore *cache.LocalPackageManifestStore
 
 	HTTPServer *atreugo.Atreugo
+
+	// ResolveTimeout cancels a resolution that runs longer. Zero is no limit.
+	ResolveTimeout time.Duration
+	// Registrar is where packages come from. Nil is npm's registry.
+	Registrar lockfile.Registrar
+}
+
+// resolveContext bounds one request's resolution. It's cancelled when the client disconnects, or after ResolveTimeout.
+// fasthttp only cancels a request's own context on shutdown, so disconnects are watched for here.
+func (state *Server) resolveContext(ctx *atreugo.RequestCtx) (context.Context, context.CancelFunc) {
+	var resolveCtx context.Context
+	var cancel context.CancelFunc
+	if state.ResolveTimeout > 0 {
+		resolveCtx, cancel = context.WithTimeout(ctx, state.ResolveTimeout)
+	} else {
+		resolveCtx, cancel = context.WithCancel(ctx)
+	}
+
+	stop := WatchDisconnect(ctx.Conn(), cancel)
+	return resolveCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func resolveErrorStatusCode(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 504
+	}
+
+	return 400
 }
 
 func (state *Server) PackagePartial(ctx *atreugo.RequestCtx) error {
@@ -65,7 +99,9 @@ func (state *Server) PackagePartial(ctx *atreugo.RequestCtx) error {
 				return nil
 			}
 
-			manifest, err = state.Store.ResolveDependencies(req.Manifest, ctx)
+			resolveCtx, cancel := state.resolveContext(ctx)
+			manifest, err = state.Store.ResolveDependenciesWithOptions(req.Manifest, lockfile.ResolveOptions{Groups: req.DependencyGroups()}, resolveCtx)
+			cancel()
 
 			if err != nil {
 				resp.Result = nil
@@ -76,7 +112,7 @@ func (state *Server) PackagePartial(ctx *atreugo.RequestCtx) error {
 				resp.ErrorCode = &code
 				resp.Encode(&encoder)
 				ctx.Write(encoder.Slice())
-				ctx.SetStatusCode(400)
+				ctx.SetStatusCode(resolveErrorStatusCode(err))
 				return nil
 			}
 
@@ -100,7 +136,9 @@ func Index(ctx *atreugo.RequestCtx) {
 }
 
 func (state *Server) ResolvePartial(partial lockfile.JavascriptPackageManifestPartial, ctx *atreugo.RequestCtx, isBinary bool) error {
-	manifest, err := state.Store.ResolveDependencies(&partial, ctx)
+	resolveCtx, cancel := state.resolveContext(ctx)
+	manifest, err := state.Store.ResolveDependencies(&partial, resolveCtx)
+	cancel()
 
 	var message *string
 	if err != nil {
@@ -110,6 +148,10 @@ func (state *Server) ResolvePartial(partial lockfile.JavascriptPackageManifestPa
 		message = nil
 	}
 
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return ctx.ErrorResponse(err, 504)
+	}
+
 	if manifest.Count == 0 {
 		return ctx.ErrorResponse(errors.New("package not found"), 404)
 	}
@@ -187,7 +229,11 @@ func (state *Server) Launch(port uint) {
 			var err error
 			state.LocalStore, err = cache.NewLocalPackageManifestStore(config.Global.Cache)
 			state.Store = state.LocalStore.Store
-			state.Store.RegistrarAPI = config.Global.Registrar
+			state.Store.Registrar = state.Registrar
+			state.Store.ResolutionMode = config.Global.ResolutionMode
+			state.Store.MetadataSource = config.Global.MetadataSource
+			state.Store.Npmrc = &config.Global.Npmrc
+			state.Store.HTTP = httpclient.New(httpclient.ConfigPolicy(&config.Global))
 
 			if err != nil {
 				state.Store.Logger.Fatal("Error starting", zap.Error(err))
@@ -201,7 +247,11 @@ func (state *Server) Launch(port uint) {
 	case config.CacheTypeNone:
 		{
 			state.Store = cache.NewMemoryPackageManifestStore()
-			state.Store.RegistrarAPI = config.Global.Registrar
+			state.Store.Registrar = state.Registrar
+			state.Store.ResolutionMode = config.Global.ResolutionMode
+			state.Store.MetadataSource = config.Global.MetadataSource
+			state.Store.Npmrc = &config.Global.Npmrc
+			state.Store.HTTP = httpclient.New(httpclient.ConfigPolicy(&config.Global))
 			state.Store.Logger.Info("Started server with memory cache "+"http://localhost:"+strconv.FormatUint(uint64(config.Global.Port), 10), zap.Uint("port", port))
 			if err := state.StartServer(port); err != nil {
 				state.Store.Logger.Fatal("Error in ListenAndServe: %s", zap.Error(err))
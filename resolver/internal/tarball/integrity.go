@@ -0,0 +1,104 @@
+package tarball
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// IntegrityError is returned when what was downloaded doesn't match the integrity it's supposed to have.
+type IntegrityError struct {
+	Source    string
+	Integrity string
+	Actual    string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%s doesn't match its integrity %s", e.Source, e.Integrity)
+}
+
+// Strongest first, like browsers pick between the hashes in an integrity attribute
+var integrityAlgorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha384", sha512.New384},
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+}
+
+// ShasumIntegrity converts a hex sha1, like the "dist.shasum" of packages published before npm had integrity, to Subresource Integrity format.
+func ShasumIntegrity(shasum string) string {
+	sum, err := hex.DecodeString(strings.TrimSpace(shasum))
+	if err != nil || len(sum) != sha1.Size {
+		return ""
+	}
+
+	return "sha1-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+// Verifier hashes everything read through it, so a download is checked while it's extracted instead of after.
+type Verifier struct {
+	reader    io.Reader
+	hash      hash.Hash
+	algorithm string
+	integrity string
+	// The digests integrity allows for algorithm, in base64
+	expected []string
+}
+
+// NewVerifier checks r against integrity, which can have several space-separated hashes like "sha512-<base64> sha1-<base64>".
+// Only the strongest algorithm in it is checked, and any of its hashes can match.
+func NewVerifier(r io.Reader, integrity string) (*Verifier, error) {
+	hashes := strings.Fields(integrity)
+	for _, algorithm := range integrityAlgorithms {
+		var expected []string
+		for _, value := range hashes {
+			if !strings.HasPrefix(value, algorithm.name+"-") {
+				continue
+			}
+
+			// Options like "?foo" come after the digest
+			digest := value[len(algorithm.name)+1:]
+			if question := strings.IndexByte(digest, '?'); question > -1 {
+				digest = digest[:question]
+			}
+			expected = append(expected, digest)
+		}
+
+		if len(expected) > 0 {
+			verifier := &Verifier{hash: algorithm.new(), algorithm: algorithm.name, integrity: integrity, expected: expected}
+			verifier.reader = io.TeeReader(r, verifier.hash)
+			return verifier, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported integrity %q", integrity)
+}
+
+func (v *Verifier) Read(p []byte) (int, error) {
+	return v.reader.Read(p)
+}
+
+// Verify reads whatever's left, and returns an *IntegrityError when it didn't match. source is what the error calls it.
+func (v *Verifier) Verify(source string) error {
+	if _, err := io.Copy(io.Discard, v.reader); err != nil {
+		return err
+	}
+
+	actual := base64.StdEncoding.EncodeToString(v.hash.Sum(nil))
+	for _, expected := range v.expected {
+		if actual == expected {
+			return nil
+		}
+	}
+
+	return &IntegrityError{Source: source, Integrity: v.integrity, Actual: v.algorithm + "-" + actual}
+}
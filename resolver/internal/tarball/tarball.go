@@ -0,0 +1,194 @@
+// Package tarball downloads .tgz packages once and keeps them in a cache folder, next to their integrity hash.
+package tarball
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
+)
+
+const integritySuffix = ".integrity"
+
+var ErrPackageJSONMissing = errors.New("tarball has no package.json")
+
+// DefaultClient downloads tarballs when a Cache doesn't have a Client. A download that takes longer than its Timeout has stalled.
+var DefaultClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Cache stores downloaded tarballs in Dir, each with a ".integrity" file holding its sha512 in Subresource Integrity format.
+type Cache struct {
+	Dir string
+	// Client is DefaultClient when it's nil
+	Client *http.Client
+	// HTTP retries failed downloads and limits how many each host gets. Nil downloads once, without limits.
+	HTTP *httpclient.Client
+	// Authorization is sent with downloads, for tarballs on a private registry
+	Authorization string
+}
+
+// HTTPError is returned when the server responds with anything but a 200.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", config.RedactURL(e.URL), e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Each URL is only downloaded by one caller at a time
+var downloadLocks sync.Map
+
+// Path is where the tarball for url is cached.
+func (c *Cache) Path(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(hash[:])+".tgz")
+}
+
+// Fetch returns the cached tarball for url and its integrity, downloading it when it isn't cached or doesn't match its integrity.
+func (c *Cache) Fetch(ctx context.Context, url string) (string, string, error) {
+	tarballPath := c.Path(url)
+
+	lock, _ := downloadLocks.LoadOrStore(tarballPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if integrity, err := os.ReadFile(tarballPath + integritySuffix); err == nil {
+		if Verify(tarballPath, string(integrity)) == nil {
+			return tarballPath, string(integrity), nil
+		}
+	}
+
+	integrity, err := c.download(ctx, url, tarballPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	return tarballPath, integrity, nil
+}
+
+func (c *Cache) download(ctx context.Context, url string, tarballPath string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(c.Authorization) > 0 {
+		request.Header.Set("Authorization", c.Authorization)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	response, err := c.HTTP.DoHTTP(ctx, client, request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", &HTTPError{URL: url, StatusCode: response.StatusCode}
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	// Written somewhere else first, so an interrupted download is never mistaken for a cached one
+	file, err := os.CreateTemp(c.Dir, ".download")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	hash := sha512.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(file.Name(), tarballPath); err != nil {
+		return "", err
+	}
+
+	return integrity, os.WriteFile(tarballPath+integritySuffix, []byte(integrity), 0644)
+}
+
+// Integrity hashes r in Subresource Integrity format, like "sha512-<base64>".
+func Integrity(r io.Reader) (string, error) {
+	hash := sha512.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+
+	return "sha512-" + base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Verify checks the file at tarballPath against integrity. A mismatch is an *IntegrityError.
+func Verify(tarballPath string, integrity string) error {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	verifier, err := NewVerifier(file, integrity)
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify(tarballPath)
+}
+
+// ReadPackageJSON returns package.json from the top-level folder of the tarball at tarballPath.
+// npm's tarballs use a "package" folder, but any folder name works.
+func ReadPackageJSON(tarballPath string) ([]byte, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, ErrPackageJSONMissing
+		} else if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		slash := strings.IndexByte(name, '/')
+		if header.Typeflag == tar.TypeReg && slash > -1 && name[slash+1:] == "package.json" {
+			return io.ReadAll(reader)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tarball_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
+	"github.com/jarred-sumner/devserverless/resolver/internal/tarball"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(gz)
+	for name, body := range files {
+		assert.Nil(t, writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+		_, err := writer.Write([]byte(body))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestFetch(t *testing.T) {
+	archive := newTarball(t, map[string]string{
+		"pkg/index.js":     "module.exports = 1",
+		"pkg/package.json": `{"name": "pkg", "version": "1.2.3"}`,
+	})
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/pkg-1.2.3.tgz" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	cache := tarball.Cache{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	tarballPath, integrity, err := cache.Fetch(ctx, server.URL+"/pkg-1.2.3.tgz")
+	assert.Nil(t, err)
+	expected, _ := tarball.Integrity(bytes.NewReader(archive))
+	assert.Equal(t, expected, integrity)
+	assert.Nil(t, tarball.Verify(tarballPath, integrity))
+
+	body, err := tarball.ReadPackageJSON(tarballPath)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name": "pkg", "version": "1.2.3"}`, string(body))
+
+	// Cached, so it isn't downloaded again
+	_, _, err = cache.Fetch(ctx, server.URL+"/pkg-1.2.3.tgz")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// A corrupt tarball is downloaded again
+	assert.Nil(t, os.WriteFile(tarballPath, []byte("corrupt"), 0644))
+	_, integrity, err = cache.Fetch(ctx, server.URL+"/pkg-1.2.3.tgz")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, integrity)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	_, _, err = cache.Fetch(ctx, server.URL+"/missing.tgz")
+	var httpError *tarball.HTTPError
+	assert.ErrorAs(t, err, &httpError)
+	assert.Equal(t, 404, httpError.StatusCode)
+}
+
+func TestFetchRetries(t *testing.T) {
+	archive := newTarball(t, map[string]string{"pkg/package.json": `{"name": "pkg", "version": "1.2.3"}`})
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			{
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(429)
+			}
+		case 2:
+			{
+				w.WriteHeader(503)
+			}
+		default:
+			{
+				w.Write(archive)
+			}
+		}
+	}))
+	defer server.Close()
+
+	cache := tarball.Cache{
+		Dir:  t.TempDir(),
+		HTTP: httpclient.New(httpclient.Policy{Retries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetryAfter: time.Second}),
+	}
+
+	_, integrity, err := cache.Fetch(context.Background(), server.URL+"/pkg-1.2.3.tgz")
+	assert.Nil(t, err)
+	expected, _ := tarball.Integrity(bytes.NewReader(archive))
+	assert.Equal(t, expected, integrity)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestReadPackageJSONMissing(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "*.tgz")
+	assert.Nil(t, err)
+	file.Write(newTarball(t, map[string]string{"package/index.js": "", "package/lib/package.json": "{}"}))
+	file.Close()
+
+	_, err = tarball.ReadPackageJSON(file.Name())
+	assert.ErrorIs(t, err, tarball.ErrPackageJSONMissing)
+}
+
+func TestVerifier(t *testing.T) {
+	body := []byte("module.exports = 1")
+	sha512Integrity, _ := tarball.Integrity(bytes.NewReader(body))
+	// sha1 of body, like npm's "dist.shasum"
+	sha1Integrity := tarball.ShasumIntegrity("18c12027c96f2f319790771d664c58daa9f0334b")
+
+	verifier, err := tarball.NewVerifier(bytes.NewReader(body), sha512Integrity)
+	assert.Nil(t, err)
+	assert.Nil(t, verifier.Verify("pkg"))
+
+	verifier, err = tarball.NewVerifier(bytes.NewReader(body), sha1Integrity)
+	assert.Nil(t, err)
+	assert.Nil(t, verifier.Verify("pkg"))
+
+	// Only the strongest hash is checked
+	verifier, _ = tarball.NewVerifier(bytes.NewReader(body), "sha1-bogus "+sha512Integrity+"?opt")
+	assert.Nil(t, verifier.Verify("pkg"))
+
+	verifier, _ = tarball.NewVerifier(bytes.NewReader([]byte("tampered")), sha512Integrity)
+	var integrityError *tarball.IntegrityError
+	assert.ErrorAs(t, verifier.Verify("pkg"), &integrityError)
+	assert.Equal(t, sha512Integrity, integrityError.Integrity)
+
+	_, err = tarball.NewVerifier(bytes.NewReader(body), "md5-abc")
+	assert.NotNil(t, err)
+	assert.Empty(t, tarball.ShasumIntegrity("not hex"))
+}
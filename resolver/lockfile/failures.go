@@ -0,0 +1,150 @@
+package lockfile
+
+import (
+	"sort"
+	"strings"
+)
+
+type packageFailure struct {
+	name      string
+	version   string
+	status    PackageResolutionStatus
+	parentKey string
+}
+
+func (s PackageResolutionStatus) Description() string {
+	switch s {
+	case PackageResolutionStatusSuccess:
+		{
+			return "ok"
+		}
+	case PackageResolutionStatusMissingName:
+		{
+			return "package.json is missing a name"
+		}
+	case PackageResolutionStatusMissingVersion:
+		{
+			return "package.json is missing a version"
+		}
+	case PackageResolutionStatusNotFound:
+		{
+			return "not found"
+		}
+	case PackageResolutionStatusCorruptPackage:
+		{
+			return "package.json is corrupt"
+		}
+	case PackageResolutionStatusRateLimit:
+		{
+			return "rate limited by the registry"
+		}
+	case PackageResolutionStatusInvalidVersion:
+		{
+			return "no version matches"
+		}
+	case PackageResolutionStatusUnsupportedPlatform:
+		{
+			return "not supported on this platform"
+		}
+	}
+
+	return "internal error"
+}
+
+// recordFailure notes a dependency that couldn't be resolved. Each name & range is only recorded once, and optional dependencies aren't recorded.
+func (p *PackageFlatPack) recordFailure(name string, version string, status PackageResolutionStatus, parentKey string) {
+	p.packageKeysMutex.Lock()
+	defer p.packageKeysMutex.Unlock()
+
+	if p.optional[edgeKey(parentKey, name)] {
+		return
+	}
+
+	for _, failure := range p.failures {
+		if failure.name == name && failure.version == version {
+			return
+		}
+	}
+
+	p.failures = append(p.failures, packageFailure{name: name, version: version, status: status, parentKey: parentKey})
+}
+
+// setParent remembers the first package that depended on key, so failures can show how they were reached.
+func (p *PackageFlatPack) setParent(key string, parentKey string) {
+	p.packageKeysMutex.Lock()
+	defer p.packageKeysMutex.Unlock()
+
+	if _, exists := p.parents[key]; !exists {
+		p.parents[key] = parentKey
+	}
+}
+
+// parentChain lists the keys from the root package's direct dependency down to parentKey.
+func (p *PackageFlatPack) parentChain(parentKey string) []string {
+	chain := make([]string, 0, 4)
+	visited := make(map[string]bool, 4)
+	for key := parentKey; key != "" && !visited[key]; key = p.parents[key] {
+		visited[key] = true
+		chain = append(chain, key)
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+
+	return chain
+}
+
+func (p *PackageFlatPack) buildFailures() PackageResolutionFailures {
+	sort.Slice(p.failures, func(i, j int) bool {
+		if p.failures[i].name != p.failures[j].name {
+			return p.failures[i].name < p.failures[j].name
+		}
+
+		return p.failures[i].version < p.failures[j].version
+	})
+
+	count := len(p.failures)
+	failures := PackageResolutionFailures{
+		Name:        make([]string, count),
+		Range:       make([]string, count),
+		Status:      make([]PackageResolutionStatus, count),
+		ParentCount: make([]uint, count),
+		Parents:     make([]string, 0, count*2),
+	}
+
+	for i, failure := range p.failures {
+		chain := p.parentChain(failure.parentKey)
+		failures.Name[i] = failure.name
+		failures.Range[i] = failure.version
+		failures.Status[i] = failure.status
+		failures.ParentCount[i] = uint(len(chain))
+		failures.Parents = append(failures.Parents, chain...)
+	}
+
+	return failures
+}
+
+// Messages describes each failure on one line, like "left-pad@^9.0.0: no version matches (via a@1.0.0 > b@2.0.0)".
+func (f *PackageResolutionFailures) Messages() []string {
+	messages := make([]string, len(f.Name))
+	offset := uint(0)
+	for i := range f.Name {
+		var b strings.Builder
+		b.WriteString(NewPackageManifestKey(f.Name[i], f.Range[i]))
+		b.WriteString(": ")
+		b.WriteString(f.Status[i].Description())
+
+		parents := f.Parents[offset : offset+f.ParentCount[i]]
+		offset += f.ParentCount[i]
+		if len(parents) > 0 {
+			b.WriteString(" (via ")
+			b.WriteString(strings.Join(parents, " > "))
+			b.WriteString(")")
+		}
+
+		messages[i] = b.String()
+	}
+
+	return messages
+}
@@ -1,8 +1,11 @@
 package lockfile
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"time"
 
 	"github.com/jarred-sumner/devserverless/config"
@@ -10,7 +13,63 @@ import (
 	"go.uber.org/zap"
 )
 
-func (store *PackageManifestStore) fetchFromNPM(name string, version string, parentName string) (*JavascriptPackageManifestPartial, error) {
+// contextDone is true once ctx is cancelled or past its deadline.
+// fasthttp can time out a request a moment before the context's own timer fires, so the deadline is checked too.
+func contextDone(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+func contextError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return context.DeadlineExceeded
+}
+
+// registrarFor is where name comes from: its scope's registry in .npmrc, or Registrar.
+func (store *PackageManifestStore) registrarFor(name string) Registrar {
+	return ScopeRegistrar(store.Registrar, store.Npmrc, name)
+}
+
+// usesPackuments is whether name's versions come from its registrar's packument instead of jsDelivr.
+func (store *PackageManifestStore) usesPackuments(name string) bool {
+	return !store.registrarFor(name).Public() || store.MetadataSource == config.MetadataSourceRegistry
+}
+
+// do sends req with NPMClient through HTTP, or reads it from disk when it's a file:// URL, like a local directory registrar's.
+func (store *PackageManifestStore) do(req *fasthttp.Request, resp *fasthttp.Response, ctx context.Context) error {
+	path, ok := FilePath(string(req.Header.RequestURI()))
+	if !ok {
+		return store.HTTP.Do(ctx, store.NPMClient, req, resp)
+	}
+
+	body, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		resp.SetStatusCode(fasthttp.StatusNotFound)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	resp.SetStatusCode(fasthttp.StatusOK)
+	resp.SetBody(body)
+	return nil
+}
+
+// authorize adds the credentials .npmrc has for req's URL.
+func (store *PackageManifestStore) authorize(req *fasthttp.Request) {
+	if authorization := store.Npmrc.Authorization(req.URI().String(), ""); len(authorization) > 0 {
+		req.Header.Set(fasthttp.HeaderAuthorization, authorization)
+	}
+}
+
+func (store *PackageManifestStore) fetchFromNPM(name string, version string, parentName string, ctx context.Context) (*JavascriptPackageManifestPartial, error) {
 	_req := fasthttp.AcquireRequest()
 	_resp := fasthttp.AcquireResponse()
 
@@ -19,14 +78,20 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 	req := _req
 	resp := _resp
 
-	req.SetRequestURI(store.RegistrarAPI.PackageJSON(name, version))
+	registrar := store.registrarFor(name)
+	req.SetRequestURI(registrar.ManifestURL(name, version))
+	store.authorize(req)
 	var _logger *zap.Logger
-	_logger = store.Logger.With(zap.String("url", req.URI().String()), zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
+	_logger = store.Logger.With(zap.String("url", config.RedactURL(req.URI().String())), zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
 	_logger.Info("GET Dependency")
 
 	// log.Println(fmt.Sprintf(packageJsonFormatterString, name, version))
 	var err error
-	err = store.NPMClient.DoDeadline(req, resp, time.Now().Add(time.Minute))
+	if contextDone(ctx) {
+		return nil, contextError(ctx)
+	}
+
+	err = store.do(req, resp, ctx)
 	statusCode := resp.StatusCode()
 	var loc string
 	if statusCode == 302 || statusCode == 301 {
@@ -34,21 +99,29 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 		if len(loc) > 0 {
 			_req := fasthttp.AcquireRequest()
 			uri := fasthttp.AcquireURI()
-			uri.Update(store.RegistrarAPI.PackageJSON(name, version))
+			uri.Update(registrar.ManifestURL(name, version))
 			uri.Update(loc)
 			_req.SetRequestURI(uri.String())
 			fasthttp.ReleaseURI(uri)
+			store.authorize(_req)
 			defer fasthttp.ReleaseRequest(_req)
+			_resp := fasthttp.AcquireResponse()
 			defer fasthttp.ReleaseResponse(_resp)
-			_resp = fasthttp.AcquireResponse()
 
 			req = _req
 			resp = _resp
 
-			err = store.NPMClient.DoDeadline(_req, _resp, time.Now().Add(time.Minute))
+			err = store.HTTP.Do(ctx, store.NPMClient, _req, _resp)
 			statusCode = resp.StatusCode()
 		}
 	}
+
+	// Other resolutions may be waiting on this package, so a cancelled request must not be cached.
+	if contextDone(ctx) {
+		_logger.Debug("Cancelled")
+		return nil, contextError(ctx)
+	}
+
 	_logger = _logger.With(zap.Int("statusCode", statusCode))
 	var manifest JavascriptPackageManifestPartial
 
@@ -60,13 +133,16 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 		return &manifest, err
 	}
 
-	switch statusCode {
-	case 200:
+	switch {
+	case statusCode == 200:
 		{
 			var body []byte
 			encoding := string(resp.Header.Peek(fasthttp.HeaderContentEncoding))
 			switch encoding {
 			case "deflate":
+				{
+					body, err = resp.BodyInflate()
+				}
 			case "gzip":
 				{
 					body, err = resp.BodyGunzip()
@@ -87,7 +163,7 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 				return &manifest, err
 			}
 
-			manifest, err = NewJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, false)
+			manifest, err = registrar.DecodeManifest(body)
 
 			if err != nil {
 				manifest = NewJavascriptPackageManifestWithError(name, version, PackageResolutionStatusInternal)
@@ -99,7 +175,7 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 
 			_logger.Debug("Success")
 		}
-	case 404:
+	case statusCode == 404:
 		{
 			err = errors.New(fmt.Sprintf("package \"%s\" : \"%s\" not found", name, version))
 			_logger.Debug("Fail")
@@ -108,13 +184,7 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 			return &manifest, err
 		}
 
-	case 509:
-	case 505:
-	case 504:
-	case 503:
-	case 502:
-	case 501:
-	case 500:
+	case statusCode >= 500:
 		{
 			err = errors.New("internal error while validating package")
 			_logger.Debug("Fail")
@@ -123,7 +193,7 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 			return &manifest, err
 		}
 
-	case 429:
+	case statusCode == 429:
 		{
 			err = errors.New("too many requests")
 			manifest = NewJavascriptPackageManifestWithError(name, version, PackageResolutionStatusRateLimit)
@@ -145,7 +215,7 @@ func (store *PackageManifestStore) fetchFromNPM(name string, version string, par
 	return &manifest, err
 }
 
-func (store *PackageManifestStore) fetchFromGithub(name string, version string, parentName string, partialURL string) (*JavascriptPackageManifestPartial, error) {
+func (store *PackageManifestStore) fetchFromGithub(name string, version string, parentName string, partialURL string, ctx context.Context) (*JavascriptPackageManifestPartial, error) {
 	_req := fasthttp.AcquireRequest()
 	_resp := fasthttp.AcquireResponse()
 
@@ -156,12 +226,16 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 
 	req.SetRequestURI(partialURL)
 	var _logger *zap.Logger
-	_logger = store.Logger.With(zap.String("url", req.URI().String()), zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
+	_logger = store.Logger.With(zap.String("url", config.RedactURL(req.URI().String())), zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
 	_logger.Info("GET Dependency")
 
 	// log.Println(fmt.Sprintf(packageJsonFormatterString, name, version))
 	var err error
-	err = store.NPMClient.DoDeadline(req, resp, time.Now().Add(time.Minute))
+	if contextDone(ctx) {
+		return nil, contextError(ctx)
+	}
+
+	err = store.HTTP.Do(ctx, store.NPMClient, req, resp)
 	statusCode := resp.StatusCode()
 	var loc string
 	if statusCode == 302 || statusCode == 301 {
@@ -169,21 +243,28 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 		if len(loc) > 0 {
 			_req := fasthttp.AcquireRequest()
 			uri := fasthttp.AcquireURI()
-			uri.Update(store.RegistrarAPI.PackageJSON(name, version))
+			uri.Update(store.registrarFor(name).ManifestURL(name, version))
 			uri.Update(loc)
 			_req.SetRequestURI(uri.String())
 			fasthttp.ReleaseURI(uri)
 			defer fasthttp.ReleaseRequest(_req)
+			_resp := fasthttp.AcquireResponse()
 			defer fasthttp.ReleaseResponse(_resp)
-			_resp = fasthttp.AcquireResponse()
 
 			req = _req
 			resp = _resp
 
-			err = store.NPMClient.DoDeadline(_req, _resp, time.Now().Add(time.Minute))
+			err = store.HTTP.Do(ctx, store.NPMClient, _req, _resp)
 			statusCode = resp.StatusCode()
 		}
 	}
+
+	// Other resolutions may be waiting on this package, so a cancelled request must not be cached.
+	if contextDone(ctx) {
+		_logger.Debug("Cancelled")
+		return nil, contextError(ctx)
+	}
+
 	_logger = _logger.With(zap.Int("statusCode", statusCode))
 	var manifest JavascriptPackageManifestPartial
 
@@ -195,13 +276,16 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 		return &manifest, err
 	}
 
-	switch statusCode {
-	case 200:
+	switch {
+	case statusCode == 200:
 		{
 			var body []byte
 			encoding := string(resp.Header.Peek(fasthttp.HeaderContentEncoding))
 			switch encoding {
 			case "deflate":
+				{
+					body, err = resp.BodyInflate()
+				}
 			case "gzip":
 				{
 					body, err = resp.BodyGunzip()
@@ -234,7 +318,7 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 
 			_logger.Debug("Success")
 		}
-	case 404:
+	case statusCode == 404:
 		{
 			err = errors.New(fmt.Sprintf("package \"%s\" : \"%s\" not found", name, version))
 			_logger.Debug("Fail")
@@ -243,13 +327,7 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 			return &manifest, err
 		}
 
-	case 509:
-	case 505:
-	case 504:
-	case 503:
-	case 502:
-	case 501:
-	case 500:
+	case statusCode >= 500:
 		{
 			err = errors.New("internal error while validating package")
 			_logger.Debug("Fail")
@@ -258,7 +336,7 @@ func (store *PackageManifestStore) fetchFromGithub(name string, version string,
 			return &manifest, err
 		}
 
-	case 429:
+	case statusCode == 429:
 		{
 			err = errors.New("too many requests")
 			manifest = NewJavascriptPackageManifestWithError(name, version, PackageResolutionStatusRateLimit)
@@ -0,0 +1,173 @@
+package lockfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Packages rebuilds a manifest for each package in the lockfile, so it can be installed without resolving anything.
+// Only the name, version, provider, integrity & platforms are in the lockfile, so everything else is empty.
+func (p *JavascriptPackageManifest) Packages() []JavascriptPackageManifestPartial {
+	packages := make([]JavascriptPackageManifestPartial, len(p.Name))
+	for i, name := range p.Name {
+		version := p.Version[i]
+		protocol := NewPackageVersionProtocol(version, len(version))
+		packages[i] = JavascriptPackageManifestPartial{
+			Name:     name,
+			Provider: protocol.Provider(),
+			Status:   PackageResolutionStatusSuccess,
+			// Not SetVersion, since it lowercases paths & URLs
+			Version: Version{
+				Protocol:    protocol,
+				OriginalTag: version,
+				Tag:         version,
+			},
+			Integrity: p.Integrity[i],
+			Os:        splitPlatformList(p.Os[i]),
+			Cpu:       splitPlatformList(p.Cpu[i]),
+			Libc:      splitPlatformList(p.Libc[i]),
+		}
+	}
+
+	return packages
+}
+
+// Validate checks that every list with an item per package has one for each package, and that dependencies point at packages that exist.
+// Lockfiles that are cut short or from an older version of ducky decode without an error, but don't pass.
+func (p *JavascriptPackageManifest) Validate() error {
+	count := len(p.Name)
+	lists := []struct {
+		name   string
+		length int
+	}{
+		{"version", len(p.Version)},
+		{"dependencyIndex", len(p.DependencyIndex)},
+		{"groups", len(p.Groups)},
+		{"integrity", len(p.Integrity)},
+		{"os", len(p.Os)},
+		{"cpu", len(p.Cpu)},
+		{"libc", len(p.Libc)},
+	}
+
+	for _, list := range lists {
+		if list.length != count {
+			return fmt.Errorf("lockfile has %d packages, but %d items in %s", count, list.length, list.name)
+		}
+	}
+
+	dependencyCount := uint(0)
+	for _, length := range p.DependencyIndex {
+		dependencyCount += length
+	}
+
+	if dependencyCount != uint(len(p.Dependencies)) {
+		return fmt.Errorf("lockfile has %d dependencies, but dependencyIndex adds up to %d", len(p.Dependencies), dependencyCount)
+	}
+
+	for _, indices := range [][]uint{p.Dependencies, p.RootDependencies} {
+		for _, index := range indices {
+			if index >= uint(count) {
+				return fmt.Errorf("lockfile has %d packages, but depends on package %d", count, index)
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitPlatformList reads an "os", "cpu" or "libc" list the lockfile saved as "darwin,linux".
+func splitPlatformList(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
+
+// Provider is where a package with a resolved version of this protocol comes from.
+func (p PackageVersionProtocol) Provider() PackageProvider {
+	switch p {
+	case PackageVersionProtocolGithubBare, PackageVersionProtocolGithubDotCom, PackageVersionProtocolGithubTarball, PackageVersionProtocolGithubOwnerRepo:
+		{
+			return PackageProviderGithub
+		}
+	case PackageVersionProtocolGit, PackageVersionProtocolGitSsh:
+		{
+			return PackageProviderGit
+		}
+	case PackageVersionProtocolHttpsTarball, PackageVersionProtocolHttpTarball:
+		{
+			return PackageProviderTgz
+		}
+	case PackageVersionProtocolPathlike:
+		{
+			return PackageProviderDisk
+		}
+	case PackageVersionProtocolWorkspace:
+		{
+			return PackageProviderWorkspace
+		}
+	}
+
+	// npm aliases are installed from the package they point to
+	return PackageProviderNpm
+}
+
+// WorkspaceHashInputs are what GenerateWorkspaceHash hashes, for showing how package.json changed since the lockfile was saved.
+// Each workspace's are prefixed with its path.
+func WorkspaceHashInputs(root *JavascriptPackageManifestPartial, workspaces []Workspace) []string {
+	inputs := root.HashInputs()
+	for _, workspace := range workspaces {
+		prefix := workspace.Path + ":"
+		inputs = append(inputs, prefix+NewPackageManifestKey(workspace.Manifest.Name, workspace.Manifest.Version.Tag))
+		for _, input := range workspace.Manifest.HashInputs() {
+			inputs = append(inputs, prefix+input)
+		}
+	}
+
+	return inputs
+}
+
+// DiffHashInputs lists the inputs only in saved as "- input", and the ones only in current as "+ input".
+// They're sorted by input, so a range that changed is a "-" & "+" pair.
+func DiffHashInputs(saved []string, current []string) []string {
+	inSaved := make(map[string]bool, len(saved))
+	for _, input := range saved {
+		inSaved[input] = true
+	}
+
+	inCurrent := make(map[string]bool, len(current))
+	for _, input := range current {
+		inCurrent[input] = true
+	}
+
+	var inputs []string
+	for _, input := range saved {
+		if !inCurrent[input] {
+			inputs = append(inputs, input)
+		}
+	}
+
+	removed := len(inputs)
+	for _, input := range current {
+		if !inSaved[input] {
+			inputs = append(inputs, input)
+		}
+	}
+
+	lines := make([]string, len(inputs))
+	for i, input := range inputs {
+		if i < removed {
+			lines[i] = "- " + input
+		} else {
+			lines[i] = "+ " + input
+		}
+	}
+
+	sort.SliceStable(lines, func(a, b int) bool {
+		return lines[a][2:] < lines[b][2:]
+	})
+
+	return lines
+}
@@ -0,0 +1,109 @@
+package lockfile_test
+
+import (
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJavascriptPackageManifestPackages(t *testing.T) {
+	manifest := lockfile.JavascriptPackageManifest{
+		Name: []string{"react", "string-width-cjs", "my-lib", "peek", "tool", "ui"},
+		Version: []string{
+			"17.0.2",
+			"npm:string-width@4.2.3",
+			"file:../My-Lib",
+			"git+https://github.com/Jarred-Sumner/git-peek.git#0123abc",
+			"https://example.com/Tool-1.0.0.tgz",
+			lockfile.NewWorkspaceVersion("packages/ui"),
+		},
+		Integrity: []string{"sha512-react", "sha512-string-width", "", "", "sha512-tool", ""},
+		Os:        []string{"", "", "", "darwin,linux", "!win32", ""},
+		Cpu:       []string{"", "", "", "arm64", "", ""},
+		Libc:      []string{"", "", "", "", "", ""},
+	}
+
+	packages := manifest.Packages()
+	providers := make([]lockfile.PackageProvider, len(packages))
+	for i, pkg := range packages {
+		providers[i] = pkg.Provider
+		assert.Equal(t, manifest.Name[i], pkg.Name)
+		// Paths & URLs keep their case
+		assert.Equal(t, manifest.Version[i], pkg.Version.Tag)
+		assert.Equal(t, manifest.Integrity[i], pkg.Integrity)
+	}
+
+	assert.Equal(t, []lockfile.PackageProvider{
+		lockfile.PackageProviderNpm,
+		lockfile.PackageProviderNpm,
+		lockfile.PackageProviderDisk,
+		lockfile.PackageProviderGit,
+		lockfile.PackageProviderTgz,
+		lockfile.PackageProviderWorkspace,
+	}, providers)
+
+	assert.Nil(t, packages[0].Os)
+	assert.Equal(t, []string{"darwin", "linux"}, packages[3].Os)
+	assert.Equal(t, []string{"arm64"}, packages[3].Cpu)
+	assert.Equal(t, []string{"!win32"}, packages[4].Os)
+	assert.Nil(t, packages[4].Libc)
+}
+
+func TestJavascriptPackageManifestValidate(t *testing.T) {
+	valid := func() lockfile.JavascriptPackageManifest {
+		return lockfile.JavascriptPackageManifest{
+			Name:             []string{"debug", "ms"},
+			Version:          []string{"4.3.1", "2.1.2"},
+			DependencyIndex:  []uint{1, 0},
+			Dependencies:     []uint{1},
+			RootDependencies: []uint{0},
+			Groups:           []uint{1, 1},
+			Integrity:        []string{"sha512-debug", "sha512-ms"},
+			Os:               []string{"", ""},
+			Cpu:              []string{"", ""},
+			Libc:             []string{"", ""},
+		}
+	}
+
+	manifest := valid()
+	assert.Nil(t, manifest.Validate())
+
+	// Like a lockfile saved before platforms were
+	manifest.Os, manifest.Cpu, manifest.Libc = nil, nil, nil
+	assert.EqualError(t, manifest.Validate(), "lockfile has 2 packages, but 0 items in os")
+
+	manifest = valid()
+	manifest.Integrity = manifest.Integrity[:1]
+	assert.EqualError(t, manifest.Validate(), "lockfile has 2 packages, but 1 items in integrity")
+
+	manifest = valid()
+	manifest.DependencyIndex = []uint{2, 0}
+	assert.EqualError(t, manifest.Validate(), "lockfile has 1 dependencies, but dependencyIndex adds up to 2")
+
+	manifest = valid()
+	manifest.RootDependencies = []uint{2}
+	assert.EqualError(t, manifest.Validate(), "lockfile has 2 packages, but depends on package 2")
+}
+
+func TestDiffHashInputs(t *testing.T) {
+	saved := []byte(`{"name": "app", "dependencies": {"react": "^17.0.2", "left-pad": "1.3.0"}}`)
+	current := []byte(`{"name": "app", "dependencies": {"react": "^18.0.0"}, "devDependencies": {"vite": "^2.0.0"}}`)
+
+	savedManifest, err := lockfile.NewJavascriptPackageManifestPartial(&saved, false, true)
+	assert.Nil(t, err)
+	currentManifest, err := lockfile.NewJavascriptPackageManifestPartial(&current, false, true)
+	assert.Nil(t, err)
+
+	workspaces := []lockfile.Workspace{{Path: "packages/ui", Manifest: savedManifest}}
+	assert.Contains(t, lockfile.WorkspaceHashInputs(&currentManifest, workspaces), "packages/ui:react@^17.0.2")
+
+	assert.Equal(t, []string{
+		"- left-pad@1.3.0",
+		"- react@^17.0.2",
+		"+ react@^18.0.0",
+		"+ vite@^2.0.0",
+	}, lockfile.DiffHashInputs(savedManifest.HashInputs(), currentManifest.HashInputs()))
+
+	assert.Empty(t, lockfile.DiffHashInputs(currentManifest.HashInputs(), currentManifest.HashInputs()))
+}
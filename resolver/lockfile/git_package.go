@@ -0,0 +1,159 @@
+package lockfile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/git"
+	"go.uber.org/zap"
+)
+
+var errGitCacheDirMissing = errors.New("git dependencies need a cache folder")
+
+// git+https://, git+http:// and git+file:// are cloned without the "git+"
+var gitPlusPrefixes = [...]string{"git+https://", "git+http://", "git+file://"}
+
+func isGitPlusPrefix(version string, length int) bool {
+	for _, prefix := range gitPlusPrefixes {
+		if length > len(prefix) && version[0:len(prefix)] == prefix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SplitGitURL splits a git dependency into the URL git clones and the fragment after "#".
+// "git+ssh://git@host:org/repo.git" is rewritten to the scp-like "git@host:org/repo.git" that git expects.
+func SplitGitURL(version string) (url string, fragment string) {
+	url = version
+	if hash := strings.LastIndexByte(version, HASHTAG_BYTE); hash > -1 {
+		url = version[:hash]
+		fragment = version[hash+1:]
+	}
+
+	url = strings.TrimPrefix(url, "git+")
+	if strings.HasPrefix(url, "ssh://") {
+		hostAndPath := url[len("ssh://"):]
+		slash := strings.IndexByte(hostAndPath, SLASH_BYTE)
+		colon := strings.IndexByte(hostAndPath, ':')
+		// A colon before the path that isn't a port
+		if colon > -1 && (slash == -1 || colon < slash) && (colon+1 == len(hostAndPath) || hostAndPath[colon+1] < '0' || hostAndPath[colon+1] > '9') {
+			url = hostAndPath
+		}
+	}
+
+	return url, fragment
+}
+
+// NewGitVersion is the version a git dependency is stored under, pinned to commit.
+func NewGitVersion(version string, commit string) string {
+	if hash := strings.LastIndexByte(version, HASHTAG_BYTE); hash > -1 {
+		version = version[:hash]
+	}
+
+	return version + "#" + commit
+}
+
+func (p *PackageFlatPack) setGitKey(requestKey string, key string) {
+	p.packageKeysMutex.Lock()
+	defer p.packageKeysMutex.Unlock()
+	p.gitKeys[requestKey] = key
+}
+
+// gitKey returns the commit-pinned key that a git dependency resolved to.
+func (p *PackageFlatPack) gitKey(requestKey string) (string, bool) {
+	p.packageKeysMutex.Lock()
+	defer p.packageKeysMutex.Unlock()
+	key, ok := p.gitKeys[requestKey]
+	return key, ok
+}
+
+// enqueueGitPackage resolves a git dependency to a commit with a mirror in the store's GitCacheDir, then reads package.json from that commit.
+func (p *PackageFlatPack) enqueueGitPackage(name string, version string, parentName string, parentKey string, protocol PackageVersionProtocol) {
+	s := p.store
+	if s.GitCacheDir == "" {
+		s.Logger.Warn("Skipping git dependency", zap.String("name", name), zap.String("version", config.RedactURL(version)), zap.String("parent", parentName), zap.Error(errGitCacheDirMissing))
+		p.recordFailure(name, version, PackageResolutionStatusNotFound, parentKey)
+		atomic.AddUint64(&p.ErrorPackageCount, 1)
+		return
+	}
+
+	w := p.Waiter
+	w.Add(1)
+	s.PackageJSONWorkers.Submit(func() {
+		defer w.Done()
+		if contextDone(p.ctx) {
+			return
+		}
+
+		manifest, status, err := p.store.FetchGitPackage(name, version, protocol, p.ctx)
+		if err != nil {
+			if contextDone(p.ctx) {
+				return
+			}
+
+			s.Logger.Warn("Failed to resolve git dependency", zap.String("name", name), zap.String("version", config.RedactURL(version)), zap.String("parent", parentName), zap.Error(err))
+			p.recordFailure(name, version, status, parentKey)
+			atomic.AddUint64(&p.ErrorPackageCount, 1)
+			return
+		}
+
+		key := NewPackageManifestKey(name, manifest.Version.Tag)
+		p.setGitKey(NewPackageManifestKey(name, version), key)
+		p.setParent(key, parentKey)
+
+		if !p.claim(key) {
+			if s.Installer != nil {
+				s.Installer.Enqueue(manifest)
+			}
+			return
+		}
+
+		p.appendCachedManifest(key, manifest, version, parentKey)
+	})
+}
+
+// FetchGitPackage reads package.json from the commit version points to. Manifests are cached by commit, since a commit never changes.
+func (s *PackageManifestStore) FetchGitPackage(name string, version string, protocol PackageVersionProtocol, ctx context.Context) (*JavascriptPackageManifestPartial, PackageResolutionStatus, error) {
+	url, fragment := SplitGitURL(version)
+	repo, err := git.Open(ctx, s.GitCacheDir, url)
+	if err != nil {
+		return nil, PackageResolutionStatusNotFound, err
+	}
+
+	commit, err := repo.Resolve(ctx, fragment)
+	if err != nil {
+		return nil, PackageResolutionStatusInvalidVersion, err
+	}
+
+	pinned := NewGitVersion(version, commit)
+	if manifest, ok := s.Manifests.Get(name, pinned); ok && manifest.Status == PackageResolutionStatusSuccess {
+		return manifest, PackageResolutionStatusSuccess, nil
+	}
+
+	body, err := repo.Show(ctx, commit, "package.json")
+	if err != nil {
+		return nil, PackageResolutionStatusNotFound, err
+	}
+
+	manifest, err := NewJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, false)
+	if err != nil {
+		return nil, PackageResolutionStatusCorruptPackage, err
+	}
+
+	manifest.Name = name
+	manifest.Provider = PackageProviderGit
+	manifest.Status = PackageResolutionStatusSuccess
+	manifest.Version = Version{
+		Protocol:    protocol,
+		OriginalTag: pinned,
+		Tag:         pinned,
+	}
+
+	s.Manifests.Put(name, pinned, &manifest)
+	return &manifest, PackageResolutionStatusSuccess, nil
+}
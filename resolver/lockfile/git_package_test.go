@@ -0,0 +1,82 @@
+package lockfile_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, out)
+	}
+
+	return string(bytes.TrimSpace(out))
+}
+
+func TestResolveDependenciesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	work := t.TempDir()
+	runGit(t, work, "init", "--quiet")
+	for _, version := range []string{"1.0.0", "1.2.0"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(work, "package.json"), []byte(`{"name": "my-lib", "version": "`+version+`", "main": "index.js"}`), 0644))
+		runGit(t, work, "add", "package.json")
+		runGit(t, work, "commit", "--quiet", "-m", version)
+		runGit(t, work, "tag", "v"+version)
+	}
+	v100 := runGit(t, work, "rev-parse", "v1.0.0^{commit}")
+	v120 := runGit(t, work, "rev-parse", "v1.2.0^{commit}")
+
+	bare := filepath.Join(t.TempDir(), "my-lib.git")
+	runGit(t, work, "clone", "--quiet", "--bare", work, bare)
+	url := "git+file://" + bare
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.GitCacheDir = t.TempDir()
+
+	root := lockfile.JavascriptPackageManifestPartial{
+		Name:               "root",
+		Status:             lockfile.PackageResolutionStatusSuccess,
+		DependencyNames:    []string{"lib-semver", "lib-tag", "lib-missing"},
+		DependencyVersions: []string{url + "#semver:^1.0.0", url + "#v1.0.0", url + "#no-such-branch"},
+	}
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"lib-semver", "lib-tag"}, manifest.Name)
+	assert.Equal(t, []string{url + "#" + v120, url + "#" + v100}, manifest.Version)
+	assert.Equal(t, []uint{0, 1}, manifest.RootDependencies)
+	assert.Equal(t, []string{"lib-missing"}, manifest.Failures.Name)
+	assert.Equal(t, []lockfile.PackageResolutionStatus{lockfile.PackageResolutionStatusInvalidVersion}, manifest.Failures.Status)
+}
+
+func TestSplitGitURL(t *testing.T) {
+	url, fragment := lockfile.SplitGitURL("git+ssh://git@gitea.example.com:acme/lib.git#semver:^1.0.0")
+	assert.Equal(t, "git@gitea.example.com:acme/lib.git", url)
+	assert.Equal(t, "semver:^1.0.0", fragment)
+
+	url, fragment = lockfile.SplitGitURL("git+ssh://git@gitlab.example.com:2222/acme/lib.git")
+	assert.Equal(t, "ssh://git@gitlab.example.com:2222/acme/lib.git", url)
+	assert.Equal(t, "", fragment)
+
+	url, fragment = lockfile.SplitGitURL("git+https://gitlab.example.com/acme/lib.git#main")
+	assert.Equal(t, "https://gitlab.example.com/acme/lib.git", url)
+	assert.Equal(t, "main", fragment)
+
+	for _, version := range []string{"git+https://gitlab.example.com/acme/lib.git", "git+file:///srv/lib.git", "git://gitlab.example.com/acme/lib.git"} {
+		assert.Equal(t, lockfile.PackageVersionProtocolGit, lockfile.NewPackageVersionProtocol(version, len(version)), version)
+	}
+}
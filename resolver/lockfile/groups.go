@@ -0,0 +1,99 @@
+package lockfile
+
+// DefaultDependencyGroups is what resolves when nothing else is picked: everything except devDependencies.
+const DefaultDependencyGroups = DependencyGroupProd | DependencyGroupOptional | DependencyGroupPeer
+
+// Has reports whether every group in other is in g.
+func (g DependencyGroup) Has(other DependencyGroup) bool {
+	return g&other == other
+}
+
+// dependencyGroups is Groups, or DefaultDependencyGroups when it's empty. dependencies & optionalDependencies always resolve.
+func (o ResolveOptions) dependencyGroups() DependencyGroup {
+	if o.Groups == 0 {
+		return DefaultDependencyGroups
+	}
+
+	return o.Groups | DependencyGroupProd | DependencyGroupOptional
+}
+
+// DependencyGroups is the groups a request asked for. Older clients don't send any, so they get DefaultDependencyGroups.
+func (r *JavascriptPackageRequest) DependencyGroups() DependencyGroup {
+	if r.Groups == nil {
+		return 0
+	}
+
+	return DependencyGroup(*r.Groups)
+}
+
+// eachDependencyGroup calls cb with each of manifest's dependency lists in groups.
+// Only the root & workspace packages have their devDependencies and peerDependencies resolved, so direct is false for everything else.
+func eachDependencyGroup(manifest *JavascriptPackageManifestPartial, groups DependencyGroup, direct bool, cb func(group DependencyGroup, names []string, versions []string)) {
+	cb(DependencyGroupProd, manifest.DependencyNames, manifest.DependencyVersions)
+
+	if direct && groups.Has(DependencyGroupDev) {
+		cb(DependencyGroupDev, manifest.DevDependencyNames, manifest.DevDependencyVersions)
+	}
+
+	if direct && groups.Has(DependencyGroupPeer) {
+		cb(DependencyGroupPeer, manifest.PeerDependencyNames, manifest.PeerDependencyVersions)
+	}
+
+	cb(DependencyGroupOptional, manifest.OptionalDependencyNames, manifest.OptionalDependencyVersions)
+}
+
+// buildGroups finds which groups brought in each package, as a bitmask of DependencyGroup.
+// The root & workspace packages' own dependency lists pick the group, and everything below them inherits it.
+func (s *PackageFlatPack) buildGroups(full *JavascriptPackageManifest, pkg *JavascriptPackageManifestPartial, keysList []string, keysIndex map[string]uint) []uint {
+	groups := make([]uint, len(keysList))
+	queue := make([]uint, 0, len(keysList))
+
+	visit := func(index uint, group uint) {
+		if groups[index]|group == groups[index] {
+			return
+		}
+
+		groups[index] |= group
+		queue = append(queue, index)
+	}
+
+	seed := func(manifest *JavascriptPackageManifestPartial, parentKey string) {
+		eachDependencyGroup(manifest, s.groups, true, func(group DependencyGroup, names []string, versions []string) {
+			for i, name := range names {
+				if index, ok := keysIndex[s.resolvedKey(name, versions[i], parentKey)]; ok {
+					visit(index, uint(group))
+				}
+			}
+		})
+	}
+
+	seed(pkg, "")
+	for index, key := range keysList {
+		if !s.roots[key] {
+			continue
+		}
+
+		// Workspace packages are always installed
+		visit(uint(index), uint(DependencyGroupProd))
+		if manifest, ok := s.GetManifest(key); ok {
+			seed(manifest, key)
+		}
+	}
+
+	lists := full.DependencyLists()
+	for len(queue) > 0 {
+		index := queue[0]
+		queue = queue[1:]
+
+		// Their dependencies were already seeded with their own groups
+		if s.roots[keysList[index]] {
+			continue
+		}
+
+		for _, dependency := range lists[index] {
+			visit(dependency, groups[index])
+		}
+	}
+
+	return groups
+}
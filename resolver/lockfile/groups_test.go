@@ -0,0 +1,50 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesGroups(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"app-lib":   `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"shared":    `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"test-tool": `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"react":     `{"tags": {"latest": "17.0.2"}, "versions": ["17.0.2"]}`,
+		},
+		map[string]string{
+			"app-lib/1.0.0":   `{"name": "app-lib", "version": "1.0.0", "dependencies": {"shared": "^1.0.0"}, "devDependencies": {"test-tool": "^1.0.0"}}`,
+			"shared/1.0.0":    `{"name": "shared", "version": "1.0.0"}`,
+			"test-tool/1.0.0": `{"name": "test-tool", "version": "1.0.0", "dependencies": {"shared": "^1.0.0"}}`,
+			"react/17.0.2":    `{"name": "react", "version": "17.0.2"}`,
+		},
+	)
+
+	body := []byte(`{
+		"name": "root",
+		"dependencies": {"app-lib": "^1.0.0"},
+		"devDependencies": {"test-tool": "^1.0.0"},
+		"peerDependencies": {"react": "^17.0.0"}
+	}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+
+	// By default, devDependencies don't resolve, even for packages from the registry
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app-lib", "react", "shared"}, manifest.Name)
+	prod, peer := uint(lockfile.DependencyGroupProd), uint(lockfile.DependencyGroupPeer)
+	assert.Equal(t, []uint{prod, peer, prod}, manifest.Groups)
+
+	manifest, err = store.ResolveDependenciesWithOptions(&root, lockfile.ResolveOptions{Groups: lockfile.DependencyGroupProd | lockfile.DependencyGroupDev}, context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app-lib", "shared", "test-tool"}, manifest.Name)
+	dev := uint(lockfile.DependencyGroupDev)
+	// shared is needed by both
+	assert.Equal(t, []uint{prod, prod | dev, dev}, manifest.Groups)
+	assert.ElementsMatch(t, []uint{0, 2}, manifest.RootDependencies)
+}
@@ -3,6 +3,7 @@ package lockfile
 import (
 	"fmt"
 	"path"
+	"strings"
 
 	jsoniter "github.com/json-iterator/go"
 )
@@ -24,20 +25,74 @@ func normalizeName(name string, source string) string {
 	}
 }
 
+// URLs point at the real package, even when it's imported under an npm alias.
+// Workspace & path packages are served from their folder instead of hostBaseURL.
+func packageBaseURL(manifest *JavascriptPackageManifest, hostBaseURL string, i uint) string {
+	if localPath, ok := LocalPackagePath(manifest.Version[i]); ok {
+		return localPackageURL(localPath)
+	}
+
+	name, version := NpmAliasTarget(manifest.Name[i], manifest.Version[i])
+	return fmt.Sprintf("%s%s@%s/", hostBaseURL, name, version)
+}
+
+func packageBareURL(manifest *JavascriptPackageManifest, hostBaseURL string, i uint) string {
+	if localPath, ok := LocalPackagePath(manifest.Version[i]); ok {
+		return strings.TrimSuffix(localPackageURL(localPath), "/") + normalizeName(manifest.ExportsManifest.Bare[i], manifest.ExportsManifest.Bare[i])
+	}
+
+	name, version := NpmAliasTarget(manifest.Name[i], manifest.Version[i])
+	return fmt.Sprintf("%s%s@%s%s", hostBaseURL, name, version, normalizeName(manifest.ExportsManifest.Bare[i], manifest.ExportsManifest.Bare[i]))
+}
+
 func NewImportMap(manifest *JavascriptPackageManifest, hostBaseURL string) ([]byte, error) {
+	return newImportMap(manifest, hostBaseURL, manifest.RootDependencies)
+}
+
+// NewWorkspaceImportMap is the import map for the workspace package at index. Its own dependencies are top-level instead of the root package's.
+func NewWorkspaceImportMap(manifest *JavascriptPackageManifest, hostBaseURL string, index uint) ([]byte, error) {
+	return newImportMap(manifest, hostBaseURL, manifest.DependencyLists()[index])
+}
+
+func newImportMap(manifest *JavascriptPackageManifest, hostBaseURL string, roots []uint) ([]byte, error) {
+	// Only the root package.json's folder is served, so nothing could load them
+	for i, version := range manifest.Version {
+		if localPath, ok := LocalPackagePath(version); ok && isOutsideRoot(localPath) {
+			return nil, fmt.Errorf("%s is at %s, outside the folder with package.json, so the import map can't point at it. Move it into that folder, or make it a workspace", manifest.Name[i], localPath)
+		}
+	}
+
 	importMap := ImportMap{
 		Imports: make(map[string]string, manifest.Count*2),
-		Scopes:  make(ScopesMap, manifest.Count*2),
+		Scopes:  make(ScopesMap),
 	}
 
-	i := uint(0)
+	topLevel := manifest.TopLevelIndicesFrom(roots)
+
+	for name, i := range topLevel {
+		importMap.Imports[name] = packageBareURL(manifest, hostBaseURL, i)
+		importMap.Imports[name+"/"] = packageBaseURL(manifest, hostBaseURL, i)
+	}
+
+	// When a package resolved one of its dependencies to a different version than the top-level one,
+	// it gets a scope so that it imports the version its own range asked for.
+	for i, dependencies := range manifest.DependencyLists() {
+		var scope map[string]string
+
+		for _, dep := range dependencies {
+			name := manifest.Name[dep]
+			if topLevel[name] == dep {
+				continue
+			}
 
-	for i < manifest.Count {
-		importMap.Imports[manifest.Name[i]] = fmt.Sprintf("%s%s@%s%s", hostBaseURL, manifest.Name[i], manifest.Version[i], normalizeName(manifest.ExportsManifest.Bare[i], manifest.ExportsManifest.Bare[i]))
-		baseURL := fmt.Sprintf("%s%s@%s/", hostBaseURL, manifest.Name[i], manifest.Version[i])
-		importMap.Imports[manifest.Name[i]+"/"] = baseURL
+			if scope == nil {
+				scope = make(map[string]string, len(dependencies))
+				importMap.Scopes[packageBaseURL(manifest, hostBaseURL, uint(i))] = scope
+			}
 
-		i++
+			scope[name] = packageBareURL(manifest, hostBaseURL, dep)
+			scope[name+"/"] = packageBaseURL(manifest, hostBaseURL, dep)
+		}
 	}
 
 	return jsoniter.Marshal(importMap)
@@ -0,0 +1,93 @@
+package lockfile_test
+
+import (
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+const importMapHost = "https://cdn.example.com/"
+
+// a@1.0.0 -> react-is@16.13.1, b@1.0.0 -> react-is@17.0.2
+func coexistingVersionsManifest(rootDependencies []uint) lockfile.JavascriptPackageManifest {
+	return lockfile.JavascriptPackageManifest{
+		Count:            4,
+		Name:             []string{"a", "b", "react-is", "react-is"},
+		Version:          []string{"1.0.0", "1.0.0", "16.13.1", "17.0.2"},
+		DependencyIndex:  []uint{1, 1, 0, 0},
+		Dependencies:     []uint{2, 3},
+		RootDependencies: rootDependencies,
+		ExportsManifest: lockfile.ExportsManifest{
+			Bare:      []string{"index.js", "index.js", "index.js", "index.js"},
+			BareField: make([]lockfile.BareField, 4),
+		},
+		ExportsManifestIndex: make([]uint, 8),
+	}
+}
+
+func decodeImportMap(t *testing.T, manifest *lockfile.JavascriptPackageManifest) lockfile.ImportMap {
+	bytes, err := lockfile.NewImportMap(manifest, importMapHost)
+	assert.Nil(t, err)
+
+	importMap := lockfile.ImportMap{}
+	assert.Nil(t, jsoniter.Unmarshal(bytes, &importMap))
+	return importMap
+}
+
+func TestImportMapScopesCoexistingVersions(t *testing.T) {
+	manifest := coexistingVersionsManifest([]uint{0, 1})
+	importMap := decodeImportMap(t, &manifest)
+
+	// Both versions have one dependent, so the lower index is hoisted.
+	assert.Equal(t, importMapHost+"react-is@16.13.1/index.js", importMap.Imports["react-is"])
+	assert.Equal(t, importMapHost+"react-is@16.13.1/", importMap.Imports["react-is/"])
+
+	assert.Len(t, importMap.Scopes, 1)
+	scope := importMap.Scopes[importMapHost+"b@1.0.0/"]
+	assert.Equal(t, importMapHost+"react-is@17.0.2/index.js", scope["react-is"])
+	assert.Equal(t, importMapHost+"react-is@17.0.2/", scope["react-is/"])
+}
+
+func TestImportMapRootDependencyWins(t *testing.T) {
+	manifest := coexistingVersionsManifest([]uint{0, 1, 3})
+	importMap := decodeImportMap(t, &manifest)
+
+	assert.Equal(t, importMapHost+"react-is@17.0.2/index.js", importMap.Imports["react-is"])
+
+	assert.Len(t, importMap.Scopes, 1)
+	scope := importMap.Scopes[importMapHost+"a@1.0.0/"]
+	assert.Equal(t, importMapHost+"react-is@16.13.1/index.js", scope["react-is"])
+}
+
+func TestImportMapOutsideRoot(t *testing.T) {
+	newManifest := func(version string) lockfile.JavascriptPackageManifest {
+		return lockfile.JavascriptPackageManifest{
+			Count:            2,
+			Name:             []string{"my-lib", "a"},
+			Version:          []string{version, "file:libs/a"},
+			DependencyIndex:  []uint{0, 0},
+			RootDependencies: []uint{0, 1},
+			ExportsManifest: lockfile.ExportsManifest{
+				Bare:      []string{"index.js", "index.js"},
+				BareField: make([]lockfile.BareField, 2),
+			},
+			ExportsManifestIndex: make([]uint, 4),
+		}
+	}
+
+	// Nothing serves folders above the root's, so they're an error instead of a URL that 404s
+	for _, version := range []string{"file:../my-lib", "link:../../shared/my-lib", "file:libs/../../my-lib"} {
+		manifest := newManifest(version)
+		_, err := lockfile.NewImportMap(&manifest, importMapHost)
+		if assert.Error(t, err, version) {
+			assert.Contains(t, err.Error(), "my-lib is at ", version)
+		}
+	}
+
+	manifest := newManifest("file:libs/my-lib")
+	importMap := decodeImportMap(t, &manifest)
+	assert.Equal(t, "/libs/my-lib/index.js", importMap.Imports["my-lib"])
+	assert.Equal(t, "/libs/a/index.js", importMap.Imports["a"])
+}
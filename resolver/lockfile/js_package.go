@@ -12,7 +12,7 @@ import (
 
 	"github.com/gammazero/workerpool"
 	"github.com/jarred-sumner/devserverless/config"
-	"github.com/jarred-sumner/devserverless/resolver/node_semver"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
 	runner "github.com/jarred-sumner/devserverless/resolver/runner"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/valyala/fasthttp"
@@ -26,8 +26,8 @@ type PackageManifestCache interface {
 }
 
 type PackageRangeCache interface {
-	Get(name string) (*JSDelivrPackageData, bool)
-	Put(name string, result JSDelivrPackageData)
+	Get(name string) (*PackageMetadata, bool)
+	Put(name string, result PackageMetadata)
 }
 
 type PackageAliasCache interface {
@@ -35,11 +35,15 @@ type PackageAliasCache interface {
 	Put(name string, result string)
 }
 
-func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName string) (*JSDelivrPackageData, error) {
+func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName string, ctx context.Context) (*PackageMetadata, error) {
+	if store.usesPackuments(name) {
+		return store.fetchPackument(name, parentName, ctx)
+	}
+
 	logger := store.Logger.With(zap.String("pkg", name))
 	var _logger *zap.Logger
 	var err error
-	var result JSDelivrPackageData
+	var result PackageMetadata
 	var body []byte
 
 	req := fasthttp.AcquireRequest()
@@ -50,12 +54,22 @@ func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName
 
 	req.SetRequestURI(fmt.Sprintf(JSDelivrMetadataFormatterString, name))
 
-	_logger = (*logger).With(zap.String("url", req.URI().String()), zap.String("name", name), zap.String("parent", parentName))
+	_logger = (*logger).With(zap.String("url", config.RedactURL(req.URI().String())), zap.String("name", name), zap.String("parent", parentName))
 	_logger.Info("GET metadata")
 
 	// log.Println(fmt.Sprintf(packageJsonFormatterString, name, version))
 
-	err = store.JSDelivrClient.DoDeadline(req, resp, time.Now().Add(time.Minute))
+	if contextDone(ctx) {
+		return &result, contextError(ctx)
+	}
+
+	err = store.HTTP.Do(ctx, store.JSDelivrClient, req, resp)
+
+	// Other resolutions may be waiting on this package, so a cancelled request must not be cached.
+	if contextDone(ctx) {
+		_logger.Debug("Cancelled")
+		return &result, contextError(ctx)
+	}
 
 	statusCode := resp.StatusCode()
 	_logger = _logger.With(zap.Int("statusCode", statusCode))
@@ -64,17 +78,21 @@ func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName
 	if err != nil {
 		_logger.Error("HTTP error", zap.Error(err))
 
+		result.Status = PackageResolutionStatusInternal
 		store.Ranges.Put(name, result)
 		return &result, err
 	}
 
-	switch statusCode {
-	case 200:
+	switch {
+	case statusCode == 200:
 		{
 
 			encoding := string(resp.Header.Peek(fasthttp.HeaderContentEncoding))
 			switch encoding {
 			case "deflate":
+				{
+					body, err = resp.BodyInflate()
+				}
 			case "gzip":
 				{
 					body, err = resp.BodyGunzip()
@@ -92,6 +110,7 @@ func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName
 			if err != nil {
 				_logger.Error("Body error", zap.Error(err))
 
+				result.Status = PackageResolutionStatusInternal
 				store.Ranges.Put(name, result)
 				return &result, err
 			}
@@ -101,57 +120,47 @@ func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName
 			if err != nil {
 				_logger.Error("Unmarshall error", zap.Error(err))
 
+				result.Status = PackageResolutionStatusCorruptPackage
 				store.Ranges.Put(name, result)
 				return &result, err
 			}
 
-			list := make(node_semver.Versions, 0, len(rawResult.Versions))
-
-			for _, versionStr := range rawResult.Versions {
-				parsed := node_semver.Tokenize(versionStr)
-				list = append(list, *parsed.Version)
-			}
-
-			// sort.Sort(list)
-
-			result = JSDelivrPackageData{
+			result = PackageMetadata{
 				Tags:     rawResult.Tags,
-				Versions: list,
+				Versions: newVersionList(rawResult.Versions),
+				Status:   PackageResolutionStatusSuccess,
 			}
 
 			store.Ranges.Put(name, result)
 			_logger.Debug("Success")
 			return &result, err
 		}
-	case 404:
+	case statusCode == 404:
 		{
 			err = errors.New(fmt.Sprintf("package \"%s\" not found", name))
 			_logger.Debug("Fail")
 
+			result.Status = PackageResolutionStatusNotFound
 			store.Ranges.Put(name, result)
 			return &result, err
 		}
 
-	case 509:
-	case 505:
-	case 504:
-	case 503:
-	case 502:
-	case 501:
-	case 500:
+	case statusCode >= 500:
 		{
 			err = errors.New("internal error while validating package")
 			_logger.Debug("Fail")
 
+			result.Status = PackageResolutionStatusInternal
 			store.Ranges.Put(name, result)
 			return &result, err
 		}
 
-	case 429:
+	case statusCode == 429:
 		{
 			err = errors.New("too many requests")
 			_logger.Debug("Fail")
 
+			result.Status = PackageResolutionStatusRateLimit
 			store.Ranges.Put(name, result)
 			return &result, err
 		}
@@ -160,12 +169,11 @@ func (store *PackageManifestStore) FetchPackageMetadata(name string, parentName
 		{
 			err = errors.New(fmt.Sprintf("error: status code %d", statusCode))
 			_logger.Debug("Fail")
+			result.Status = PackageResolutionStatusInternal
 			store.Ranges.Put(name, result)
 			return &result, err
 		}
 	}
-
-	return &result, err
 }
 
 func NewPackageManifestKey(name string, version string) string {
@@ -193,23 +201,38 @@ type PackageManifestStore struct {
 	Logger             *zap.Logger
 	NPMClient          *fasthttp.Client
 	JSDelivrClient     *fasthttp.Client
-	RegistrarAPI       config.RegistrarString
+	// HTTP retries the requests sent with NPMClient & JSDelivrClient, and limits them per host. Nil sends each request once.
+	HTTP *httpclient.Client
+	// Registrar is where packages come from, unless their scope has its own registry in Npmrc. Nil is npm's registry.
+	Registrar      Registrar
+	ResolutionMode config.ResolutionMode
+	// MetadataSource is where version lists come from. Empty is config.MetadataSourceJSDelivr.
+	MetadataSource config.MetadataSource
+	// Npmrc has the registry of each scope, and the credentials sent to registries. It can be nil.
+	Npmrc *config.Npmrc
+	// GitCacheDir is where git dependencies are mirrored. They're skipped when it's empty.
+	GitCacheDir string
+	// TarballCacheDir is where .tgz dependencies are downloaded. They're skipped when it's empty.
+	TarballCacheDir string
 }
 
 type resultStruct struct {
 	success bool
-	value   *JavascriptPackageManifestPartial
+	// cancelled means the resolution that started the request gave up on it. Anyone else waiting should try again.
+	cancelled bool
+	value     *JavascriptPackageManifestPartial
 }
 
 var successStruct = resultStruct{success: true}
 var errorStruct = resultStruct{success: false}
+var cancelledStruct = resultStruct{success: false, cancelled: true}
 
 type FetchPackageResult int
 
 const FetchPackageSuccess FetchPackageResult = 1
 const FetchPackageError FetchPackageResult = -1
 
-func (s *PackageManifestStore) flattenDependencies(pkg *JavascriptPackageManifestPartial, parentCtx context.Context) (JavascriptPackageManifest, error) {
+func (s *PackageManifestStore) flattenDependencies(pkg *JavascriptPackageManifestPartial, options ResolveOptions, parentCtx context.Context) (JavascriptPackageManifest, error) {
 	logger := s.Logger.With(zap.String("rootPackage", pkg.Name))
 
 	start := time.Now()
@@ -224,15 +247,57 @@ func (s *PackageManifestStore) flattenDependencies(pkg *JavascriptPackageManifes
 		packageKeysMutex: sync.Mutex{},
 		Logger:           logger,
 		Waiter:           &sync.WaitGroup{},
+		ctx:              parentCtx,
+		parents:          make(map[string]string, 100),
+		localManifests:   make(map[string]*JavascriptPackageManifestPartial),
+		workspaces:       make(map[string]workspaceMember, len(options.Workspaces)),
+		roots:            make(map[string]bool, len(options.Workspaces)),
+		rootDir:          options.RootDir,
+		gitKeys:          make(map[string]string),
+		overrides:        newOverrides(pkg),
+		optional:         make(map[string]bool),
+		groups:           options.dependencyGroups(),
+		peersResolved:    make(map[string]bool),
+		peerKeys:         make(map[string]string),
+		locked:           options.lockedVersions(),
 	}
 
-	pack.FetchDependencies(pkg, true)
-	pack.Waiter.Wait()
+	pack.addWorkspaces(options.Workspaces)
+	pack.FetchDependencies(pkg, "")
+
+	// Peers are picked once everything that could provide them has resolved.
+	// Missing peers are enqueued, and their own peers are picked on the next pass.
+	err := pack.wait()
+	for err == nil && pack.resolvePeers(pkg) {
+		err = pack.wait()
+	}
+
+	if err != nil {
+		logger.Info("Cancelled", zap.Uint64("successCount", pack.PackageCount), zap.Uint64("errorCount", pack.ErrorPackageCount), zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+		return JavascriptPackageManifest{}, err
+	}
 
 	defer logger.Info("Complete", zap.Uint64("successCount", pack.PackageCount), zap.Uint64("errorCount", pack.ErrorPackageCount), zap.Duration("elapsed", time.Since(start)))
 	return pack.appendDependencies(pkg), nil
 }
 
+// wait blocks until everything enqueued so far has resolved, or returns early with the context's error.
+func (pack *PackageFlatPack) wait() error {
+	done := make(chan struct{})
+	go func() {
+		pack.Waiter.Wait()
+		close(done)
+	}()
+
+	// Pending callbacks keep running after a cancellation, but they see the context is done and stop there.
+	select {
+	case <-done:
+	case <-pack.ctx.Done():
+	}
+
+	return pack.ctx.Err()
+}
+
 type PackageFlatPack struct {
 	list             []JavascriptPackageManifestPartial
 	packageKeys      map[string]bool
@@ -243,6 +308,35 @@ type PackageFlatPack struct {
 	store             *PackageManifestStore
 	Logger            *zap.Logger
 	Waiter            *sync.WaitGroup
+	ctx               context.Context
+
+	// Guarded by packageKeysMutex
+	parents  map[string]string
+	failures []packageFailure
+	// Dependencies that are optional, by edgeKey
+	optional map[string]bool
+	// Every manifest this resolution appended, including ones only it uses like npm aliases.
+	// The store's cache can drop or delay entries, so the lockfile is built from these.
+	localManifests map[string]*JavascriptPackageManifestPartial
+	// The commit-pinned keys git dependencies resolved to
+	gitKeys map[string]string
+
+	// Set before anything is enqueued, so they're read without the mutex
+	workspaces map[string]workspaceMember
+	roots      map[string]bool
+	rootDir    string
+	// The root package's overrides, by the package name they replace
+	overrides map[string][]override
+	groups    DependencyGroup
+
+	// Only used between waits, once nothing else is running
+	peersResolved map[string]bool
+	// The package each peer dependency resolved to, by edgeKey
+	peerKeys      map[string]string
+	peerConflicts []peerConflict
+
+	// What the previous lockfile resolved each dependency to, by edgeKey. Set before anything is enqueued.
+	locked map[string]string
 }
 
 func (pack *PackageFlatPack) Append(key string, value bool) {
@@ -258,25 +352,76 @@ func (pack *PackageFlatPack) Has(key string) bool {
 	return ok
 }
 
-func (pack *PackageFlatPack) FetchDependencies(res *JavascriptPackageManifestPartial, includeDevDependencies bool) {
+// claim marks key as pending, unless it's already in the pack. Only the caller that claims key resolves it.
+func (pack *PackageFlatPack) claim(key string) bool {
+	pack.packageKeysMutex.Lock()
+	defer pack.packageKeysMutex.Unlock()
+	if _, ok := pack.packageKeys[key]; ok {
+		return false
+	}
+
+	pack.packageKeys[key] = false
+	return true
+}
+
+func (pack *PackageFlatPack) putLocalManifest(key string, manifest *JavascriptPackageManifestPartial) {
+	pack.packageKeysMutex.Lock()
+	defer pack.packageKeysMutex.Unlock()
+	pack.localManifests[key] = manifest
+}
 
-	if res.DependencyNames != nil && len(res.DependencyNames) > 0 {
-		for i := range res.DependencyNames {
-			pack.enqueue(res.DependencyNames[i], res.DependencyVersions[i], res.Name)
-		}
+// GetManifest looks in this resolution's own manifests before the store's.
+func (pack *PackageFlatPack) GetManifest(key string) (*JavascriptPackageManifestPartial, bool) {
+	pack.packageKeysMutex.Lock()
+	manifest, ok := pack.localManifests[key]
+	pack.packageKeysMutex.Unlock()
+
+	if ok {
+		return manifest, ok
 	}
 
-	// if includeDevDependencies && res.DevDependencyNames != nil && len(res.DevDependencyNames) > 0 {
-	// 	for i := range res.DevDependencyNames {
-	// 		pack.enqueue(res.DevDependencyNames[i], res.DevDependencyVersions[i], res.Name)
-	// 	}
-	// }
+	return pack.store.Manifests.GetKey(key)
+}
+
+// FetchDependencies enqueues res's dependencies. parentKey is the key res was resolved to, or empty for the root package.
+func (pack *PackageFlatPack) FetchDependencies(res *JavascriptPackageManifestPartial, parentKey string) {
+	eachDependencyGroup(res, pack.groups, pack.isRoot(parentKey), func(group DependencyGroup, names []string, versions []string) {
+		for i := range names {
+			if group == DependencyGroupOptional {
+				pack.markOptional(parentKey, names[i])
+			}
 
-	if includeDevDependencies && res.PeerDependencyNames != nil && len(res.PeerDependencyVersions) > 0 {
-		for i := range res.PeerDependencyNames {
-			pack.enqueue(res.PeerDependencyNames[i], res.PeerDependencyVersions[i], res.Name)
+			pack.enqueue(names[i], versions[i], res.Name, parentKey)
 		}
+	})
+}
+
+// versionStrategy returns how a range is resolved. direct is true for the root & workspace packages' own dependencies.
+func (p *PackageFlatPack) versionStrategy(direct bool) VersionStrategy {
+	switch p.store.ResolutionMode {
+	case config.ResolutionModeLowest:
+		{
+			return VersionStrategyLowest
+		}
+	case config.ResolutionModeLowestDirect:
+		{
+			if direct {
+				return VersionStrategyLowest
+			}
+		}
+	}
+
+	return VersionStrategyHighest
+}
+
+// aliasKey is where the version a range resolved to is cached.
+// Ranges resolved to their lowest version are cached apart from the usual highest version.
+func aliasKey(key string, strategy VersionStrategy) string {
+	if strategy == VersionStrategyLowest {
+		return "lowest:" + key
 	}
+
+	return key
 }
 
 // func (s *PackageFlatPack) BuildManifest(manifest *JavascriptPackageManifest) {
@@ -284,27 +429,65 @@ func (pack *PackageFlatPack) FetchDependencies(res *JavascriptPackageManifestPar
 
 // }
 
-func (p *PackageFlatPack) enqueue(name string, version string, parentName string) {
+func (p *PackageFlatPack) enqueue(name string, version string, parentName string, parentKey string) {
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	version = p.overrideVersion(name, version, parentKey)
 	versionLength := len(version)
 	versionRange := NewVersionRange(version, versionLength)
 	protocol := NewPackageVersionProtocol(version, versionLength)
 	key := NewPackageManifestKey(name, protocol.ExtractTag(version))
 
+	// Workspace packages were added before anything was enqueued
+	if workspaceKey, ok := p.workspaceKey(name, version); ok {
+		p.setParent(workspaceKey, parentKey)
+		return
+	}
+
 	switch protocol {
 	case PackageVersionProtocolDefault:
 		{
-			p.enqueueDefaultProtocol(name, version, parentName, versionRange, key)
+			p.enqueueDefaultProtocol(name, version, parentName, parentKey, versionRange, key, "")
+		}
+	case PackageVersionProtocolNpm:
+		{
+			// The real package resolves as usual, and the alias is what it's saved as
+			realName, realVersion := ParseNpmAlias(version)
+			realVersionLength := len(realVersion)
+			p.enqueueDefaultProtocol(realName, realVersion, parentName, parentKey, NewVersionRange(realVersion, realVersionLength), NewPackageManifestKey(realName, realVersion), name)
 		}
 	case PackageVersionProtocolGithubBare, PackageVersionProtocolGithubDotCom, PackageVersionProtocolGithubTarball, PackageVersionProtocolGithubOwnerRepo:
 		{
-			p.enqueueGithubPackage(name, parentName, version, versionRange, protocol, key)
+			p.enqueueGithubPackage(name, parentName, parentKey, version, versionRange, protocol, key)
+		}
+	case PackageVersionProtocolPathlike:
+		{
+			p.enqueueLocalPackage(name, version, parentName, parentKey)
+		}
+	case PackageVersionProtocolGit, PackageVersionProtocolGitSsh:
+		{
+			p.enqueueGitPackage(name, version, parentName, parentKey, protocol)
+		}
+	case PackageVersionProtocolHttpsTarball, PackageVersionProtocolHttpTarball:
+		{
+			p.enqueueTarballPackage(name, version, parentName, parentKey, protocol)
+		}
+	case PackageVersionProtocolWorkspace:
+		{
+			s := p.store
+			s.Logger.Warn("No workspace package found", zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
+			p.recordFailure(name, version, PackageResolutionStatusNotFound, parentKey)
+			atomic.AddUint64(&p.ErrorPackageCount, 1)
 		}
 
 	}
 
 }
 
-func (p *PackageFlatPack) enqueueGithubPackage(name string, parentName string, version string, versionRange VersionRange, protocol PackageVersionProtocol, key string) {
+func (p *PackageFlatPack) enqueueGithubPackage(name string, parentName string, parentKey string, version string, versionRange VersionRange, protocol PackageVersionProtocol, key string) {
+	p.setParent(key, parentKey)
 
 	if p.Has(key) {
 		manifest, exists := p.store.Manifests.GetKey(key)
@@ -316,25 +499,34 @@ func (p *PackageFlatPack) enqueueGithubPackage(name string, parentName string, v
 
 	manifest, exists := p.store.Manifests.GetKey(key)
 	if exists {
-		isSuccess := manifest.Status == PackageResolutionStatusSuccess
-		p.Append(key, isSuccess)
-		if p.store.Installer != nil && manifest.Status == PackageResolutionStatusSuccess {
-			p.store.Installer.Enqueue(manifest)
-		}
-		atomic.AddUint64(&p.PackageCount, 1)
-		p.FetchDependencies(manifest, false)
-		// pkgSuccessCount++
+		p.appendCachedManifest(key, manifest, version, parentKey)
 		return
 	}
 
-	p.EnqueueFetchPackageJSON(key, name, version, p.Waiter, parentName, protocol)
+	p.EnqueueFetchPackageJSON(key, name, version, version, p.Waiter, parentName, parentKey, protocol, "")
 }
 
-func (p *PackageFlatPack) enqueueDefaultProtocol(name string, version string, parentName string, versionRange VersionRange, key string) {
+// enqueueDefaultProtocol resolves name@version from the registry. When alias isn't empty, it's saved under that name instead.
+func (p *PackageFlatPack) enqueueDefaultProtocol(name string, version string, parentName string, parentKey string, versionRange VersionRange, key string, alias string) {
 	s := p.store
 	var err error
+	requestedName := name
+	requested := version
+	if alias != "" {
+		requestedName = alias
+		requested = NewNpmAliasVersion(name, version)
+	}
+
+	if locked, ok := p.lockedVersion(requestedName, requested, parentKey); ok {
+		version = locked
+		versionRange = VersionRangeExact
+		key = NewPackageManifestKey(name, version)
+	}
+
 	if versionRange != VersionRangeExact {
-		aliasVersion, hasAlias := s.Aliases.Get(key)
+		strategy := p.versionStrategy(p.isRoot(parentKey))
+		versionAliasKey := aliasKey(key, strategy)
+		aliasVersion, hasAlias := s.Aliases.Get(versionAliasKey)
 		if !hasAlias {
 			metadata, hasMetadata := s.Ranges.Get(name)
 
@@ -342,48 +534,65 @@ func (p *PackageFlatPack) enqueueDefaultProtocol(name string, version string, pa
 				w := p.Waiter
 				w.Add(1)
 
-				p.EnqueueFetchPackageMetadata(name, version, parentName)
-
-				s.Emitter.SubscribeOnceAsync(name, func() {
+				// Subscribed before the fetch is submitted, so a fast fetch can't publish before anyone's listening
+				isNew, _ := s.Emitter.SubscribeOnceAsyncFirst(name, func() {
 					p := p
 					w := p.Waiter
 					defer w.Done()
 
-					name := name
-					version := version
+					requestedName := requestedName
+					requested := requested
 					parentName := parentName
 
-					p.enqueue(name, version, parentName)
+					p.enqueue(requestedName, requested, parentName, parentKey)
 
 				})
+
+				if isNew {
+					p.EnqueueFetchPackageMetadata(name, parentName)
+				}
 				return
 			}
 
-			version, err = metadata.Satisfying(version)
-			s.Aliases.Put(key, version)
-		} else {
-			version = aliasVersion
-		}
+			aliasVersion, err = metadata.SatisfyingWithStrategy(version, strategy)
 
-		if err != nil || version == "" {
-			s.Logger.Debug("No matching version found", zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
-			manifest := JavascriptPackageManifestPartial{
-				Name: name,
+			if err != nil {
+				status := PackageResolutionStatusInvalidVersion
+				// The metadata request itself failed, so that's the real reason
+				if metadata.Status != 0 && metadata.Status != PackageResolutionStatusSuccess {
+					status = metadata.Status
+				}
+
+				s.Logger.Warn("No matching version found", zap.String("name", name), zap.String("version", version), zap.String("parent", parentName), zap.Error(err))
+				manifest := JavascriptPackageManifestPartial{
+					Name: name,
+				}
+				manifest.SetVersion(version)
+				manifest.Status = status
+
+				s.Manifests.Put(name, version, &manifest)
+				p.recordFailure(requestedName, requested, status, parentKey)
+				// pkgErrorCount++
+				atomic.AddUint64(&p.ErrorPackageCount, 1)
+				return
 			}
-			manifest.SetVersion(version)
-			manifest.Status = PackageResolutionStatusInvalidVersion
 
-			s.Manifests.Put(name, version, &manifest)
-			// pkgErrorCount++
-			atomic.AddUint64(&p.ErrorPackageCount, 1)
-			return
+			s.Aliases.Put(versionAliasKey, aliasVersion)
 		}
 
+		version = aliasVersion
 		key = NewPackageManifestKey(name, version)
 	}
 
-	if p.Has(key) {
-		manifest, exists := s.Manifests.GetKey(key)
+	packKey := key
+	if alias != "" {
+		packKey = NewPackageManifestKey(alias, NewNpmAliasVersion(name, version))
+	}
+
+	p.setParent(packKey, parentKey)
+
+	if p.Has(packKey) {
+		manifest, exists := p.GetManifest(packKey)
 		if exists && p.store.Installer != nil && manifest.Status == PackageResolutionStatusSuccess {
 			p.store.Installer.Enqueue(manifest)
 		}
@@ -392,41 +601,94 @@ func (p *PackageFlatPack) enqueueDefaultProtocol(name string, version string, pa
 
 	manifest, exists := s.Manifests.GetKey(key)
 	if exists {
-		isSuccess := manifest.Status == PackageResolutionStatusSuccess
-		p.Append(key, isSuccess)
-		if p.store.Installer != nil && manifest.Status == PackageResolutionStatusSuccess {
-			p.store.Installer.Enqueue(manifest)
+		if alias != "" {
+			manifest = newNpmAliasManifest(alias, name, version, manifest)
+			p.putLocalManifest(packKey, manifest)
 		}
-		atomic.AddUint64(&p.PackageCount, 1)
-		p.FetchDependencies(manifest, false)
-		// pkgSuccessCount++
+
+		p.appendCachedManifest(packKey, manifest, requested, parentKey)
 		return
 	}
 
-	p.EnqueueFetchPackageJSON(key, name, version, p.Waiter, parentName, PackageVersionProtocolDefault)
+	p.EnqueueFetchPackageJSON(key, name, version, requested, p.Waiter, parentName, parentKey, PackageVersionProtocolDefault, alias)
 }
 
-func (p *PackageFlatPack) EnqueueFetchPackageJSON(key string, name string, version string, w *sync.WaitGroup, parentName string, protocol PackageVersionProtocol) {
+func (p *PackageFlatPack) appendCachedManifest(key string, manifest *JavascriptPackageManifestPartial, requested string, parentKey string) {
+	isSuccess := manifest.Status == PackageResolutionStatusSuccess
+	p.Append(key, isSuccess)
+
+	if !isSuccess {
+		p.recordFailure(manifest.Name, requested, manifest.Status, parentKey)
+		atomic.AddUint64(&p.ErrorPackageCount, 1)
+		return
+	}
+
+	p.putLocalManifest(key, manifest)
+	if p.store.Installer != nil {
+		p.store.Installer.Enqueue(manifest)
+	}
+	atomic.AddUint64(&p.PackageCount, 1)
+	p.FetchDependencies(manifest, key)
+}
+
+// EnqueueFetchPackageJSON fetches version, which requested resolved to.
+// When alias isn't empty, the package is added to this resolution under that name.
+func (p *PackageFlatPack) EnqueueFetchPackageJSON(key string, name string, version string, requested string, w *sync.WaitGroup, parentName string, parentKey string, protocol PackageVersionProtocol, alias string) {
 
 	s := p.store
 
 	w.Add(1)
 
-	var isNew = !s.Emitter.HasCallback(key)
+	packKey := key
+	if alias != "" {
+		packKey = NewPackageManifestKey(alias, NewNpmAliasVersion(name, version))
+	}
+
+	// Pending until the result arrives, so the same package isn't subscribed to twice.
+	p.Append(packKey, false)
 
-	s.Emitter.SubscribeOnceAsync(key, func(result resultStruct) {
+	// Subscribed before the fetch is submitted, so a fast fetch can't publish before anyone's listening
+	isNew, _ := s.Emitter.SubscribeOnceAsyncFirst(key, func(result resultStruct) {
 		w := w
 		defer w.Done()
 		p := p
 		key := key
+
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if result.cancelled {
+			// Whoever fetched it gave up, but this resolution still wants it.
+			p.EnqueueFetchPackageJSON(key, name, version, requested, w, parentName, parentKey, protocol, alias)
+			return
+		}
+
 		if result.success {
-			p.Append(key, true)
-			if p.store.Installer != nil && result.value != nil && result.value.Status == PackageResolutionStatusSuccess {
-				p.store.Installer.Enqueue(result.value)
+			manifest := result.value
+			if alias != "" {
+				manifest = newNpmAliasManifest(alias, name, version, manifest)
+			}
+
+			p.putLocalManifest(packKey, manifest)
+			p.Append(packKey, true)
+			if p.store.Installer != nil && manifest != nil && manifest.Status == PackageResolutionStatusSuccess {
+				p.store.Installer.Enqueue(manifest)
 			}
 			atomic.AddUint64(&p.PackageCount, 1)
+			p.FetchDependencies(manifest, packKey)
 		} else {
-			p.Append(key, false)
+			status := PackageResolutionStatusInternal
+			if result.value != nil && result.value.Status != 0 {
+				status = result.value.Status
+			}
+
+			failureName := name
+			if alias != "" {
+				failureName = alias
+			}
+
+			p.recordFailure(failureName, requested, status, parentKey)
 			atomic.AddUint64(&p.ErrorPackageCount, 1)
 		}
 	})
@@ -445,53 +707,38 @@ func (p *PackageFlatPack) EnqueueFetchPackageJSON(key string, name string, versi
 
 			p := p
 			s := s
-			res, err := s.FetchPackageJSON(name, version, parentName, protocol)
+			res, err := s.FetchPackageJSON(name, version, parentName, protocol, p.ctx)
 
 			if err == nil {
-				p.FetchDependencies(res, false)
 				s.Emitter.Publish(key, resultStruct{value: res, success: true})
-
+			} else if contextDone(p.ctx) {
+				s.Emitter.Publish(key, cancelledStruct)
 			} else {
-				s.Emitter.Publish(key, errorStruct)
+				s.Emitter.Publish(key, resultStruct{value: res, success: false})
 			}
 		})
 	}
 
 }
 
-func (p *PackageFlatPack) EnqueueFetchPackageMetadata(name string, pendingVersion string, parentName string) {
+// EnqueueFetchPackageMetadata fetches name's metadata, then publishes name. Only the first subscriber to name calls it.
+func (p *PackageFlatPack) EnqueueFetchPackageMetadata(name string, parentName string) {
 	s := p.store
 
-	var isNew = !s.Emitter.HasCallback(name)
-
-	// if !ok {
-
-	// 	s.MetadataBroadcaster.Store(name, broadcaster)
-
-	// } else {
-
-	// }
-
-	// broadcaster.L.Lock()
-
-	if isNew {
-		p.Logger.Info("Enqueue metadata", zap.String("name", name))
-		s.MetadataWorkers.Submit(func() {
-
-			name := name
-			s := s
-			p := p
-			pendingVersion := pendingVersion
-			parentName := parentName
+	p.Logger.Info("Enqueue metadata", zap.String("name", name))
+	s.MetadataWorkers.Submit(func() {
 
-			s.FetchPackageMetadata(name, parentName)
-			// prevent stack overflow
-			p.enqueue(name, pendingVersion, parentName)
+		name := name
+		s := s
+		p := p
+		parentName := parentName
 
-			s.Emitter.Publish(name)
-		})
-	}
+		// Subscribers enqueue the pending version themselves.
+		// If this was cancelled, nothing was cached and they fetch it again.
+		s.FetchPackageMetadata(name, parentName, p.ctx)
 
+		s.Emitter.Publish(name)
+	})
 }
 
 // func (s *PackageFlatPack) appendDependencyGroup(names []string, versions []string, recurseDep bool, recurseDev bool, recursePeer bool) {
@@ -543,6 +790,52 @@ func (p *JavascriptPackageManifest) GrowArrays(to int) {
 	// p.DependenciesIndex = DependenciesIndex
 }
 
+// DependencyLists splits Dependencies into one slice per package, using DependencyIndex as the lengths.
+func (p *JavascriptPackageManifest) DependencyLists() [][]uint {
+	lists := make([][]uint, len(p.DependencyIndex))
+	offset := uint(0)
+	for i, length := range p.DependencyIndex {
+		lists[i] = p.Dependencies[offset : offset+length]
+		offset += length
+	}
+
+	return lists
+}
+
+// TopLevelIndices picks the version of each package name that everything sees by default.
+// A version the root package depends on directly always wins. Otherwise it's the version the most packages depend on, and ties go to the lower index.
+func (p *JavascriptPackageManifest) TopLevelIndices() map[string]uint {
+	return p.TopLevelIndicesFrom(p.RootDependencies)
+}
+
+// TopLevelIndicesFrom is TopLevelIndices, with roots' versions winning instead of the root package's dependencies.
+func (p *JavascriptPackageManifest) TopLevelIndicesFrom(roots []uint) map[string]uint {
+	topLevel := make(map[string]uint, p.Count)
+	direct := make(map[string]bool, len(roots))
+	for _, index := range roots {
+		topLevel[p.Name[index]] = index
+		direct[p.Name[index]] = true
+	}
+
+	dependents := make([]uint, len(p.Name))
+	for _, index := range p.Dependencies {
+		dependents[index]++
+	}
+
+	for index, name := range p.Name {
+		if direct[name] {
+			continue
+		}
+
+		current, exists := topLevel[name]
+		if !exists || dependents[index] > dependents[current] {
+			topLevel[name] = uint(index)
+		}
+	}
+
+	return topLevel
+}
+
 type Slice struct {
 	sort.Interface
 
@@ -560,6 +853,65 @@ func (s Slice) Swap(i, j int) {
 	s.ExportLengths[i], s.ExportLengths[j] = s.ExportLengths[j], s.ExportLengths[i]
 }
 
+// resolvedKey returns the key a dependency range of parentKey resolved to, matching the key enqueue stored it under.
+func (s *PackageFlatPack) resolvedKey(name string, version string, parentKey string) string {
+	return s.resolvedRangeKey(name, s.overrideVersion(name, version, parentKey), parentKey)
+}
+
+// resolvedRangeKey is resolvedKey, after overrides are applied.
+func (s *PackageFlatPack) resolvedRangeKey(name string, version string, parentKey string) string {
+	if workspaceKey, ok := s.workspaceKey(name, version); ok {
+		return workspaceKey
+	}
+
+	length := len(version)
+	protocol := NewPackageVersionProtocol(version, length)
+	key := NewPackageManifestKey(name, protocol.ExtractTag(version))
+
+	if protocol == PackageVersionProtocolNpm {
+		realName, realVersion := ParseNpmAlias(version)
+		if locked, ok := s.lockedVersion(name, version, parentKey); ok {
+			realVersion = locked
+		}
+
+		return NewPackageManifestKey(name, npmAliasPrefix+s.resolvedRangeKey(realName, realVersion, parentKey))
+	}
+
+	if protocol == PackageVersionProtocolPathlike {
+		if localVersion, ok := s.localVersion(version, parentKey); ok {
+			return NewPackageManifestKey(name, localVersion)
+		}
+	}
+
+	if protocol == PackageVersionProtocolGit || protocol == PackageVersionProtocolGitSsh {
+		if gitKey, ok := s.gitKey(NewPackageManifestKey(name, version)); ok {
+			return gitKey
+		}
+	}
+
+	if protocol == PackageVersionProtocolDefault && NewVersionRange(version, length) != VersionRangeExact {
+		if locked, ok := s.lockedVersion(name, version, parentKey); ok {
+			return NewPackageManifestKey(name, locked)
+		}
+
+		if alias, ok := s.store.Aliases.Get(aliasKey(key, s.versionStrategy(s.isRoot(parentKey)))); ok {
+			key = NewPackageManifestKey(name, alias)
+		}
+	}
+
+	return key
+}
+
+func (s *PackageFlatPack) appendDependencyIndices(indices []uint, keysIndex map[string]uint, names []string, versions []string, parentKey string) []uint {
+	for i, name := range names {
+		if index, ok := keysIndex[s.resolvedKey(name, versions[i], parentKey)]; ok {
+			indices = append(indices, index)
+		}
+	}
+
+	return indices
+}
+
 func (s *PackageFlatPack) appendDependencies(pkg *JavascriptPackageManifestPartial) JavascriptPackageManifest {
 	keysList := make(sort.StringSlice, 0, len(s.packageKeys))
 	for key, v := range s.packageKeys {
@@ -590,24 +942,24 @@ func (s *PackageFlatPack) appendDependencies(pkg *JavascriptPackageManifestParti
 		ExportsManifestIndex: make([]uint, count*2),
 		Dependencies:         make([]uint, 0, s.PackageCount+s.ErrorPackageCount),
 		DependencyIndex:      make([]uint, count),
+		Integrity:            make([]string, count),
+		Os:                   make([]string, count),
+		Cpu:                  make([]string, count),
+		Libc:                 make([]string, count),
 	}
 
-	// var dependencyI uint
-	// var dependencyIndexer = make(map[string]uint, s.PackageCount)
 	var manifest *JavascriptPackageManifestPartial
 	var manifestExists bool
 
 	var exportI uint
-	// var depExists bool
-	for key, index := range keysIndex {
-		// s.packageKeys.Range(func(key string, value bool) bool {
-
-		manifest, manifestExists = s.store.Manifests.GetKey(key)
+	// Walk the keys in index order so that the offsets into Dependencies line up
+	// with the running sum of DependencyIndex.
+	for index, key := range keysList {
+		manifest, manifestExists = s.GetManifest(key)
 		if manifestExists {
 			full.ExportsManifest.Bare[index] = manifest.ExportsManifest.Bare
 			full.ExportsManifest.BareField[index] = manifest.ExportsManifest.BareField
-			full.DependencyIndex[index] = uint(len(manifest.DependencyNames) + len(manifest.PeerDependencyNames))
-			exportI = index * 2
+			exportI = uint(index) * 2
 			full.ExportsManifestIndex[exportI] = uint(len(full.ExportsManifest.Source))
 			exportI++
 			full.ExportsManifestIndex[exportI] = uint(len(manifest.ExportsManifest.Destination))
@@ -616,53 +968,36 @@ func (s *PackageFlatPack) appendDependencies(pkg *JavascriptPackageManifestParti
 			full.ExportsManifest.Source = append(full.ExportsManifest.Source, manifest.ExportsManifest.Source...)
 			full.Name[index] = manifest.Name
 			full.Version[index] = manifest.Version.Tag
-
-			addedDepsCount := uint(0)
-			for i, depName := range manifest.DependencyNames {
-				depVersion := manifest.DependencyVersions[i]
-				depKey := NewPackageManifestKey(depName, depVersion)
-				length := len(depVersion)
-
-				if NewVersionRange(depVersion, length) != VersionRangeExact {
-					aliasKey, ok := s.store.Aliases.Get(NewPackageManifestKey(depName, depVersion))
-					if ok {
-						depKey = NewPackageManifestKey(depName, aliasKey)
-					}
-				}
-
-				depIndex, ok := keysIndex[depKey]
-
-				if ok {
-					full.Dependencies = append(full.Dependencies, depIndex)
-					addedDepsCount++
-				}
-			}
-
-			for i, depName := range manifest.PeerDependencyNames {
-				depVersion := manifest.PeerDependencyVersions[i]
-				depKey := NewPackageManifestKey(depName, depVersion)
-				length := len(depVersion)
-
-				if NewVersionRange(depVersion, length) != VersionRangeExact {
-					aliasKey, ok := s.store.Aliases.Get(NewPackageManifestKey(depName, depVersion))
-					if ok {
-						depKey = NewPackageManifestKey(depName, aliasKey)
-					}
-				}
-
-				depIndex, ok := keysIndex[depKey]
-
-				if ok {
-					full.Dependencies = append(full.Dependencies, depIndex)
-					addedDepsCount++
-				}
+			full.Integrity[index] = manifest.Integrity
+			// Packages for every platform are kept, so the installer can skip the ones that don't match wherever the lockfile is installed
+			full.Os[index] = strings.Join(manifest.Os, ",")
+			full.Cpu[index] = strings.Join(manifest.Cpu, ",")
+			full.Libc[index] = strings.Join(manifest.Libc, ",")
+
+			start := len(full.Dependencies)
+			full.Dependencies = s.appendDependencyIndices(full.Dependencies, keysIndex, manifest.DependencyNames, manifest.DependencyVersions, key)
+			full.Dependencies = s.appendPeerIndices(full.Dependencies, keysIndex, manifest, key)
+			full.Dependencies = s.appendDependencyIndices(full.Dependencies, keysIndex, manifest.OptionalDependencyNames, manifest.OptionalDependencyVersions, key)
+			if s.isRoot(key) && s.groups.Has(DependencyGroupDev) {
+				full.Dependencies = s.appendDependencyIndices(full.Dependencies, keysIndex, manifest.DevDependencyNames, manifest.DevDependencyVersions, key)
 			}
-			full.DependencyIndex[index] = addedDepsCount
+			full.DependencyIndex[index] = uint(len(full.Dependencies) - start)
 		} else {
 			s.Logger.Sugar().Warnf("Expected %s to exist", key)
 		}
 	}
 
+	full.RootDependencies = make([]uint, 0, len(pkg.DependencyNames)+len(pkg.PeerDependencyNames)+len(pkg.OptionalDependencyNames))
+	full.RootDependencies = s.appendDependencyIndices(full.RootDependencies, keysIndex, pkg.DependencyNames, pkg.DependencyVersions, "")
+	full.RootDependencies = s.appendDependencyIndices(full.RootDependencies, keysIndex, pkg.PeerDependencyNames, pkg.PeerDependencyVersions, "")
+	full.RootDependencies = s.appendDependencyIndices(full.RootDependencies, keysIndex, pkg.OptionalDependencyNames, pkg.OptionalDependencyVersions, "")
+	if s.groups.Has(DependencyGroupDev) {
+		full.RootDependencies = s.appendDependencyIndices(full.RootDependencies, keysIndex, pkg.DevDependencyNames, pkg.DevDependencyVersions, "")
+	}
+	full.Failures = s.buildFailures()
+	full.Groups = s.buildGroups(&full, pkg, keysList, keysIndex)
+	full.PeerConflicts = s.buildPeerConflicts(pkg)
+
 	// return true
 	// })
 
@@ -671,8 +1006,14 @@ func (s *PackageFlatPack) appendDependencies(pkg *JavascriptPackageManifestParti
 	return full
 }
 
+// ResolveDependencies stops early with parentCtx's error when it's cancelled or its deadline passes.
 func (s *PackageManifestStore) ResolveDependencies(pkg *JavascriptPackageManifestPartial, parentCtx context.Context) (JavascriptPackageManifest, error) {
-	list, err := s.flattenDependencies(pkg, parentCtx)
+	return s.ResolveDependenciesWithOptions(pkg, ResolveOptions{}, parentCtx)
+}
+
+// ResolveDependenciesWithOptions is ResolveDependencies, with workspace packages resolved into the same graph as pkg.
+func (s *PackageManifestStore) ResolveDependenciesWithOptions(pkg *JavascriptPackageManifestPartial, options ResolveOptions, parentCtx context.Context) (JavascriptPackageManifest, error) {
+	list, err := s.flattenDependencies(pkg, options, parentCtx)
 
 	if err != nil {
 		return list, err
@@ -687,15 +1028,22 @@ func (s *PackageManifestStore) ResolveDependencies(pkg *JavascriptPackageManifes
 	return list, err
 }
 
-func (store *PackageManifestStore) FetchPackageJSON(name string, version string, parentName string, protocol PackageVersionProtocol) (*JavascriptPackageManifestPartial, error) {
+func (store *PackageManifestStore) FetchPackageJSON(name string, version string, parentName string, protocol PackageVersionProtocol, ctx context.Context) (*JavascriptPackageManifestPartial, error) {
 	switch protocol {
 	case PackageVersionProtocolGithubBare, PackageVersionProtocolGithubDotCom, PackageVersionProtocolGithubTarball, PackageVersionProtocolGithubOwnerRepo:
 		{
 			uri, _ := protocol.ExtractJSDelivrGithubPackageJSONURL(version)
-			return store.fetchFromGithub(name, version, parentName, uri)
+			return store.fetchFromGithub(name, version, parentName, uri, ctx)
 		}
 
 	}
 
-	return store.fetchFromNPM(name, version, parentName)
+	// The packument already has every version's package.json
+	if store.usesPackuments(name) {
+		if manifest, ok := store.packumentManifest(name, version, parentName, ctx); ok {
+			return manifest, nil
+		}
+	}
+
+	return store.fetchFromNPM(name, version, parentName, ctx)
 }
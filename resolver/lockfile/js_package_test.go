@@ -0,0 +1,217 @@
+package lockfile_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/internal/httpclient"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesDeadline(t *testing.T) {
+	release := make(chan struct{})
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/metadata/") {
+			w.Write([]byte(`{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`))
+			return
+		}
+
+		<-release
+		w.Write([]byte(`{"name": "slow", "version": "1.0.0"}`))
+	}))
+	defer registry.Close()
+	defer close(release)
+
+	metadataURL := lockfile.JSDelivrMetadataFormatterString
+	lockfile.JSDelivrMetadataFormatterString = registry.URL + "/metadata/%s"
+	defer func() { lockfile.JSDelivrMetadataFormatterString = metadataURL }()
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.Registrar, _ = lockfile.NewRegistrar(registry.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("slow", "1.0.0", true)
+	start := time.Now()
+	_, err := store.ResolveDependencies(&root, ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// The timed out request shouldn't leave an error behind for the next resolution
+	time.Sleep(time.Millisecond * 50)
+	_, cached := store.Manifests.Get("slow", "1.0.0")
+	assert.False(t, cached)
+}
+
+func TestResolveDependenciesCancelled(t *testing.T) {
+	store := cache.NewMemoryPackageManifestStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("react", "^17.0.0", true)
+	_, err := store.ResolveDependencies(&root, ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// newFakeRegistry serves jsDelivr metadata and package.json files from memory. Anything missing is a 404.
+func newFakeRegistry(t *testing.T, metadata map[string]string, packages map[string]string) *lockfile.PackageManifestStore {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		var ok bool
+		if strings.HasPrefix(r.URL.Path, "/metadata/") {
+			body, ok = metadata[strings.TrimPrefix(r.URL.Path, "/metadata/")]
+		} else {
+			body, ok = packages[strings.TrimPrefix(r.URL.Path, "/")]
+		}
+
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(registry.Close)
+
+	metadataURL := lockfile.JSDelivrMetadataFormatterString
+	lockfile.JSDelivrMetadataFormatterString = registry.URL + "/metadata/%s"
+	t.Cleanup(func() { lockfile.JSDelivrMetadataFormatterString = metadataURL })
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.Registrar, _ = lockfile.NewRegistrar(registry.URL)
+	return store
+}
+
+func TestResolveDependenciesFailures(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"ok":  `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"bad": `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+		},
+		map[string]string{
+			"ok/1.0.0": `{"name": "ok", "version": "1.0.0", "dependencies": {"bad": "^9.0.0", "missing": "^1.0.0"}}`,
+		},
+	)
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("ok", "^1.0.0", true)
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"ok"}, manifest.Name)
+	assert.Equal(t, []string{"bad", "missing"}, manifest.Failures.Name)
+	assert.Equal(t, []string{"^9.0.0", "^1.0.0"}, manifest.Failures.Range)
+	assert.Equal(t, []lockfile.PackageResolutionStatus{lockfile.PackageResolutionStatusInvalidVersion, lockfile.PackageResolutionStatusNotFound}, manifest.Failures.Status)
+	assert.Equal(t, []string{
+		"bad@^9.0.0: no version matches (via ok@1.0.0)",
+		"missing@^1.0.0: not found (via ok@1.0.0)",
+	}, manifest.Failures.Messages())
+}
+
+func TestResolveDependenciesServerErrors(t *testing.T) {
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		attempt := requests[r.URL.Path]
+		lock.Unlock()
+
+		switch r.URL.Path {
+		case "/metadata/ok", "/metadata/broken":
+			w.Write([]byte(`{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`))
+		case "/metadata/flaky":
+			if attempt == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(429)
+				return
+			}
+			w.Write([]byte(`{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`))
+		case "/ok/1.0.0":
+			w.Write([]byte(`{"name": "ok", "version": "1.0.0", "dependencies": {"flaky": "^1.0.0", "broken": "^1.0.0", "down": "^1.0.0"}}`))
+		case "/flaky/1.0.0":
+			w.Write([]byte(`{"name": "flaky", "version": "1.0.0"}`))
+		default:
+			w.WriteHeader(502)
+		}
+	}))
+	t.Cleanup(registry.Close)
+
+	metadataURL := lockfile.JSDelivrMetadataFormatterString
+	lockfile.JSDelivrMetadataFormatterString = registry.URL + "/metadata/%s"
+	t.Cleanup(func() { lockfile.JSDelivrMetadataFormatterString = metadataURL })
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.Registrar, _ = lockfile.NewRegistrar(registry.URL)
+	store.HTTP = httpclient.New(httpclient.Policy{Retries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetryAfter: time.Second})
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("ok", "^1.0.0", true)
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	// 5xxs are failures instead of empty package.json files, after they're retried
+	assert.Equal(t, []string{"flaky", "ok"}, manifest.Name)
+	assert.Equal(t, []string{"broken", "down"}, manifest.Failures.Name)
+	assert.Equal(t, []lockfile.PackageResolutionStatus{lockfile.PackageResolutionStatusInternal, lockfile.PackageResolutionStatusInternal}, manifest.Failures.Status)
+	// The memory cache is eventually consistent, so a package can be fetched again by another dependent
+	assert.GreaterOrEqual(t, requests["/broken/1.0.0"], 3)
+	assert.GreaterOrEqual(t, requests["/metadata/down"], 3)
+}
+
+func TestResolveDependenciesNpmAlias(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"string-width": `{"tags": {"latest": "5.1.2"}, "versions": ["4.2.2", "4.2.3", "5.1.2"]}`,
+		},
+		map[string]string{
+			"string-width/4.2.3": `{"name": "string-width", "version": "4.2.3", "main": "index.js"}`,
+			"string-width/5.1.2": `{"name": "string-width", "version": "5.1.2", "main": "index.js"}`,
+		},
+	)
+
+	root := lockfile.JavascriptPackageManifestPartial{
+		Name:               "root",
+		Status:             lockfile.PackageResolutionStatusSuccess,
+		DependencyNames:    []string{"string-width", "string-width-cjs"},
+		DependencyVersions: []string{"^5.0.0", "npm:string-width@^4.2.0"},
+	}
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, manifest.Failures.Name)
+
+	// Sorted by key, and "string-width-cjs@" comes before "string-width@"
+	assert.Equal(t, []string{"string-width-cjs", "string-width"}, manifest.Name)
+	assert.Equal(t, []string{"npm:string-width@4.2.3", "5.1.2"}, manifest.Version)
+	assert.Equal(t, []uint{1, 0}, manifest.RootDependencies)
+
+	importMap := decodeImportMap(t, &manifest)
+	assert.Equal(t, importMapHost+"string-width@5.1.2/index.js", importMap.Imports["string-width"])
+	assert.Equal(t, importMapHost+"string-width@4.2.3/index.js", importMap.Imports["string-width-cjs"])
+}
+
+func TestParseNpmAlias(t *testing.T) {
+	name, version := lockfile.ParseNpmAlias("npm:string-width@^4.2.0")
+	assert.Equal(t, "string-width", name)
+	assert.Equal(t, "^4.2.0", version)
+
+	name, version = lockfile.ParseNpmAlias("npm:@babel/core@7.0.0")
+	assert.Equal(t, "@babel/core", name)
+	assert.Equal(t, "7.0.0", version)
+
+	name, version = lockfile.ParseNpmAlias("npm:@babel/core")
+	assert.Equal(t, "@babel/core", name)
+	assert.Equal(t, "*", version)
+
+	version = "npm:string-width@^4.2.0"
+	assert.Equal(t, lockfile.PackageVersionProtocolNpm, lockfile.NewPackageVersionProtocol(version, len(version)))
+}
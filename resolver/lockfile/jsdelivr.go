@@ -1,12 +1,28 @@
 package lockfile
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/jarred-sumner/devserverless/resolver/node_semver"
 )
 
-type JSDelivrPackageData struct {
+// PackageMetadata is every published version of a package, from jsDelivr's data API or the registry's packument.
+type PackageMetadata struct {
 	Tags     map[string]string    `json:"tags"`
 	Versions node_semver.Versions `json:"versions"`
+	// Status is why the metadata is empty when fetching it failed
+	Status PackageResolutionStatus `json:"status,omitempty"`
+
+	// Only packuments have the rest
+	// Deprecated has the message of each deprecated version
+	Deprecated map[string]string `json:"deprecated,omitempty"`
+	// Time is when each version was published, when the registry says
+	Time map[string]string `json:"time,omitempty"`
+	// Manifests has the package.json of each version, as JSON, so they don't need a request each
+	Manifests map[string][]byte `json:"manifests,omitempty"`
 }
 
 type RawJSDelivrPackageData struct {
@@ -14,42 +30,107 @@ type RawJSDelivrPackageData struct {
 	Versions []string          `json:"versions"`
 }
 
-func (p *JSDelivrPackageData) Satisfying(version string) (string, error) {
-	if version == "*" {
+// newVersionList parses versions, in order of precedence. Anything that isn't a version is left out.
+func newVersionList(versions []string) node_semver.Versions {
+	list := make(node_semver.Versions, 0, len(versions))
+
+	for _, versionStr := range versions {
+		parsed := node_semver.Tokenize(versionStr)
+		if parsed.Value != node_semver.TokenizeResultValueVersion || parsed.Version == nil {
+			continue
+		}
+
+		list = append(list, *parsed.Version)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return ComparePrecedence(list[i], list[j]) < 0
+	})
+
+	return list
+}
+
+// VersionStrategy decides which version wins when more than one satisfies a range.
+type VersionStrategy byte
+
+const (
+	VersionStrategyHighest VersionStrategy = iota
+	VersionStrategyLowest
+)
+
+func (p *PackageMetadata) Satisfying(version string) (string, error) {
+	return p.SatisfyingWithStrategy(version, VersionStrategyHighest)
+}
+
+// SatisfyingWithStrategy returns the highest or lowest published version matching the range.
+// The answer doesn't depend on the order of Versions, so cached metadata resolves the same way as fresh metadata.
+func (p *PackageMetadata) SatisfyingWithStrategy(version string, strategy VersionStrategy) (string, error) {
+	if (version == "*" || version == "") && strategy == VersionStrategyHighest {
 		version = "latest"
 	}
 
 	if len(p.Tags[version]) > 0 {
-		version = p.Tags[version]
+		return p.Tags[version], nil
 	}
 
-	tokenized := node_semver.Tokenize(version)
+	if version == "" {
+		version = "*"
+	}
 
-	if len(p.Versions) > 0 {
+	tokenized := node_semver.Tokenize(version)
 
-		switch tokenized.Value {
-		case node_semver.TokenizeResultValueVersion:
-			{
-				version := tokenized.Version
+	switch tokenized.Value {
+	case node_semver.TokenizeResultValueVersion:
+		{
+			version := tokenized.Version
 
-				for i := range p.Versions {
-					if p.Versions[i].EQ(*version) {
-						return p.Versions[i].String(), nil
-					}
+			for i := range p.Versions {
+				if p.Versions[i].EQ(*version) {
+					return p.Versions[i].String(), nil
 				}
 			}
-		case node_semver.TokenizeResultValueRange:
-			{
-				for i := range p.Versions {
-					if tokenized.Range(p.Versions[i]) {
-						return p.Versions[i].String(), nil
-					}
+		}
+	case node_semver.TokenizeResultValueRange:
+		{
+			best := -1
+
+			for i := range p.Versions {
+				if !tokenized.Range(p.Versions[i]) {
+					continue
+				}
+
+				if best == -1 {
+					best = i
+					continue
+				}
+
+				comparison := ComparePrecedence(p.Versions[i], p.Versions[best])
+				if (strategy == VersionStrategyHighest && comparison > 0) || (strategy == VersionStrategyLowest && comparison < 0) {
+					best = i
 				}
 			}
+
+			if best > -1 {
+				return p.Versions[best].String(), nil
+			}
 		}
+	default:
+		{
+			return "", errors.New(fmt.Sprintf("invalid version range \"%s\"", version))
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("no version satisfies \"%s\"", version))
+}
+
+// ComparePrecedence orders versions by semver precedence.
+// Versions with equal precedence fall back to comparing their strings, so sorting is stable across runs.
+func ComparePrecedence(a node_semver.Version, b node_semver.Version) int {
+	if comparison := a.Compare(b); comparison != 0 {
+		return comparison
 	}
 
-	return "latest", nil
+	return strings.Compare(a.String(), b.String())
 }
 
 var JSDelivrMetadataFormatterString = "https://data.jsdelivr.com/v1/package/npm/%s"
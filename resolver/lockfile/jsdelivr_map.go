@@ -70,7 +70,7 @@ type readOnlyJSDelivrPackageDataMap struct {
 
 // expunged is an arbitrary pointer that marks entries which have been deleted
 // from the dirty map.
-var expungedJSDelivrPackageDataMap = unsafe.Pointer(new(*JSDelivrPackageData))
+var expungedJSDelivrPackageDataMap = unsafe.Pointer(new(*PackageMetadata))
 
 // An entry is a slot in the map corresponding to a particular key.
 type entryJSDelivrPackageDataMap struct {
@@ -95,14 +95,14 @@ type entryJSDelivrPackageDataMap struct {
 	p unsafe.Pointer // *interface{}
 }
 
-func newEntryJSDelivrPackageDataMap(i *JSDelivrPackageData) *entryJSDelivrPackageDataMap {
+func newEntryJSDelivrPackageDataMap(i *PackageMetadata) *entryJSDelivrPackageDataMap {
 	return &entryJSDelivrPackageDataMap{p: unsafe.Pointer(&i)}
 }
 
 // Load returns the value stored in the map for a key, or nil if no
 // value is present.
 // The ok result indicates whether value was found in the map.
-func (m *JSDelivrPackageDataMap) Load(key string) (value *JSDelivrPackageData, ok bool) {
+func (m *JSDelivrPackageDataMap) Load(key string) (value *PackageMetadata, ok bool) {
 	read, _ := m.read.Load().(readOnlyJSDelivrPackageDataMap)
 	e, ok := read.m[key]
 	if !ok && read.amended {
@@ -127,16 +127,16 @@ func (m *JSDelivrPackageDataMap) Load(key string) (value *JSDelivrPackageData, o
 	return e.load()
 }
 
-func (e *entryJSDelivrPackageDataMap) load() (value *JSDelivrPackageData, ok bool) {
+func (e *entryJSDelivrPackageDataMap) load() (value *PackageMetadata, ok bool) {
 	p := atomic.LoadPointer(&e.p)
 	if p == nil || p == expungedJSDelivrPackageDataMap {
 		return value, false
 	}
-	return *(**JSDelivrPackageData)(p), true
+	return *(**PackageMetadata)(p), true
 }
 
 // Store sets the value for a key.
-func (m *JSDelivrPackageDataMap) Store(key string, value *JSDelivrPackageData) {
+func (m *JSDelivrPackageDataMap) Store(key string, value *PackageMetadata) {
 	read, _ := m.read.Load().(readOnlyJSDelivrPackageDataMap)
 	if e, ok := read.m[key]; ok && e.tryStore(&value) {
 		return
@@ -169,7 +169,7 @@ func (m *JSDelivrPackageDataMap) Store(key string, value *JSDelivrPackageData) {
 //
 // If the entry is expunged, tryStore returns false and leaves the entry
 // unchanged.
-func (e *entryJSDelivrPackageDataMap) tryStore(i **JSDelivrPackageData) bool {
+func (e *entryJSDelivrPackageDataMap) tryStore(i **PackageMetadata) bool {
 	for {
 		p := atomic.LoadPointer(&e.p)
 		if p == expungedJSDelivrPackageDataMap {
@@ -192,14 +192,14 @@ func (e *entryJSDelivrPackageDataMap) unexpungeLocked() (wasExpunged bool) {
 // storeLocked unconditionally stores a value to the entry.
 //
 // The entry must be known not to be expunged.
-func (e *entryJSDelivrPackageDataMap) storeLocked(i **JSDelivrPackageData) {
+func (e *entryJSDelivrPackageDataMap) storeLocked(i **PackageMetadata) {
 	atomic.StorePointer(&e.p, unsafe.Pointer(i))
 }
 
 // LoadOrStore returns the existing value for the key if present.
 // Otherwise, it stores and returns the given value.
 // The loaded result is true if the value was loaded, false if stored.
-func (m *JSDelivrPackageDataMap) LoadOrStore(key string, value *JSDelivrPackageData) (actual *JSDelivrPackageData, loaded bool) {
+func (m *JSDelivrPackageDataMap) LoadOrStore(key string, value *PackageMetadata) (actual *PackageMetadata, loaded bool) {
 	// Avoid locking if it's a clean hit.
 	read, _ := m.read.Load().(readOnlyJSDelivrPackageDataMap)
 	if e, ok := read.m[key]; ok {
@@ -239,13 +239,13 @@ func (m *JSDelivrPackageDataMap) LoadOrStore(key string, value *JSDelivrPackageD
 //
 // If the entry is expunged, tryLoadOrStore leaves the entry unchanged and
 // returns with ok==false.
-func (e *entryJSDelivrPackageDataMap) tryLoadOrStore(i *JSDelivrPackageData) (actual *JSDelivrPackageData, loaded, ok bool) {
+func (e *entryJSDelivrPackageDataMap) tryLoadOrStore(i *PackageMetadata) (actual *PackageMetadata, loaded, ok bool) {
 	p := atomic.LoadPointer(&e.p)
 	if p == expungedJSDelivrPackageDataMap {
 		return actual, false, false
 	}
 	if p != nil {
-		return *(**JSDelivrPackageData)(p), true, true
+		return *(**PackageMetadata)(p), true, true
 	}
 
 	// Copy the interface after the first load to make this method more amenable
@@ -261,14 +261,14 @@ func (e *entryJSDelivrPackageDataMap) tryLoadOrStore(i *JSDelivrPackageData) (ac
 			return actual, false, false
 		}
 		if p != nil {
-			return *(**JSDelivrPackageData)(p), true, true
+			return *(**PackageMetadata)(p), true, true
 		}
 	}
 }
 
 // LoadAndDelete deletes the value for a key, returning the previous value if any.
 // The loaded result reports whether the key was present.
-func (m *JSDelivrPackageDataMap) LoadAndDelete(key string) (value *JSDelivrPackageData, loaded bool) {
+func (m *JSDelivrPackageDataMap) LoadAndDelete(key string) (value *PackageMetadata, loaded bool) {
 	read, _ := m.read.Load().(readOnlyJSDelivrPackageDataMap)
 	e, ok := read.m[key]
 	if !ok && read.amended {
@@ -296,14 +296,14 @@ func (m *JSDelivrPackageDataMap) Delete(key string) {
 	m.LoadAndDelete(key)
 }
 
-func (e *entryJSDelivrPackageDataMap) delete() (value *JSDelivrPackageData, ok bool) {
+func (e *entryJSDelivrPackageDataMap) delete() (value *PackageMetadata, ok bool) {
 	for {
 		p := atomic.LoadPointer(&e.p)
 		if p == nil || p == expungedJSDelivrPackageDataMap {
 			return value, false
 		}
 		if atomic.CompareAndSwapPointer(&e.p, p, nil) {
-			return *(**JSDelivrPackageData)(p), true
+			return *(**PackageMetadata)(p), true
 		}
 	}
 }
@@ -318,7 +318,7 @@ func (e *entryJSDelivrPackageDataMap) delete() (value *JSDelivrPackageData, ok b
 //
 // Range may be O(N) with the number of elements in the map even if f returns
 // false after a constant number of calls.
-func (m *JSDelivrPackageDataMap) Range(f func(key string, value *JSDelivrPackageData) bool) {
+func (m *JSDelivrPackageDataMap) Range(f func(key string, value *PackageMetadata) bool) {
 	// We need to be able to iterate over all of the keys that were already
 	// present at the start of the call to Range.
 	// If read.amended is false, then read.m satisfies that property without
@@ -1,11 +1,13 @@
 package lockfile_test
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
 	"github.com/jarred-sumner/devserverless/resolver/cache"
 	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/jarred-sumner/devserverless/resolver/node_semver"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,20 +44,19 @@ func TestGetDiff(t *testing.T) {
 var rollup = "^1.20.0||^2.0.0"
 
 func TestSatisfying(t *testing.T) {
-	var store lockfile.PackageManifestStore
-	store = cache.NewMemoryPackageManifestStore()
+	store := cache.NewMemoryPackageManifestStore()
 
 	assertRange(t, rollup, lockfile.VersionRangeRange)
-	assertResolves(t, &store, "rollup", rollup, "2.42.4")
+	assertResolves(t, store, "rollup", rollup, "2.42.4")
 
 	res := TRICKY_VERSIONS[0]
 	name := res[0]
 
 	assertRange(t, "1", lockfile.VersionRangeWildcard)
-	assertResolves(t, &store, "abbrev", "1", "1.1.1")
+	assertResolves(t, store, "abbrev", "1", "1.1.1")
 
 	assertRange(t, "^1.1.2", lockfile.VersionRangeRange)
-	assertResolves(t, &store, name, res[1], "1.1.2")
+	assertResolves(t, store, name, res[1], "1.1.2")
 
 }
 
@@ -65,12 +66,94 @@ func assertRange(t *testing.T, version string, r lockfile.VersionRange) {
 
 func assertResolves(t *testing.T, store *lockfile.PackageManifestStore, name string, version string, to string) {
 
-	metadata, _ := store.FetchPackageMetadata(name, "")
+	metadata, _ := store.FetchPackageMetadata(name, "", context.Background())
 	resolved, err := metadata.Satisfying(version)
 	assert.Nil(t, err)
 	assert.Equal(t, to, resolved)
 }
 
+func newPackageData(latest string, versions ...string) lockfile.PackageMetadata {
+	metadata := lockfile.PackageMetadata{
+		Tags:     map[string]string{"latest": latest},
+		Versions: make(node_semver.Versions, len(versions)),
+	}
+
+	for i, version := range versions {
+		metadata.Versions[i] = *node_semver.Tokenize(version).Version
+	}
+
+	return metadata
+}
+
+func TestSatisfyingStrategies(t *testing.T) {
+	// Unsorted, the way the registry sometimes returns them
+	metadata := newPackageData("2.1.0", "1.2.0", "2.1.0", "1.10.0", "1.9.3", "2.0.0", "2.2.0-beta.1", "2.2.0-beta.0")
+
+	resolved, err := metadata.Satisfying("^1.2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.10.0", resolved)
+
+	resolved, err = metadata.SatisfyingWithStrategy("^1.2.0", lockfile.VersionStrategyLowest)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", resolved)
+
+	resolved, err = metadata.Satisfying("*")
+	assert.Nil(t, err)
+	assert.Equal(t, "2.1.0", resolved)
+
+	resolved, err = metadata.SatisfyingWithStrategy("*", lockfile.VersionStrategyLowest)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", resolved)
+
+	// Prereleases only match ranges that ask for them
+	resolved, err = metadata.Satisfying(">=2.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "2.1.0", resolved)
+
+	resolved, err = metadata.Satisfying(">=2.2.0-beta.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "2.2.0-beta.1", resolved)
+
+	// Only prereleases of the same major.minor.patch as the range's prerelease
+	metadata = newPackageData("1.0.0-beta.0", "1.0.0-beta.1", "1.0.0", "1.5.0-rc.0", "1.5.0", "3.0.0-alpha.0")
+
+	resolved, err = metadata.Satisfying(">=1.0.0-beta.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5.0", resolved)
+
+	resolved, err = metadata.Satisfying(">=1.0.0-beta.0 <1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0.0-beta.1", resolved)
+
+	// A hyphen range has a "-" without asking for a prerelease
+	resolved, err = metadata.Satisfying("1.0.0 - 2.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5.0", resolved)
+
+	resolved, err = metadata.SatisfyingWithStrategy("1.0.0-beta.1 - 2.0.0", lockfile.VersionStrategyLowest)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0.0-beta.1", resolved)
+
+	_, err = metadata.Satisfying("2.0.0 - 3.0.0")
+	assert.NotNil(t, err)
+
+	// Each "||" set only lets in its own prereleases
+	resolved, err = metadata.Satisfying("1.5.0-rc.0 || >=3.0.0-beta")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5.0-rc.0", resolved)
+
+	metadata = newPackageData("2.1.0", "1.2.0", "2.1.0", "1.10.0", "1.9.3", "2.0.0", "2.2.0-beta.1", "2.2.0-beta.0")
+	resolved, err = metadata.Satisfying("1.9.3")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.9.3", resolved)
+
+	_, err = metadata.Satisfying("^3.0.0")
+	assert.NotNil(t, err)
+
+	_, err = metadata.Satisfying("1.9.4")
+	assert.NotNil(t, err)
+}
+
 func TestNewVersionRange(t *testing.T) {
 	for _, tilda := range TILDA_VERSIONS {
 		assert.Equal(t, lockfile.NewVersionRange(tilda, len(tilda)), lockfile.VersionRangeRange)
@@ -0,0 +1,163 @@
+package lockfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"go.uber.org/zap"
+)
+
+const filePrefix = "file:"
+const linkPrefix = "link:"
+const localPathPrefixLength = len(filePrefix)
+
+func isLocalPathPrefix(version string, length int) bool {
+	return length > localPathPrefixLength && (version[0:localPathPrefixLength] == filePrefix || version[0:localPathPrefixLength] == linkPrefix)
+}
+
+// ParseLocalPath splits a path dependency like "file:../my-lib" or "link:../my-lib" into its path.
+// link is true when it should be installed as a symlink instead of a copy. Bare paths like "../my-lib" are copies.
+func ParseLocalPath(version string) (localPath string, link bool) {
+	if isLocalPathPrefix(version, len(version)) {
+		return version[localPathPrefixLength:], version[0:localPathPrefixLength] == linkPrefix
+	}
+
+	return version, false
+}
+
+// NewLocalPathVersion is the version a path dependency is stored under.
+// localPath is relative to the root package.json's folder, so the same folder always has the same key.
+func NewLocalPathVersion(localPath string, link bool) string {
+	if link {
+		return linkPrefix + localPath
+	}
+
+	return filePrefix + localPath
+}
+
+// LocalPackagePath returns the folder of a workspace or path dependency, relative to the root package.json.
+func LocalPackagePath(version string) (string, bool) {
+	if workspacePath, ok := WorkspacePath(version); ok {
+		return workspacePath, true
+	}
+
+	if isLocalPathPrefix(version, len(version)) {
+		localPath, _ := ParseLocalPath(version)
+		return localPath, true
+	}
+
+	return "", false
+}
+
+// localPackageURL is where the import map points at a package on disk. It assumes the root package.json's folder is served at "/".
+func localPackageURL(localPath string) string {
+	return "/" + strings.TrimPrefix(localPath, "./") + "/"
+}
+
+// isOutsideRoot is true for a path dependency above the root package.json's folder, like "../my-lib".
+func isOutsideRoot(localPath string) bool {
+	localPath = path.Clean(localPath)
+	return localPath == ".." || strings.HasPrefix(localPath, "../") || path.IsAbs(localPath)
+}
+
+// localVersion returns the stored version of a path dependency that parentKey depends on.
+// Paths are relative to the package that depends on them, and only the root, workspace & path packages can depend on paths.
+func (p *PackageFlatPack) localVersion(version string, parentKey string) (string, bool) {
+	if p.rootDir == "" {
+		return "", false
+	}
+
+	parentDir := "."
+	if parentKey != "" {
+		parent, ok := p.GetManifest(parentKey)
+		if !ok {
+			return "", false
+		}
+
+		if parentDir, ok = LocalPackagePath(parent.Version.Tag); !ok {
+			return "", false
+		}
+	}
+
+	localPath, link := ParseLocalPath(version)
+	localPath = filepath.FromSlash(localPath)
+	if !filepath.IsAbs(localPath) {
+		localPath = filepath.Join(p.rootDir, filepath.FromSlash(parentDir), localPath)
+	}
+
+	relativePath, err := filepath.Rel(p.rootDir, localPath)
+	if err != nil {
+		return "", false
+	}
+
+	return NewLocalPathVersion(path.Clean(filepath.ToSlash(relativePath)), link), true
+}
+
+// ReadLocalPackage reads the package.json of the folder at localVersion. The package is saved as name, like it is in node_modules.
+func ReadLocalPackage(rootDir string, name string, localVersion string) (*JavascriptPackageManifestPartial, PackageResolutionStatus, error) {
+	localPath, _ := ParseLocalPath(localVersion)
+	packageJSONPath := filepath.Join(rootDir, filepath.FromSlash(localPath), "package.json")
+	body, err := ioutil.ReadFile(packageJSONPath)
+	if os.IsNotExist(err) {
+		return nil, PackageResolutionStatusNotFound, err
+	} else if err != nil {
+		return nil, PackageResolutionStatusInternal, err
+	}
+
+	manifest, err := newJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, true, false)
+	if err != nil {
+		return nil, PackageResolutionStatusCorruptPackage, fmt.Errorf("failed to parse %s: %w", packageJSONPath, err)
+	}
+
+	manifest.Name = name
+	manifest.Provider = PackageProviderDisk
+	manifest.Status = PackageResolutionStatusSuccess
+	// Not SetVersion, since it lowercases the path
+	manifest.Version = Version{
+		Protocol:    PackageVersionProtocolPathlike,
+		OriginalTag: localVersion,
+		Tag:         localVersion,
+	}
+
+	return &manifest, PackageResolutionStatusSuccess, nil
+}
+
+// enqueueLocalPackage resolves a path dependency from the package.json in its folder.
+func (p *PackageFlatPack) enqueueLocalPackage(name string, version string, parentName string, parentKey string) {
+	s := p.store
+	localVersion, ok := p.localVersion(version, parentKey)
+	if !ok {
+		s.Logger.Warn("Path dependencies only work in local packages", zap.String("name", name), zap.String("version", version), zap.String("parent", parentName))
+		p.recordFailure(name, version, PackageResolutionStatusNotFound, parentKey)
+		atomic.AddUint64(&p.ErrorPackageCount, 1)
+		return
+	}
+
+	key := NewPackageManifestKey(name, localVersion)
+	p.setParent(key, parentKey)
+
+	if !p.claim(key) {
+		manifest, exists := p.GetManifest(key)
+		if exists && s.Installer != nil && manifest.Status == PackageResolutionStatusSuccess {
+			s.Installer.Enqueue(manifest)
+		}
+		return
+	}
+
+	manifest, status, err := ReadLocalPackage(p.rootDir, name, localVersion)
+	if err != nil {
+		s.Logger.Warn("Failed to read path dependency", zap.String("name", name), zap.String("version", version), zap.String("parent", parentName), zap.Error(err))
+		p.recordFailure(name, version, status, parentKey)
+		atomic.AddUint64(&p.ErrorPackageCount, 1)
+		return
+	}
+
+	p.putLocalManifest(key, manifest)
+	p.appendCachedManifest(key, manifest, version, parentKey)
+}
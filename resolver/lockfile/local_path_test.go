@@ -0,0 +1,71 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesLocalPaths(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"left-pad": `{"tags": {"latest": "1.3.0"}, "versions": ["1.3.0"]}`,
+		},
+		map[string]string{
+			"left-pad/1.3.0": `{"name": "left-pad", "version": "1.3.0", "main": "index.js"}`,
+		},
+	)
+
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "libs/a/package.json", `{"name": "a", "version": "1.0.0", "main": "index.js", "dependencies": {"c": "file:../c", "left-pad": "^1.0.0"}}`)
+	writeWorkspaceFile(t, dir, "libs/b/package.json", `{"name": "b", "version": "1.0.0", "module": "b.mjs"}`)
+	writeWorkspaceFile(t, dir, "libs/c/package.json", `{"name": "c", "version": "1.0.0", "main": "index.js"}`)
+
+	root := lockfile.JavascriptPackageManifestPartial{
+		Name:               "root",
+		Status:             lockfile.PackageResolutionStatusSuccess,
+		DependencyNames:    []string{"a", "b", "missing"},
+		DependencyVersions: []string{"file:./libs/a", "link:libs/b", "../missing"},
+	}
+	manifest, err := store.ResolveDependenciesWithOptions(&root, lockfile.ResolveOptions{RootDir: dir}, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"a", "b", "c", "left-pad"}, manifest.Name)
+	assert.Equal(t, []string{"file:libs/a", "link:libs/b", "file:libs/c", "1.3.0"}, manifest.Version)
+	assert.Equal(t, []uint{0, 1}, manifest.RootDependencies)
+	// package.json dependencies are a map, so they're in any order
+	assert.ElementsMatch(t, []uint{2, 3}, manifest.DependencyLists()[0])
+	assert.Equal(t, []uint{0, 0, 0}, manifest.DependencyIndex[1:])
+	assert.Equal(t, []string{"missing@../missing: not found"}, manifest.Failures.Messages())
+
+	importMap := decodeImportMap(t, &manifest)
+	assert.Equal(t, "/libs/a/index.js", importMap.Imports["a"])
+	assert.Equal(t, "/libs/b/b.mjs", importMap.Imports["b"])
+	assert.Equal(t, "/libs/c/", importMap.Imports["c/"])
+
+	// Without a root folder, like on the server, nothing is read from disk
+	manifest, err = store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, manifest.Name)
+	assert.Equal(t, []string{"a", "b", "missing"}, manifest.Failures.Name)
+}
+
+func TestParseLocalPath(t *testing.T) {
+	localPath, link := lockfile.ParseLocalPath("file:../my-lib")
+	assert.Equal(t, "../my-lib", localPath)
+	assert.False(t, link)
+
+	localPath, link = lockfile.ParseLocalPath("link:../my-lib")
+	assert.Equal(t, "../my-lib", localPath)
+	assert.True(t, link)
+
+	localPath, link = lockfile.ParseLocalPath("./my-lib")
+	assert.Equal(t, "./my-lib", localPath)
+	assert.False(t, link)
+
+	for _, version := range []string{"file:../my-lib", "link:my-lib", "./my-lib", "../my-lib"} {
+		assert.Equal(t, lockfile.PackageVersionProtocolPathlike, lockfile.NewPackageVersionProtocol(version, len(version)), version)
+	}
+}
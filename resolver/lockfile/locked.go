@@ -0,0 +1,100 @@
+package lockfile
+
+// lockedVersions maps each dependency in a previous lockfile to the version it resolved to, by edgeKey.
+// The root package's dependencies have an empty parent key, like in FetchDependencies.
+func (o ResolveOptions) lockedVersions() map[string]string {
+	if o.Locked == nil {
+		return nil
+	}
+
+	locked := o.Locked
+	versions := make(map[string]string, len(locked.Dependencies)+len(locked.RootDependencies))
+	for _, index := range locked.RootDependencies {
+		versions[edgeKey("", locked.Name[index])] = locked.Version[index]
+	}
+
+	for parent, dependencies := range locked.DependencyLists() {
+		parentKey := NewPackageManifestKey(locked.Name[parent], locked.Version[parent])
+		for _, index := range dependencies {
+			versions[edgeKey(parentKey, locked.Name[index])] = locked.Version[index]
+		}
+	}
+
+	return versions
+}
+
+// lockedVersion returns the version the previous lockfile resolved name@version to, when it's still in range.
+// Only registry ranges are pinned, including npm aliases of them. For an alias, it's the real package's version.
+func (p *PackageFlatPack) lockedVersion(name string, version string, parentKey string) (string, bool) {
+	locked, ok := p.locked[edgeKey(parentKey, name)]
+	if !ok {
+		return "", false
+	}
+
+	if isNpmAliasPrefix(version, len(version)) {
+		if !isNpmAliasPrefix(locked, len(locked)) {
+			return "", false
+		}
+
+		realName, realVersion := ParseNpmAlias(version)
+		lockedName, lockedVersion := ParseNpmAlias(locked)
+		if lockedName != realName {
+			return "", false
+		}
+
+		version = realVersion
+		locked = lockedVersion
+	}
+
+	length := len(version)
+	if NewPackageVersionProtocol(version, length) != PackageVersionProtocolDefault || NewVersionRange(version, length) == VersionRangeExact {
+		return "", false
+	}
+
+	// Git commits, paths & the like aren't registry versions, so a range never pins to them
+	if NewPackageVersionProtocol(locked, len(locked)) != PackageVersionProtocolDefault {
+		return "", false
+	}
+
+	// A tag like "latest" or "beta" keeps whatever it resolved to, even if it points somewhere else now
+	if isDistTag(version) {
+		return locked, true
+	}
+
+	// Ranges are checked the same way as peers
+	if !peerSatisfies(version, locked) {
+		return "", false
+	}
+
+	return locked, true
+}
+
+// isDistTag is whether version names a dist-tag instead of a range. Like npm, tags start with a letter,
+// except for ranges like "x.1" & "v1.2.3".
+func isDistTag(version string) bool {
+	if version == "" {
+		return false
+	}
+
+	first := version[0]
+	if !(first >= 'a' && first <= 'z') && !(first >= 'A' && first <= 'Z') {
+		return false
+	}
+
+	if len(version) == 1 {
+		return first != 'x' && first != 'X'
+	}
+
+	switch first {
+	case 'x', 'X':
+		{
+			return version[1] != '.'
+		}
+	case 'v', 'V':
+		{
+			return !(version[1] >= '0' && version[1] <= '9')
+		}
+	}
+
+	return true
+}
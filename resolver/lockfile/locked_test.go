@@ -0,0 +1,59 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesLocked(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"foo": `{"tags": {"latest": "1.1.0"}, "versions": ["1.0.0", "1.1.0"]}`,
+			"bar": `{"tags": {"latest": "1.1.0"}, "versions": ["1.0.0", "1.1.0"]}`,
+			"baz": `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"qux": `{"tags": {"latest": "2.0.0"}, "versions": ["1.0.0", "2.0.0"]}`,
+			"tg":  `{"tags": {"latest": "1.0.0", "beta": "1.2.0"}, "versions": ["1.0.0", "1.1.0", "1.2.0"]}`,
+		},
+		map[string]string{
+			"foo/1.0.0": `{"name": "foo", "version": "1.0.0", "dependencies": {"bar": "^1.0.0"}}`,
+			"foo/1.1.0": `{"name": "foo", "version": "1.1.0", "dependencies": {"bar": "^1.0.0"}}`,
+			"bar/1.0.0": `{"name": "bar", "version": "1.0.0"}`,
+			"bar/1.1.0": `{"name": "bar", "version": "1.1.0"}`,
+			"baz/1.0.0": `{"name": "baz", "version": "1.0.0", "dependencies": {"bar": "^1.0.0"}}`,
+			"qux/1.0.0": `{"name": "qux", "version": "1.0.0"}`,
+			"qux/2.0.0": `{"name": "qux", "version": "2.0.0"}`,
+			"tg/1.1.0":  `{"name": "tg", "version": "1.1.0"}`,
+			"tg/1.2.0":  `{"name": "tg", "version": "1.2.0"}`,
+		},
+	)
+
+	// Resolved before foo 1.1.0 & bar 1.1.0 were published, and while tg's "beta" tag was 1.1.0
+	locked := lockfile.JavascriptPackageManifest{
+		Name:             []string{"bar", "foo", "qux", "sw", "tg"},
+		Version:          []string{"1.0.0", "1.0.0", "1.0.0", "npm:bar@1.0.0", "1.1.0"},
+		DependencyIndex:  []uint{0, 1, 0, 0, 0},
+		Dependencies:     []uint{0},
+		RootDependencies: []uint{1, 2, 3, 4},
+	}
+
+	// baz is new, and qux's range changed
+	body := []byte(`{"name": "root", "dependencies": {"foo": "^1.0.0", "qux": "^2.0.0", "sw": "npm:bar@^1.0.0", "baz": "^1.0.0", "tg": "beta"}}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+
+	manifest, err := store.ResolveDependenciesWithOptions(&root, lockfile.ResolveOptions{Locked: &locked}, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"bar", "bar", "baz", "foo", "qux", "sw", "tg"}, manifest.Name)
+	// tg keeps the version its tag pointed at
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "1.0.0", "1.0.0", "2.0.0", "npm:bar@1.0.0", "1.1.0"}, manifest.Version)
+	lists := manifest.DependencyLists()
+	// Only baz's bar is new
+	assert.Equal(t, []uint{1}, lists[2])
+	assert.Equal(t, []uint{0}, lists[3])
+	assert.ElementsMatch(t, []uint{2, 3, 4, 5, 6}, manifest.RootDependencies)
+	assert.Empty(t, manifest.Failures.Name)
+}
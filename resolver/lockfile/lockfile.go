@@ -16,6 +16,7 @@ const (
   PackageProviderDisk PackageProvider = 5
   PackageProviderOther PackageProvider = 6
   PackageProviderGithub PackageProvider = 7
+  PackageProviderWorkspace PackageProvider = 8
 
 )
 
@@ -27,6 +28,7 @@ var PackageProviderToString = map[PackageProvider]string{
   PackageProviderDisk: "PackageProviderDisk",
   PackageProviderOther: "PackageProviderOther",
   PackageProviderGithub: "PackageProviderGithub",
+  PackageProviderWorkspace: "PackageProviderWorkspace",
 
 }
 
@@ -38,6 +40,7 @@ var PackageProviderToID = map[string]PackageProvider{
   "PackageProviderDisk": PackageProviderDisk,
   "PackageProviderOther": PackageProviderOther,
   "PackageProviderGithub": PackageProviderGithub,
+  "PackageProviderWorkspace": PackageProviderWorkspace,
 
 }
 
@@ -78,6 +81,8 @@ const (
   PackageVersionProtocolGitSsh PackageVersionProtocol = 10
   PackageVersionProtocolPathlike PackageVersionProtocol = 11
   PackageVersionProtocolDefault PackageVersionProtocol = 12
+  PackageVersionProtocolNpm PackageVersionProtocol = 13
+  PackageVersionProtocolWorkspace PackageVersionProtocol = 14
 
 )
 
@@ -94,6 +99,8 @@ var PackageVersionProtocolToString = map[PackageVersionProtocol]string{
   PackageVersionProtocolGitSsh: "PackageVersionProtocolGitSsh",
   PackageVersionProtocolPathlike: "PackageVersionProtocolPathlike",
   PackageVersionProtocolDefault: "PackageVersionProtocolDefault",
+  PackageVersionProtocolNpm: "PackageVersionProtocolNpm",
+  PackageVersionProtocolWorkspace: "PackageVersionProtocolWorkspace",
 
 }
 
@@ -110,6 +117,8 @@ var PackageVersionProtocolToID = map[string]PackageVersionProtocol{
   "PackageVersionProtocolGitSsh": PackageVersionProtocolGitSsh,
   "PackageVersionProtocolPathlike": PackageVersionProtocolPathlike,
   "PackageVersionProtocolDefault": PackageVersionProtocolDefault,
+  "PackageVersionProtocolNpm": PackageVersionProtocolNpm,
+  "PackageVersionProtocolWorkspace": PackageVersionProtocolWorkspace,
 
 }
 
@@ -200,6 +209,7 @@ const (
   PackageResolutionStatusRateLimit PackageResolutionStatus = 6
   PackageResolutionStatusInvalidVersion PackageResolutionStatus = 7
   PackageResolutionStatusInternal PackageResolutionStatus = 8
+  PackageResolutionStatusUnsupportedPlatform PackageResolutionStatus = 9
 
 )
 
@@ -212,6 +222,7 @@ var PackageResolutionStatusToString = map[PackageResolutionStatus]string{
   PackageResolutionStatusRateLimit: "PackageResolutionStatusRateLimit",
   PackageResolutionStatusInvalidVersion: "PackageResolutionStatusInvalidVersion",
   PackageResolutionStatusInternal: "PackageResolutionStatusInternal",
+  PackageResolutionStatusUnsupportedPlatform: "PackageResolutionStatusUnsupportedPlatform",
 
 }
 
@@ -224,6 +235,7 @@ var PackageResolutionStatusToID = map[string]PackageResolutionStatus{
   "PackageResolutionStatusRateLimit": PackageResolutionStatusRateLimit,
   "PackageResolutionStatusInvalidVersion": PackageResolutionStatusInvalidVersion,
   "PackageResolutionStatusInternal": PackageResolutionStatusInternal,
+  "PackageResolutionStatusUnsupportedPlatform": PackageResolutionStatusUnsupportedPlatform,
 
 }
 
@@ -249,6 +261,54 @@ func (s *PackageResolutionStatus) UnmarshalJSON(b []byte) error {
 }
 
         
+type DependencyGroup uint
+
+const (
+  DependencyGroupProd DependencyGroup = 1
+  DependencyGroupDev DependencyGroup = 2
+  DependencyGroupPeer DependencyGroup = 4
+  DependencyGroupOptional DependencyGroup = 8
+
+)
+
+var DependencyGroupToString = map[DependencyGroup]string{
+  DependencyGroupProd: "DependencyGroupProd",
+  DependencyGroupDev: "DependencyGroupDev",
+  DependencyGroupPeer: "DependencyGroupPeer",
+  DependencyGroupOptional: "DependencyGroupOptional",
+
+}
+
+var DependencyGroupToID = map[string]DependencyGroup{
+  "DependencyGroupProd": DependencyGroupProd,
+  "DependencyGroupDev": DependencyGroupDev,
+  "DependencyGroupPeer": DependencyGroupPeer,
+  "DependencyGroupOptional": DependencyGroupOptional,
+
+}
+
+
+// MarshalJSON marshals the enum as a quoted json string
+func (s DependencyGroup) MarshalJSON() ([]byte, error) {
+  buffer := bytes.NewBufferString(`"`)
+  buffer.WriteString(DependencyGroupToString[s])
+  buffer.WriteString(`"`)
+  return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON unmashals a quoted json string to the enum value
+func (s *DependencyGroup) UnmarshalJSON(b []byte) error {
+  var j string
+  err := json.Unmarshal(b, &j)
+  if err != nil {
+    return err
+  }
+  // Note that if the string cannot be found then it will be set to the zero value, 'Created' in this case.
+  *s = DependencyGroupToID[j]
+  return nil
+}
+
+        
 type BareField byte
 
 const (
@@ -472,6 +532,136 @@ func (i *RawDependencyList) Encode(buf *buffer.Buffer) error {
   return nil
 }
 
+type PackageResolutionFailures struct {
+Name    []string     `json:"name" redis:"name"`
+Range    []string     `json:"range" redis:"range"`
+Status    []PackageResolutionStatus     `json:"status" redis:"status"`
+ParentCount    []uint     `json:"parentCount" redis:"parentCount"`
+Parents    []string     `json:"parents" redis:"parents"`
+}
+
+func DecodePackageResolutionFailures(buf *buffer.Buffer) (PackageResolutionFailures, error) {
+   result := PackageResolutionFailures{}
+
+  var length uint;
+  length = buf.ReadVarUint();
+  result.Name = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Name[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Range = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Range[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Status = make([]PackageResolutionStatus, length)
+  for j := uint(0); j < length; j++ { result.Status[j] = PackageResolutionStatus(buf.ReadByte()); }
+  length = buf.ReadVarUint();
+  result.ParentCount = make([]uint, length)
+  for j := uint(0); j < length; j++ { result.ParentCount[j] = buf.ReadVarUint(); }
+  length = buf.ReadVarUint();
+  result.Parents = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Parents[j] = buf.ReadAlphanumeric(); }
+  return result, nil;
+}
+
+func (i *PackageResolutionFailures) Encode(buf *buffer.Buffer) error {
+
+    var n uint;
+    n = uint(len(i.Name))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Name[j]);
+    }
+
+    n = uint(len(i.Range))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Range[j]);
+    }
+
+    n = uint(len(i.Status))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteByte(byte(i.Status[j]))
+    }
+
+    n = uint(len(i.ParentCount))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteVarUint(i.ParentCount[j]);
+    }
+
+    n = uint(len(i.Parents))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Parents[j]);
+    }
+  return nil
+}
+
+type PeerDependencyConflicts struct {
+Name    []string     `json:"name" redis:"name"`
+Range    []string     `json:"range" redis:"range"`
+Requester    []string     `json:"requester" redis:"requester"`
+Provided    []string     `json:"provided" redis:"provided"`
+Provider    []string     `json:"provider" redis:"provider"`
+}
+
+func DecodePeerDependencyConflicts(buf *buffer.Buffer) (PeerDependencyConflicts, error) {
+   result := PeerDependencyConflicts{}
+
+  var length uint;
+  length = buf.ReadVarUint();
+  result.Name = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Name[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Range = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Range[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Requester = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Requester[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Provided = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Provided[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Provider = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Provider[j] = buf.ReadAlphanumeric(); }
+  return result, nil;
+}
+
+func (i *PeerDependencyConflicts) Encode(buf *buffer.Buffer) error {
+
+    var n uint;
+    n = uint(len(i.Name))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Name[j]);
+    }
+
+    n = uint(len(i.Range))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Range[j]);
+    }
+
+    n = uint(len(i.Requester))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Requester[j]);
+    }
+
+    n = uint(len(i.Provided))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Provided[j]);
+    }
+
+    n = uint(len(i.Provider))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Provider[j]);
+    }
+  return nil
+}
+
 type JavascriptPackageManifest struct {
 Hash    string     `json:"hash" redis:"hash"`
 Count    uint     `json:"count" redis:"count"`
@@ -482,6 +672,17 @@ Provider    PackageProvider     `json:"provider" redis:"provider"`
 ExportsManifest    ExportsManifest     `json:"exportsManifest" redis:"exportsManifest"`
 ExportsManifestIndex    []uint     `json:"exportsManifestIndex" redis:"exportsManifestIndex"`
 Dependencies    []uint     `json:"dependencies" redis:"dependencies"`
+RootDependencies    []uint     `json:"rootDependencies" redis:"rootDependencies"`
+Failures    PackageResolutionFailures     `json:"failures" redis:"failures"`
+Groups    []uint     `json:"groups" redis:"groups"`
+PeerConflicts    PeerDependencyConflicts     `json:"peerConflicts" redis:"peerConflicts"`
+Registrar    string     `json:"registrar" redis:"registrar"`
+ImportMapHost    string     `json:"importMapHost" redis:"importMapHost"`
+HashInputs    []string     `json:"hashInputs" redis:"hashInputs"`
+Integrity    []string     `json:"integrity" redis:"integrity"`
+Os    []string     `json:"os" redis:"os"`
+Cpu    []string     `json:"cpu" redis:"cpu"`
+Libc    []string     `json:"libc" redis:"libc"`
 }
 
 func DecodeJavascriptPackageManifest(buf *buffer.Buffer) (JavascriptPackageManifest, error) {
@@ -511,6 +712,37 @@ var err error;
   length = buf.ReadVarUint();
   result.Dependencies = make([]uint, length)
   for j := uint(0); j < length; j++ { result.Dependencies[j] = buf.ReadVarUint(); }
+  length = buf.ReadVarUint();
+  result.RootDependencies = make([]uint, length)
+  for j := uint(0); j < length; j++ { result.RootDependencies[j] = buf.ReadVarUint(); }
+  result.Failures, err = DecodePackageResolutionFailures(buf)
+  if err != nil {
+    return result, err;
+  }
+  length = buf.ReadVarUint();
+  result.Groups = make([]uint, length)
+  for j := uint(0); j < length; j++ { result.Groups[j] = buf.ReadVarUint(); }
+  result.PeerConflicts, err = DecodePeerDependencyConflicts(buf)
+  if err != nil {
+    return result, err;
+  }
+  result.Registrar = buf.ReadString()
+  result.ImportMapHost = buf.ReadString()
+  length = buf.ReadVarUint();
+  result.HashInputs = make([]string, length)
+  for j := uint(0); j < length; j++ { result.HashInputs[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Integrity = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Integrity[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Os = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Os[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Cpu = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Cpu[j] = buf.ReadString(); }
+  length = buf.ReadVarUint();
+  result.Libc = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Libc[j] = buf.ReadString(); }
   return result, nil;
 }
 
@@ -559,6 +791,64 @@ var err error;
     for j := uint(0); j < n; j++ {
       buf.WriteVarUint(i.Dependencies[j]);
     }
+
+    n = uint(len(i.RootDependencies))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteVarUint(i.RootDependencies[j]);
+    }
+
+    err =i.Failures.Encode(buf)
+    if err != nil {
+ return err
+}
+
+
+    n = uint(len(i.Groups))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteVarUint(i.Groups[j]);
+    }
+
+    err =i.PeerConflicts.Encode(buf)
+    if err != nil {
+ return err
+}
+
+
+    buf.WriteString(i.Registrar);
+
+    buf.WriteString(i.ImportMapHost);
+
+    n = uint(len(i.HashInputs))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.HashInputs[j]);
+    }
+
+    n = uint(len(i.Integrity))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Integrity[j]);
+    }
+
+    n = uint(len(i.Os))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Os[j]);
+    }
+
+    n = uint(len(i.Cpu))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Cpu[j]);
+    }
+
+    n = uint(len(i.Libc))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteString(i.Libc[j]);
+    }
   return nil
 }
 
@@ -613,6 +903,16 @@ ScriptValues    []string     `json:"scriptValues" redis:"scriptValues"`
 BinKeys    []string     `json:"binKeys" redis:"binKeys"`
 BinValues    []string     `json:"binValues" redis:"binValues"`
 HasPostInstall    bool     `json:"hasPostInstall" redis:"hasPostInstall"`
+OverrideSelectors    []string     `json:"overrideSelectors" redis:"overrideSelectors"`
+OverrideVersions    []string     `json:"overrideVersions" redis:"overrideVersions"`
+OptionalDependencyNames    []string     `json:"optionalDependencyNames" redis:"optionalDependencyNames"`
+OptionalDependencyVersions    []string     `json:"optionalDependencyVersions" redis:"optionalDependencyVersions"`
+Os    []string     `json:"os" redis:"os"`
+Cpu    []string     `json:"cpu" redis:"cpu"`
+Libc    []string     `json:"libc" redis:"libc"`
+OptionalPeerDependencyNames    []string     `json:"optionalPeerDependencyNames" redis:"optionalPeerDependencyNames"`
+BinDirectory    string     `json:"binDirectory" redis:"binDirectory"`
+Integrity    string     `json:"integrity" redis:"integrity"`
 }
 
 func DecodeJavascriptPackageManifestPartial(buf *buffer.Buffer) (JavascriptPackageManifestPartial, error) {
@@ -662,6 +962,32 @@ var err error;
   result.BinValues = make([]string, length)
   for j := uint(0); j < length; j++ { result.BinValues[j] = buf.ReadString(); }
   result.HasPostInstall = buf.ReadBool()
+  length = buf.ReadVarUint();
+  result.OverrideSelectors = make([]string, length)
+  for j := uint(0); j < length; j++ { result.OverrideSelectors[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.OverrideVersions = make([]string, length)
+  for j := uint(0); j < length; j++ { result.OverrideVersions[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.OptionalDependencyNames = make([]string, length)
+  for j := uint(0); j < length; j++ { result.OptionalDependencyNames[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.OptionalDependencyVersions = make([]string, length)
+  for j := uint(0); j < length; j++ { result.OptionalDependencyVersions[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Os = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Os[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Cpu = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Cpu[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.Libc = make([]string, length)
+  for j := uint(0); j < length; j++ { result.Libc[j] = buf.ReadAlphanumeric(); }
+  length = buf.ReadVarUint();
+  result.OptionalPeerDependencyNames = make([]string, length)
+  for j := uint(0); j < length; j++ { result.OptionalPeerDependencyNames[j] = buf.ReadAlphanumeric(); }
+  result.BinDirectory = buf.ReadString()
+  result.Integrity = buf.ReadString()
   return result, nil;
 }
 
@@ -748,6 +1074,58 @@ var err error;
     }
 
     buf.WriteBool(i.HasPostInstall);
+
+    n = uint(len(i.OverrideSelectors))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.OverrideSelectors[j]);
+    }
+
+    n = uint(len(i.OverrideVersions))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.OverrideVersions[j]);
+    }
+
+    n = uint(len(i.OptionalDependencyNames))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.OptionalDependencyNames[j]);
+    }
+
+    n = uint(len(i.OptionalDependencyVersions))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.OptionalDependencyVersions[j]);
+    }
+
+    n = uint(len(i.Os))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Os[j]);
+    }
+
+    n = uint(len(i.Cpu))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Cpu[j]);
+    }
+
+    n = uint(len(i.Libc))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.Libc[j]);
+    }
+
+    n = uint(len(i.OptionalPeerDependencyNames))
+    buf.WriteVarUint(n);
+    for j := uint(0); j < n; j++ {
+      buf.WriteAlphanumeric(i.OptionalPeerDependencyNames[j]);
+    }
+
+    buf.WriteString(i.BinDirectory);
+
+    buf.WriteString(i.Integrity);
   return nil
 }
 
@@ -756,6 +1134,10 @@ ClientVersion    *string     `json:"clientVersion" redis:"clientVersion"`
 Name    *string     `json:"name" redis:"name"`
 EnableDenylist    *bool     `json:"enableDenylist" redis:"enableDenylist"`
 Manifest    *JavascriptPackageManifestPartial     `json:"manifest" redis:"manifest"`
+Os    *string     `json:"os" redis:"os"`
+Cpu    *string     `json:"cpu" redis:"cpu"`
+Libc    *string     `json:"libc" redis:"libc"`
+Groups    *uint     `json:"groups" redis:"groups"`
 }
 
 func DecodeJavascriptPackageRequest(buf *buffer.Buffer) (JavascriptPackageRequest, error) {
@@ -788,6 +1170,22 @@ var fieldType uint;
         return result, err;
       }
 
+    case 5:
+      os_4 := buf.ReadAlphanumeric()
+      result.Os = &os_4
+
+    case 6:
+      cpu_5 := buf.ReadAlphanumeric()
+      result.Cpu = &cpu_5
+
+    case 7:
+      libc_6 := buf.ReadAlphanumeric()
+      result.Libc = &libc_6
+
+    case 8:
+      groups_7 := buf.ReadVarUint()
+      result.Groups = &groups_7
+
     default:
       return result, errors.New("attempted to parse invalid message");
     }
@@ -820,6 +1218,26 @@ var err error;
 }
 
    }
+
+  if i.Os != nil {
+    buf.WriteVarUint(5);
+    buf.WriteAlphanumeric(*i.Os);
+   }
+
+  if i.Cpu != nil {
+    buf.WriteVarUint(6);
+    buf.WriteAlphanumeric(*i.Cpu);
+   }
+
+  if i.Libc != nil {
+    buf.WriteVarUint(7);
+    buf.WriteAlphanumeric(*i.Libc);
+   }
+
+  if i.Groups != nil {
+    buf.WriteVarUint(8);
+    buf.WriteVarUint(*i.Groups);
+   }
   buf.WriteVarUint(0);
   return nil
 }
@@ -0,0 +1,47 @@
+package lockfile
+
+import "strings"
+
+const npmAliasPrefix = "npm:"
+const npmAliasPrefixLength = len(npmAliasPrefix)
+
+func isNpmAliasPrefix(version string, length int) bool {
+	return length > npmAliasPrefixLength && version[0:npmAliasPrefixLength] == npmAliasPrefix
+}
+
+// ParseNpmAlias splits "npm:string-width@^4.2.0" into "string-width" and "^4.2.0".
+// Without a version, like "npm:string-width", the range is "*".
+func ParseNpmAlias(version string) (string, string) {
+	target := version[npmAliasPrefixLength:]
+
+	// Skip the @ at the start of a scoped package name
+	at := strings.LastIndexByte(target, '@')
+	if at < 1 {
+		return target, "*"
+	}
+
+	return target[:at], target[at+1:]
+}
+
+// NewNpmAliasVersion is the version an alias of name@version is stored under.
+func NewNpmAliasVersion(name string, version string) string {
+	return npmAliasPrefix + NewPackageManifestKey(name, version)
+}
+
+// NpmAliasTarget returns the real package name & version when version is an "npm:" alias.
+// Otherwise, it returns name & version unchanged.
+func NpmAliasTarget(name string, version string) (string, string) {
+	if isNpmAliasPrefix(version, len(version)) {
+		return ParseNpmAlias(version)
+	}
+
+	return name, version
+}
+
+// newNpmAliasManifest copies the real package's manifest so it's installed & imported as alias.
+func newNpmAliasManifest(alias string, name string, version string, manifest *JavascriptPackageManifestPartial) *JavascriptPackageManifestPartial {
+	aliased := *manifest
+	aliased.Name = alias
+	aliased.SetVersion(NewNpmAliasVersion(name, version))
+	return &aliased
+}
@@ -0,0 +1,259 @@
+package lockfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jarred-sumner/devserverless/resolver/node_semver"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Separates the package names in an override selector, like "foo>bar"
+const overrideSeparator = ">"
+
+// Override versions starting with this use the root package's own range for the named dependency, like "$foo"
+const overrideReferencePrefix = "$"
+
+// appendNpmOverrides flattens npm's nested "overrides" object into selectors.
+// {"foo": {".": "1.0.0", "bar": "1.2.3"}} becomes "foo" = "1.0.0" and "foo>bar" = "1.2.3".
+func appendNpmOverrides(selectors []string, versions []string, obj jsoniter.Any, parent string) ([]string, []string) {
+	if obj.ValueType() != jsoniter.ObjectValue {
+		return selectors, versions
+	}
+
+	for _, key := range obj.Keys() {
+		child := obj.Get(key)
+		selector := parent
+		if key != "." {
+			selector = joinOverrideSelector(parent, NormalizePackageNameString(key))
+		}
+
+		switch child.ValueType() {
+		case jsoniter.StringValue:
+			{
+				if selector != "" {
+					selectors = append(selectors, selector)
+					versions = append(versions, child.ToString())
+				}
+			}
+		case jsoniter.ObjectValue:
+			{
+				if key != "." {
+					selectors, versions = appendNpmOverrides(selectors, versions, child, selector)
+				}
+			}
+		}
+	}
+
+	return selectors, versions
+}
+
+func joinOverrideSelector(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + overrideSeparator + name
+}
+
+// NewYarnResolutionSelector turns a yarn "resolutions" key like "foo/**/bar" or "**/@scope/bar" into an override selector like "foo>bar".
+// "**" segments are dropped, since selectors already match anywhere below their parents.
+func NewYarnResolutionSelector(key string) string {
+	parts := strings.Split(strings.Trim(key, " /"), "/")
+	names := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if part == "" || part == "**" || part == "*" {
+			continue
+		}
+
+		if part[0] == '@' && i+1 < len(parts) {
+			i++
+			part = part + "/" + parts[i]
+		}
+
+		names = append(names, NormalizePackageNameString(part))
+	}
+
+	return strings.Join(names, overrideSeparator)
+}
+
+// resolveOverrideReferences replaces "$foo" override versions with the range p depends on foo with.
+func (p *JavascriptPackageManifestPartial) resolveOverrideReferences() error {
+	for i, version := range p.OverrideVersions {
+		if !strings.HasPrefix(version, overrideReferencePrefix) {
+			continue
+		}
+
+		name := NormalizePackageNameString(version[len(overrideReferencePrefix):])
+		resolved, ok := findDependencyVersion(name, p.DependencyNames, p.DependencyVersions)
+		if !ok {
+			resolved, ok = findDependencyVersion(name, p.DevDependencyNames, p.DevDependencyVersions)
+		}
+		if !ok {
+			resolved, ok = findDependencyVersion(name, p.PeerDependencyNames, p.PeerDependencyVersions)
+		}
+		if !ok {
+			resolved, ok = findDependencyVersion(name, p.OptionalDependencyNames, p.OptionalDependencyVersions)
+		}
+
+		if !ok {
+			return fmt.Errorf("override for %s references %s, which %s doesn't depend on", p.OverrideSelectors[i], version, p.Name)
+		}
+
+		p.OverrideVersions[i] = resolved
+	}
+
+	return nil
+}
+
+func findDependencyVersion(name string, names []string, versions []string) (string, bool) {
+	for i := range names {
+		if names[i] == name {
+			return versions[i], true
+		}
+	}
+
+	return "", false
+}
+
+type overrideSegment struct {
+	name string
+	// Empty when any version matches
+	versionRange string
+}
+
+func newOverrideSegment(segment string) overrideSegment {
+	// The first character is skipped so a scope's "@" isn't mistaken for a version
+	if at := strings.IndexByte(segment[1:], '@'); at > -1 {
+		return overrideSegment{name: segment[:at+1], versionRange: segment[at+2:]}
+	}
+
+	return overrideSegment{name: segment}
+}
+
+// satisfiedBy reports whether version is in the segment's range. Anything that isn't a semver range only matches itself.
+func (s overrideSegment) satisfiedBy(version string) bool {
+	switch s.versionRange {
+	case "", "*", version:
+		{
+			return true
+		}
+	}
+
+	if NewPackageVersionProtocol(s.versionRange, len(s.versionRange)) != PackageVersionProtocolDefault {
+		return false
+	}
+
+	tokenized := node_semver.Tokenize(s.versionRange)
+	return tokenized.Value != node_semver.TokenizeResultValueNone && tokenized.TestString(version)
+}
+
+// override replaces the range of every dependency on target that's somewhere below all of ancestors, in order.
+// target's own version, like "bar@1.0.0", is matched against the range bar was requested with.
+type override struct {
+	ancestors []overrideSegment
+	target    overrideSegment
+	version   string
+}
+
+// newOverrides groups the root package's overrides by the package name they replace.
+func newOverrides(pkg *JavascriptPackageManifestPartial) map[string][]override {
+	overrides := make(map[string][]override, len(pkg.OverrideSelectors))
+	for i, selector := range pkg.OverrideSelectors {
+		segments := strings.Split(selector, overrideSeparator)
+		o := override{
+			ancestors: make([]overrideSegment, 0, len(segments)-1),
+			version:   pkg.OverrideVersions[i],
+		}
+
+		valid := true
+		for _, segment := range segments {
+			if segment == "" {
+				valid = false
+				break
+			}
+
+			o.ancestors = append(o.ancestors, newOverrideSegment(segment))
+		}
+
+		if !valid {
+			continue
+		}
+
+		o.target = o.ancestors[len(o.ancestors)-1]
+		o.ancestors = o.ancestors[:len(o.ancestors)-1]
+		overrides[o.target.name] = append(overrides[o.target.name], o)
+	}
+
+	return overrides
+}
+
+// overrideVersion returns the range to resolve name with, after the root package's overrides.
+// The most specific matching selector wins. Packages reached through several parents are matched against the first one, same as failures.
+func (p *PackageFlatPack) overrideVersion(name string, version string, parentKey string) string {
+	candidates, ok := p.overrides[name]
+	if !ok {
+		return version
+	}
+
+	var chain []string
+	var best *override
+	for i := range candidates {
+		candidate := &candidates[i]
+		if !candidate.target.satisfiedBy(version) {
+			continue
+		}
+
+		if best != nil && len(candidate.ancestors) <= len(best.ancestors) {
+			continue
+		}
+
+		if len(candidate.ancestors) > 0 && chain == nil {
+			p.packageKeysMutex.Lock()
+			chain = p.parentChain(parentKey)
+			p.packageKeysMutex.Unlock()
+		}
+
+		if matchesAncestors(candidate.ancestors, chain) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return version
+	}
+
+	return best.version
+}
+
+// matchesAncestors reports whether every segment matches a key in chain, in the same order.
+func matchesAncestors(ancestors []overrideSegment, chain []string) bool {
+	next := 0
+	for _, key := range chain {
+		if next == len(ancestors) {
+			break
+		}
+
+		name, version := splitPackageManifestKey(key)
+		if ancestors[next].name == name && ancestors[next].satisfiedBy(version) {
+			next++
+		}
+	}
+
+	return next == len(ancestors)
+}
+
+// splitPackageManifestKey is the inverse of NewPackageManifestKey.
+func splitPackageManifestKey(key string) (string, string) {
+	if len(key) == 0 {
+		return "", ""
+	}
+
+	at := strings.IndexByte(key[1:], '@')
+	if at == -1 {
+		return key, ""
+	}
+
+	return key[:at+1], key[at+2:]
+}
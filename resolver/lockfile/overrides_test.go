@@ -0,0 +1,81 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesOverrides(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"foo":    `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"qux":    `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"bar":    `{"tags": {"latest": "2.0.0"}, "versions": ["1.0.0", "1.2.3", "2.0.0"]}`,
+			"lodash": `{"tags": {"latest": "4.17.21"}, "versions": ["4.17.20", "4.17.21"]}`,
+		},
+		map[string]string{
+			"foo/1.0.0":      `{"name": "foo", "version": "1.0.0", "dependencies": {"bar": "^2.0.0", "lodash": "4.17.20"}}`,
+			"qux/1.0.0":      `{"name": "qux", "version": "1.0.0", "dependencies": {"bar": "^2.0.0"}}`,
+			"bar/1.0.0":      `{"name": "bar", "version": "1.0.0"}`,
+			"bar/1.2.3":      `{"name": "bar", "version": "1.2.3"}`,
+			"bar/2.0.0":      `{"name": "bar", "version": "2.0.0"}`,
+			"lodash/4.17.20": `{"name": "lodash", "version": "4.17.20"}`,
+			"lodash/4.17.21": `{"name": "lodash", "version": "4.17.21"}`,
+		},
+	)
+
+	body := []byte(`{
+		"name": "root",
+		"dependencies": {"foo": "^1.0.0", "qux": "^1.0.0", "lodash": "^4.17.21"},
+		"overrides": {"foo": {"bar": "1.2.3"}, "lodash": "$lodash"}
+	}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"bar", "bar", "foo", "lodash", "qux"}, manifest.Name)
+	assert.Equal(t, []string{"1.2.3", "2.0.0", "1.0.0", "4.17.21", "1.0.0"}, manifest.Version)
+	lists := manifest.DependencyLists()
+	// foo's bar is overridden, but qux's isn't
+	assert.ElementsMatch(t, []uint{0, 3}, lists[2])
+	assert.Equal(t, []uint{1}, lists[4])
+	assert.Empty(t, manifest.Failures.Name)
+}
+
+func TestOverridesParsing(t *testing.T) {
+	body := []byte(`{
+		"name": "root",
+		"dependencies": {"react": "^17.0.2"},
+		"overrides": {"foo": {".": "1.0.0", "bar": {"baz": "2.0.0"}}, "react": "$react"},
+		"resolutions": {"**/@scope/qux": "3.0.0", "a/**/b": "4.0.0"}
+	}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+
+	overrides := make(map[string]string, len(root.OverrideSelectors))
+	for i, selector := range root.OverrideSelectors {
+		overrides[selector] = root.OverrideVersions[i]
+	}
+	assert.Equal(t, map[string]string{
+		"foo":         "1.0.0",
+		"foo>bar>baz": "2.0.0",
+		"react":       "^17.0.2",
+		"@scope/qux":  "3.0.0",
+		"a>b":         "4.0.0",
+	}, overrides)
+
+	body = []byte(`{"name": "root", "overrides": {"react": "$react"}}`)
+	_, err = lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.NotNil(t, err)
+
+	// Dependencies' overrides never apply, so they don't fail
+	body = []byte(`{"name": "dep", "overrides": {"react": "$react"}, "resolutions": {"foo": "1.0.0"}}`)
+	dep, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Empty(t, dep.OverrideSelectors)
+}
@@ -1,7 +1,6 @@
 package lockfile
 
 import (
-	"fmt"
 	"os"
 	"sort"
 	"strconv"
@@ -9,6 +8,7 @@ import (
 
 	"github.com/cespare/xxhash"
 	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/tarball"
 	"github.com/jarred-sumner/peechy/buffer"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/valyala/bytebufferpool"
@@ -74,7 +74,13 @@ func NewJavascriptPackageManifestPartialFromNameVersion(name string, version str
 
 }
 
+// NewJavascriptPackageManifestPartial parses a package.json. enableScripts is for the root & workspace package.json, which keep every script and their overrides.
 func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, enableScripts bool) (JavascriptPackageManifestPartial, error) {
+	return newJavascriptPackageManifestPartial(body, enableBlacklist, enableScripts, enableScripts)
+}
+
+// Only the root & workspace overrides apply, so other packages skip them instead of failing on ones that don't parse.
+func newJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, enableScripts bool, enableOverrides bool) (JavascriptPackageManifestPartial, error) {
 
 	res := JavascriptPackageManifestPartial{
 		Name:     "",
@@ -100,6 +106,7 @@ func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, ena
 	var depsMap map[string]string
 	depsMap = make(map[string]string)
 	var bareImports = make(map[BareField]string, 2)
+	var binPath string
 
 	for {
 		objectKey = iter.ReadObject()
@@ -223,6 +230,46 @@ func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, ena
 				}
 
 			}
+		case "peerDependenciesMeta":
+			{
+				iter.ReadMapCB(func(iterator *jsoniter.Iterator, key string) bool {
+					if iter.ReadAny().Get("optional").ToBool() {
+						res.OptionalPeerDependencyNames = append(res.OptionalPeerDependencyNames, NormalizePackageNameString(key))
+					}
+					return true
+				})
+			}
+
+		case "optionalDependencies":
+			{
+				for k := range depsMap {
+					delete(depsMap, k)
+				}
+				iter.ReadMapCB(func(iterator *jsoniter.Iterator, key string) bool {
+					depsMap[key] = iter.ReadString()
+					return true
+				})
+
+				if len(depsMap) > 0 {
+					res.OptionalDependencyNames, res.OptionalDependencyVersions = res.processDependencyList(depsMap)
+				}
+			}
+
+		case "os":
+			{
+				res.Os = stringListFromAny(iter.ReadAny())
+			}
+
+		case "cpu":
+			{
+				res.Cpu = stringListFromAny(iter.ReadAny())
+			}
+
+		case "libc":
+			{
+				res.Libc = stringListFromAny(iter.ReadAny())
+			}
+
 		case "scripts":
 			{
 				if enableScripts {
@@ -239,7 +286,22 @@ func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, ena
 						return true
 					})
 				} else {
-					iter.Skip()
+					// Dependencies only keep the scripts that run when they're installed
+					iter.ReadMapCB(func(iterator *jsoniter.Iterator, key string) bool {
+						switch key {
+						case "preinstall", "install", "postinstall":
+							{
+								res.ScriptKeys = append(res.ScriptKeys, key)
+								res.ScriptValues = append(res.ScriptValues, iter.ReadString())
+								res.HasPostInstall = res.HasPostInstall || key == "postinstall"
+							}
+						default:
+							{
+								iter.Skip()
+							}
+						}
+						return true
+					})
 				}
 			}
 
@@ -248,9 +310,72 @@ func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, ena
 				res.BinKeys = make([]string, 0, 1)
 				res.BinValues = make([]string, 0, 1)
 
+				switch iter.WhatIsNext() {
+				case jsoniter.StringValue:
+					{
+						// "bin": "cli.js" is named after the package, which might not be read yet
+						binPath = iter.ReadString()
+					}
+				case jsoniter.ObjectValue:
+					{
+						iter.ReadMapCB(func(iterator *jsoniter.Iterator, key string) bool {
+							res.BinKeys = append(res.BinKeys, key)
+							res.BinValues = append(res.BinValues, iter.ReadString())
+							return true
+						})
+					}
+				default:
+					{
+						iter.Skip()
+					}
+				}
+			}
+
+		case "directories":
+			{
+				res.BinDirectory = iter.ReadAny().Get("bin").ToString()
+			}
+
+		case "hasInstallScript":
+			{
+				// Packuments leave out scripts, and only say whether there's one that runs on install
+				res.HasPostInstall = res.HasPostInstall || iter.ReadBool()
+			}
+
+		case "dist":
+			{
+				// Registries only add "dist" to the package.json of a published version
+				dist := iter.ReadAny()
+				res.Integrity = dist.Get("integrity").ToString()
+				if len(res.Integrity) == 0 {
+					res.Integrity = tarball.ShasumIntegrity(dist.Get("shasum").ToString())
+				}
+			}
+
+		case "overrides":
+			{
+				if !enableOverrides {
+					iter.Skip()
+					break
+				}
+
+				res.OverrideSelectors, res.OverrideVersions = appendNpmOverrides(res.OverrideSelectors, res.OverrideVersions, iter.ReadAny(), "")
+			}
+
+		case "resolutions":
+			{
+				if !enableOverrides {
+					iter.Skip()
+					break
+				}
+
 				iter.ReadMapCB(func(iterator *jsoniter.Iterator, key string) bool {
-					res.BinKeys = append(res.BinKeys, key)
-					res.BinValues = append(res.BinValues, iter.ReadString())
+					if selector := NewYarnResolutionSelector(key); selector != "" {
+						res.OverrideSelectors = append(res.OverrideSelectors, selector)
+						res.OverrideVersions = append(res.OverrideVersions, iter.ReadString())
+					} else {
+						iter.Skip()
+					}
 					return true
 				})
 			}
@@ -271,6 +396,20 @@ func NewJavascriptPackageManifestPartial(body *[]byte, enableBlacklist bool, ena
 		return res, err
 	}
 
+	if err = res.resolveOverrideReferences(); err != nil {
+		return res, err
+	}
+
+	if len(binPath) > 0 {
+		res.BinKeys = append(res.BinKeys, res.Name[strings.LastIndexByte(res.Name, '/')+1:])
+		res.BinValues = append(res.BinValues, binPath)
+	}
+
+	// Like npm, an optional dependency replaces a regular one with the same name
+	if len(res.OptionalDependencyNames) > 0 {
+		res.DependencyNames, res.DependencyVersions = removeDependencies(res.DependencyNames, res.DependencyVersions, res.OptionalDependencyNames)
+	}
+
 	var specifier string
 	var ok bool
 
@@ -429,7 +568,12 @@ func (v *VersionRange) Trim(input string) string {
 }
 
 func (p *JavascriptPackageManifestPartial) GeneratePackageHash() string {
-	allKeys := make([]string, 0, len(p.DependencyNames)+len(p.DevDependencyNames)+len(p.PeerDependencyNames))
+	return strconv.FormatUint(xxhash.Sum64String(strings.Join(p.HashInputs(), ",")), 16)
+}
+
+// HashInputs are the sorted dependencies & overrides that GeneratePackageHash hashes, as name@range.
+func (p *JavascriptPackageManifestPartial) HashInputs() []string {
+	allKeys := make([]string, 0, len(p.DependencyNames)+len(p.DevDependencyNames)+len(p.PeerDependencyNames)+len(p.OptionalDependencyNames)+len(p.OverrideSelectors))
 
 	for i, dep := range p.DependencyNames {
 		allKeys = append(allKeys, NewPackageManifestKey(dep, p.DependencyVersions[i]))
@@ -443,18 +587,15 @@ func (p *JavascriptPackageManifestPartial) GeneratePackageHash() string {
 		allKeys = append(allKeys, NewPackageManifestKey(dep, p.PeerDependencyVersions[i]))
 	}
 
-	sort.Strings(allKeys)
-
-	return strconv.FormatUint(xxhash.Sum64String(strings.Join(allKeys, ",")), 16)
-}
+	for i, dep := range p.OptionalDependencyNames {
+		allKeys = append(allKeys, NewPackageManifestKey(dep, p.OptionalDependencyVersions[i]))
+	}
 
-func (p PackageProvider) ToArchiveURL(name string, version string) string {
-	switch p {
-	case PackageProviderNpm:
-		{
-			return fmt.Sprintf("https://registry.npmjs.org/%s/-/%s-%s.tgz", name, name, version)
-		}
+	for i, selector := range p.OverrideSelectors {
+		allKeys = append(allKeys, NewPackageManifestKey("overrides:"+selector, p.OverrideVersions[i]))
 	}
 
-	return ""
+	sort.Strings(allKeys)
+
+	return allKeys
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/jarred-sumner/peechy/buffer"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
 	"github.com/valyala/bytebufferpool"
 )
 
@@ -60,3 +61,45 @@ func TestPackageManifestEncoding(t *testing.T) {
 	}
 
 }
+
+func TestPackageManifestBin(t *testing.T) {
+	body := []byte(`{"bin": "./cli.js", "name": "@scope/tool", "version": "1.0.0"}`)
+	manifest, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tool"}, manifest.BinKeys)
+	assert.Equal(t, []string{"./cli.js"}, manifest.BinValues)
+
+	body = []byte(`{"name": "tools", "bin": {"a": "bin/a.js", "b": "bin/b.js"}}`)
+	manifest, err = lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, manifest.BinKeys)
+	assert.Equal(t, []string{"bin/a.js", "bin/b.js"}, manifest.BinValues)
+
+	body = []byte(`{"name": "tools", "directories": {"bin": "./scripts"}}`)
+	manifest, err = lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Empty(t, manifest.BinKeys)
+	assert.Equal(t, "./scripts", manifest.BinDirectory)
+}
+
+func TestPackageManifestIntegrity(t *testing.T) {
+	body := []byte(`{"name": "left-pad", "version": "1.3.0", "dist": {"integrity": "sha512-XI5MPzVNApjAyhQzphX8BkmKsKUxD4LdyK24iZeQEY=", "shasum": "5b8a3a7765dfe001261dde915589e782f8c94d1e"}}`)
+	manifest, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "sha512-XI5MPzVNApjAyhQzphX8BkmKsKUxD4LdyK24iZeQEY=", manifest.Integrity)
+
+	// Packages published before npm had integrity only have a shasum
+	body = []byte(`{"name": "left-pad", "version": "0.0.3", "dist": {"shasum": "5b8a3a7765dfe001261dde915589e782f8c94d1e"}}`)
+	manifest, err = lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "sha1-W4o6d2Xf4AEmHd6RVYnngvjJTR4=", manifest.Integrity)
+}
+
+func TestPackageManifestHasInstallScript(t *testing.T) {
+	// Packuments leave out scripts
+	body := []byte(`{"name": "esbuild", "version": "0.14.0", "hasInstallScript": true}`)
+	manifest, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, false)
+	assert.Nil(t, err)
+	assert.True(t, manifest.HasPostInstall)
+	assert.Empty(t, manifest.ScriptKeys)
+}
@@ -113,7 +113,7 @@ func isHTTPPrefix(version string, length int) bool {
 }
 
 func isHTTPSPrefix(version string, length int) bool {
-	return length > httpsLength && version[0:httpsLength] == "https://"
+	return length > httpsLength && strings.HasPrefix(version, "https://")
 }
 
 func isGitProtocol(version string, length int) bool {
@@ -150,9 +150,18 @@ func isPathLike(version string) bool {
 const SLASH_BYTE = byte('/')
 
 func NewPackageVersionProtocol(version string, length int) PackageVersionProtocol {
-	// If no /, can't be any fancy protocol
-	// If no space, can't be anything complex
-	if strings.IndexByte(version, SLASH_BYTE) == -1 || strings.IndexByte(version, SPACE_BYTE) > -1 {
+	// npm:string-width@^4.2.0
+	if isNpmAliasPrefix(version, length) {
+		return PackageVersionProtocolNpm
+		// workspace:^ or workspace:packages/ui
+	} else if isWorkspacePrefix(version, length) {
+		return PackageVersionProtocolWorkspace
+		// file:../my-lib or link:../my-lib
+	} else if isLocalPathPrefix(version, length) {
+		return PackageVersionProtocolPathlike
+		// If no /, can't be any fancy protocol
+		// If no space, can't be anything complex
+	} else if strings.IndexByte(version, SLASH_BYTE) == -1 || strings.IndexByte(version, SPACE_BYTE) > -1 {
 		return PackageVersionProtocolDefault
 		// github:Jarred-Sumner/git-peek
 	} else if isGitHubBarePrefix(version, length) {
@@ -191,8 +200,8 @@ func NewPackageVersionProtocol(version string, length int) PackageVersionProtoco
 			// https://bitbucket.com/Jarred-Sumner/git-peek
 			return PackageVersionProtocolHttps
 		}
-		// git://git@github.com/Jarred-Sumner/git-peek.git
-	} else if isGitProtocol(version, length) {
+		// git://git@github.com/Jarred-Sumner/git-peek.git or git+https://gitea.example.com/owner/repo.git
+	} else if isGitProtocol(version, length) || isGitPlusPrefix(version, length) {
 		return PackageVersionProtocolGit
 		// git+ssh://git@github.com/Jarred-Sumner/git-peek.git
 	} else if isGitSSHProtocol(version, length) {
@@ -0,0 +1,184 @@
+package lockfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jarred-sumner/devserverless/config"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+// Abbreviated packuments only have what installing needs, so they're much smaller than the full document
+const packumentAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+
+type rawPackument struct {
+	DistTags map[string]string              `json:"dist-tags"`
+	Versions map[string]jsoniter.RawMessage `json:"versions"`
+	// Abbreviated packuments usually leave this out, but some registries send it anyway
+	Time map[string]string `json:"time"`
+}
+
+// fetchPackument is FetchPackageMetadata for config.MetadataSourceRegistry.
+func (store *PackageManifestStore) fetchPackument(name string, parentName string, ctx context.Context) (*PackageMetadata, error) {
+	var result PackageMetadata
+
+	registrar := store.registrarFor(name)
+	url, ok := registrar.MetadataURL(name)
+	if !ok {
+		result.Status = PackageResolutionStatusInternal
+		store.Ranges.Put(name, result)
+		return &result, fmt.Errorf("registrar %s doesn't have packuments", config.RedactURL(registrar.String()))
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer fasthttp.ReleaseResponse(resp)
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(url)
+	// Otherwise the "%2f" in a scoped package's name is decoded, which strict registries 404.
+	// It's set after the URI, since parsing the URI resets it.
+	req.URI().DisablePathNormalizing = true
+	req.Header.Set(fasthttp.HeaderAccept, packumentAccept)
+	req.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	store.authorize(req)
+
+	_logger := store.Logger.With(zap.String("url", config.RedactURL(url)), zap.String("name", name), zap.String("parent", parentName))
+	_logger.Info("GET packument")
+
+	if contextDone(ctx) {
+		return &result, contextError(ctx)
+	}
+
+	err := store.do(req, resp, ctx)
+
+	// Other resolutions may be waiting on this package, so a cancelled request must not be cached.
+	if contextDone(ctx) {
+		_logger.Debug("Cancelled")
+		return &result, contextError(ctx)
+	}
+
+	if err != nil {
+		_logger.Error("HTTP error", zap.Error(err))
+
+		result.Status = PackageResolutionStatusInternal
+		store.Ranges.Put(name, result)
+		return &result, err
+	}
+
+	statusCode := resp.StatusCode()
+	_logger = _logger.With(zap.Int("statusCode", statusCode))
+
+	switch {
+	case statusCode == 200:
+		{
+			var body []byte
+			if string(resp.Header.Peek(fasthttp.HeaderContentEncoding)) == "gzip" {
+				body, err = resp.BodyGunzip()
+			} else {
+				body = resp.Body()
+			}
+
+			if err == nil {
+				result, err = registrar.DecodeMetadata(body)
+			}
+
+			if err != nil {
+				_logger.Error("Corrupt packument", zap.Error(err))
+
+				result = PackageMetadata{Status: PackageResolutionStatusCorruptPackage}
+				store.Ranges.Put(name, result)
+				return &result, err
+			}
+
+			store.Ranges.Put(name, result)
+			_logger.Debug("Success")
+			return &result, nil
+		}
+	case statusCode == 404:
+		{
+			err = errors.New(fmt.Sprintf("package \"%s\" not found", name))
+			result.Status = PackageResolutionStatusNotFound
+		}
+	case statusCode == 429:
+		{
+			err = errors.New("too many requests")
+			result.Status = PackageResolutionStatusRateLimit
+		}
+	case statusCode >= 500:
+		{
+			err = errors.New("internal error while validating package")
+			result.Status = PackageResolutionStatusInternal
+		}
+	default:
+		{
+			err = errors.New(fmt.Sprintf("error: status code %d", statusCode))
+			result.Status = PackageResolutionStatusInternal
+		}
+	}
+
+	_logger.Debug("Fail")
+	store.Ranges.Put(name, result)
+	return &result, err
+}
+
+// newPackumentMetadata reads an abbreviated packument. Each version's package.json is kept as is, and only parsed once it's used.
+func newPackumentMetadata(body []byte) (PackageMetadata, error) {
+	var raw rawPackument
+	if err := jsoniter.ConfigFastest.Unmarshal(body, &raw); err != nil {
+		return PackageMetadata{}, err
+	}
+
+	versions := make([]string, 0, len(raw.Versions))
+	metadata := PackageMetadata{
+		Tags:      raw.DistTags,
+		Status:    PackageResolutionStatusSuccess,
+		Time:      raw.Time,
+		Manifests: make(map[string][]byte, len(raw.Versions)),
+	}
+
+	for version, manifest := range raw.Versions {
+		versions = append(versions, version)
+		metadata.Manifests[version] = manifest
+
+		// It's a string when deprecated, and missing or false otherwise
+		if deprecated := jsoniter.Get(manifest, "deprecated"); deprecated.ValueType() == jsoniter.StringValue {
+			if metadata.Deprecated == nil {
+				metadata.Deprecated = make(map[string]string)
+			}
+			metadata.Deprecated[version] = deprecated.ToString()
+		}
+	}
+
+	metadata.Versions = newVersionList(versions)
+	return metadata, nil
+}
+
+// packumentManifest returns name@version from its packument, fetching the packument when it isn't cached yet.
+// It's false when the packument doesn't have the version, so it's fetched by itself instead.
+func (store *PackageManifestStore) packumentManifest(name string, version string, parentName string, ctx context.Context) (*JavascriptPackageManifestPartial, bool) {
+	metadata, ok := store.Ranges.Get(name)
+	if !ok {
+		var err error
+		if metadata, err = store.fetchPackument(name, parentName, ctx); err != nil {
+			return nil, false
+		}
+	}
+
+	body, ok := metadata.Manifests[version]
+	if !ok {
+		return nil, false
+	}
+
+	manifest, err := store.registrarFor(name).DecodeManifest(body)
+	if err != nil {
+		return nil, false
+	}
+
+	store.Manifests.Put(name, version, &manifest)
+	return &manifest, true
+}
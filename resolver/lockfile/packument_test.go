@@ -0,0 +1,114 @@
+package lockfile_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesPackument(t *testing.T) {
+	packuments := map[string]string{
+		"foo": `{"name": "foo", "dist-tags": {"latest": "1.1.0"}, "versions": {
+			"1.0.0": {"name": "foo", "version": "1.0.0", "dependencies": {"@s/bar": "^1.0.0"}, "dist": {"integrity": "sha512-foo1"}},
+			"1.1.0": {"name": "foo", "version": "1.1.0", "dependencies": {"@s/bar": "^1.0.0"}, "dist": {"integrity": "sha512-foo2"}, "deprecated": "use baz"}
+		}}`,
+		"@s/bar": `{"name": "@s/bar", "dist-tags": {"latest": "1.0.0"}, "versions": {
+			"1.0.0": {"name": "@s/bar", "version": "1.0.0", "hasInstallScript": true, "dist": {"shasum": "5b8a3a7765dfe001261dde915589e782f8c94d1e"}}
+		}}`,
+	}
+
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		lock.Unlock()
+
+		assert.True(t, strings.HasPrefix(r.Header.Get("Accept"), "application/vnd.npm.install-v1+json"))
+
+		body, ok := packuments[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(registry.Close)
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.Registrar, _ = lockfile.NewRegistrar(registry.URL)
+	store.MetadataSource = config.MetadataSourceRegistry
+
+	body := []byte(`{"name": "root", "dependencies": {"foo": "^1.0.0", "@s/bar": "1.0.0"}}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"@s/bar", "foo"}, manifest.Name)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, manifest.Version)
+	assert.Equal(t, []string{"sha1-W4o6d2Xf4AEmHd6RVYnngvjJTR4=", "sha512-foo2"}, manifest.Integrity)
+	assert.Empty(t, manifest.Failures.Name)
+
+	// One request per package, instead of another for each version's package.json
+	assert.Equal(t, map[string]int{"/foo": 1, "/@s/bar": 1}, requests)
+
+	metadata, err := store.FetchPackageMetadata("foo", "", context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, []string{metadata.Versions[0].String(), metadata.Versions[1].String()})
+	assert.Equal(t, map[string]string{"1.1.0": "use baz"}, metadata.Deprecated)
+}
+
+func TestResolveDependenciesScopedRegistry(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"foo": `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+		},
+		map[string]string{
+			"foo/1.0.0": `{"name": "foo", "version": "1.0.0"}`,
+		},
+	)
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(401)
+			return
+		}
+
+		if r.URL.Path != "/@acme/widgets" {
+			w.WriteHeader(404)
+			return
+		}
+
+		w.Write([]byte(`{"name": "@acme/widgets", "dist-tags": {"latest": "1.0.0"}, "versions": {
+			"1.0.0": {"name": "@acme/widgets", "version": "1.0.0", "dependencies": {"foo": "^1.0.0"}}
+		}}`))
+	}))
+	t.Cleanup(private.Close)
+
+	store.Npmrc = &config.Npmrc{
+		Scopes: map[string]string{"@acme": private.URL + "/"},
+		Auth: map[string]config.RegistryAuth{
+			"//" + strings.TrimPrefix(private.URL, "http://") + "/": {Token: "s3cret"},
+		},
+	}
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("@acme/widgets", "^1.0.0", true)
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	// Only @acme comes from its own registry, and jsDelivr isn't asked about it
+	assert.Equal(t, []string{"@acme/widgets", "foo"}, manifest.Name)
+	assert.Equal(t, []string{"1.0.0", "1.0.0"}, manifest.Version)
+	assert.Empty(t, manifest.Failures.Name)
+}
@@ -0,0 +1,233 @@
+package lockfile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jarred-sumner/devserverless/resolver/node_semver"
+	"go.uber.org/zap"
+)
+
+type peerConflict struct {
+	name      string
+	version   string
+	requester string
+	provided  string
+	provider  string
+}
+
+// resolvePeers picks what each new package's peerDependencies resolve to, like npm 7.
+// A peer is whatever the closest package above it depends on, up to the root package. Peers nothing provides are enqueued, unless peerDependenciesMeta marks them optional.
+// It returns true when anything was enqueued, since those packages' peers need another pass.
+func (p *PackageFlatPack) resolvePeers(pkg *JavascriptPackageManifestPartial) bool {
+	p.packageKeysMutex.Lock()
+	keys := make([]string, 0, len(p.packageKeys)-len(p.peersResolved))
+	for key, resolved := range p.packageKeys {
+		// The root & workspace packages' peers resolve like any other dependency
+		if resolved && !p.peersResolved[key] && !p.roots[key] {
+			keys = append(keys, key)
+			p.peersResolved[key] = true
+		}
+	}
+	p.packageKeysMutex.Unlock()
+
+	// So conflicts & enqueues don't depend on map order
+	sort.Strings(keys)
+
+	enqueued := false
+	for _, key := range keys {
+		manifest, ok := p.GetManifest(key)
+		if !ok {
+			continue
+		}
+
+		for i, name := range manifest.PeerDependencyNames {
+			peerRange := manifest.PeerDependencyVersions[i]
+
+			// Packages that also depend on it directly already resolved it
+			if _, ok := findDependencyVersion(name, manifest.DependencyNames, manifest.DependencyVersions); ok {
+				continue
+			}
+
+			providerKey, provider, found := p.peerProvider(pkg, key, name)
+			if !found {
+				if containsString(manifest.OptionalPeerDependencyNames, name) {
+					continue
+				}
+
+				p.Logger.Debug("Installing missing peer", zap.String("name", name), zap.String("version", peerRange), zap.String("parent", key))
+				p.enqueue(name, peerRange, manifest.Name, key)
+				enqueued = true
+				continue
+			}
+
+			p.peerKeys[edgeKey(key, name)] = providerKey
+
+			_, provided := splitPackageManifestKey(providerKey)
+			if !peerSatisfies(peerRange, provided) {
+				p.peerConflicts = append(p.peerConflicts, peerConflict{
+					name:      name,
+					version:   peerRange,
+					requester: key,
+					provided:  providerKey,
+					provider:  provider,
+				})
+			}
+		}
+	}
+
+	return enqueued
+}
+
+// peerProvider finds the package key's peer on name resolves to, and the key of the package that depends on it.
+// It looks at key's parents from the closest up, then at the root package.
+func (p *PackageFlatPack) peerProvider(pkg *JavascriptPackageManifestPartial, key string, name string) (string, string, bool) {
+	p.packageKeysMutex.Lock()
+	chain := p.parentChain(p.parents[key])
+	p.packageKeysMutex.Unlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		parent, ok := p.GetManifest(chain[i])
+		if !ok {
+			continue
+		}
+
+		if providerKey, ok := p.dependencyKey(parent, chain[i], name); ok {
+			return providerKey, chain[i], true
+		}
+	}
+
+	if providerKey, ok := p.dependencyKey(pkg, "", name); ok {
+		return providerKey, pkg.Name, true
+	}
+
+	return "", "", false
+}
+
+// dependencyKey returns the key manifest's dependency on name resolved to, if it did.
+func (p *PackageFlatPack) dependencyKey(manifest *JavascriptPackageManifestPartial, parentKey string, name string) (string, bool) {
+	var found string
+	eachDependencyGroup(manifest, p.groups, p.isRoot(parentKey), func(group DependencyGroup, names []string, versions []string) {
+		if found != "" {
+			return
+		}
+
+		if version, ok := findDependencyVersion(name, names, versions); ok {
+			key := p.resolvedKey(name, version, parentKey)
+			p.packageKeysMutex.Lock()
+			resolved := p.packageKeys[key]
+			p.packageKeysMutex.Unlock()
+
+			if resolved {
+				found = key
+			}
+		}
+	})
+
+	return found, found != ""
+}
+
+// peerSatisfies is whether the version a peer resolved to is in the range it asked for.
+// Versions that aren't from the registry, like git commits, can't be compared, so they always do. Ranges that don't parse never do, so they're reported as conflicts.
+func peerSatisfies(peerRange string, version string) bool {
+	if NewPackageVersionProtocol(peerRange, len(peerRange)) != PackageVersionProtocolDefault || NewPackageVersionProtocol(version, len(version)) != PackageVersionProtocolDefault {
+		return true
+	}
+
+	switch peerRange {
+	case "", "*", "latest", version:
+		{
+			return true
+		}
+	}
+
+	// node_semver skips characters it doesn't expect, so "react@17" would be "17"
+	if strings.IndexFunc(peerRange, isNotRangeRune) > -1 {
+		return false
+	}
+
+	tokenized := node_semver.Tokenize(peerRange)
+	if tokenized.Value == node_semver.TokenizeResultValueNone {
+		return false
+	}
+
+	return tokenized.TestString(version)
+}
+
+// isNotRangeRune is whether r can't be in a semver range. Letters can, for prereleases & "x" wildcards.
+func isNotRangeRune(r rune) bool {
+	return !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !strings.ContainsRune(" .+-*^~<>=|", r)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendPeerIndices is appendDependencyIndices for manifest's peerDependencies, using what resolvePeers picked.
+func (s *PackageFlatPack) appendPeerIndices(indices []uint, keysIndex map[string]uint, manifest *JavascriptPackageManifestPartial, parentKey string) []uint {
+	for i, name := range manifest.PeerDependencyNames {
+		key, ok := s.peerKeys[edgeKey(parentKey, name)]
+		if !ok {
+			key = s.resolvedKey(name, manifest.PeerDependencyVersions[i], parentKey)
+		}
+
+		if index, ok := keysIndex[key]; ok {
+			indices = append(indices, index)
+		}
+	}
+
+	return indices
+}
+
+func (s *PackageFlatPack) buildPeerConflicts(pkg *JavascriptPackageManifestPartial) PeerDependencyConflicts {
+	sort.Slice(s.peerConflicts, func(i, j int) bool {
+		if s.peerConflicts[i].name != s.peerConflicts[j].name {
+			return s.peerConflicts[i].name < s.peerConflicts[j].name
+		}
+
+		return s.peerConflicts[i].requester < s.peerConflicts[j].requester
+	})
+
+	count := len(s.peerConflicts)
+	conflicts := PeerDependencyConflicts{
+		Name:      make([]string, count),
+		Range:     make([]string, count),
+		Requester: make([]string, count),
+		Provided:  make([]string, count),
+		Provider:  make([]string, count),
+	}
+
+	for i, conflict := range s.peerConflicts {
+		conflicts.Name[i] = conflict.name
+		conflicts.Range[i] = conflict.version
+		conflicts.Requester[i] = conflict.requester
+		conflicts.Provided[i] = conflict.provided
+		conflicts.Provider[i] = conflict.provider
+	}
+
+	return conflicts
+}
+
+// Messages describes each conflict on one line, like "react@^16.0.0 is a peer dependency of a@1.0.0, but b@2.0.0 depends on react@17.0.2".
+func (c *PeerDependencyConflicts) Messages() []string {
+	messages := make([]string, len(c.Name))
+	for i := range c.Name {
+		var b strings.Builder
+		b.WriteString(NewPackageManifestKey(c.Name[i], c.Range[i]))
+		b.WriteString(" is a peer dependency of ")
+		b.WriteString(c.Requester[i])
+		b.WriteString(", but ")
+		b.WriteString(c.Provider[i])
+		b.WriteString(" depends on ")
+		b.WriteString(c.Provided[i])
+		messages[i] = b.String()
+	}
+
+	return messages
+}
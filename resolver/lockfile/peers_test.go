@@ -0,0 +1,64 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesPeers(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"react":        `{"tags": {"latest": "18.0.0"}, "versions": ["16.14.0", "17.0.2", "18.0.0"]}`,
+			"react-dom":    `{"tags": {"latest": "17.0.2"}, "versions": ["17.0.2"]}`,
+			"old-widget":   `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"styled":       `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"needs-peer":   `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"left-pad":     `{"tags": {"latest": "1.3.0"}, "versions": ["1.3.0"]}`,
+			"maybe-plugin": `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"typo-widget":  `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+			"beta-widget":  `{"tags": {"latest": "1.0.0"}, "versions": ["1.0.0"]}`,
+		},
+		map[string]string{
+			"react/16.14.0":      `{"name": "react", "version": "16.14.0"}`,
+			"react/17.0.2":       `{"name": "react", "version": "17.0.2"}`,
+			"react/18.0.0":       `{"name": "react", "version": "18.0.0"}`,
+			"react-dom/17.0.2":   `{"name": "react-dom", "version": "17.0.2", "peerDependencies": {"react": "^17.0.0"}}`,
+			"old-widget/1.0.0":   `{"name": "old-widget", "version": "1.0.0", "peerDependencies": {"react": "^16.0.0"}}`,
+			"styled/1.0.0":       `{"name": "styled", "version": "1.0.0", "dependencies": {"needs-peer": "^1.0.0"}, "peerDependencies": {"maybe-plugin": "^1.0.0"}, "peerDependenciesMeta": {"maybe-plugin": {"optional": true}}}`,
+			"needs-peer/1.0.0":   `{"name": "needs-peer", "version": "1.0.0", "peerDependencies": {"left-pad": "^1.0.0"}}`,
+			"left-pad/1.3.0":     `{"name": "left-pad", "version": "1.3.0"}`,
+			"maybe-plugin/1.0.0": `{"name": "maybe-plugin", "version": "1.0.0"}`,
+			"typo-widget/1.0.0":  `{"name": "typo-widget", "version": "1.0.0", "peerDependencies": {"react": "react@17"}}`,
+			"beta-widget/1.0.0":  `{"name": "beta-widget", "version": "1.0.0", "peerDependencies": {"react": "17.0.0 - 17.1.0"}}`,
+		},
+	)
+
+	root := lockfile.JavascriptPackageManifestPartial{
+		Name:               "root",
+		Status:             lockfile.PackageResolutionStatusSuccess,
+		DependencyNames:    []string{"react", "react-dom", "old-widget", "styled", "typo-widget", "beta-widget"},
+		DependencyVersions: []string{"^17.0.0", "^17.0.0", "^1.0.0", "^1.0.0", "^1.0.0", "^1.0.0"},
+	}
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	// left-pad was missing, so it's installed. maybe-plugin is optional, so it isn't.
+	// react's peers resolve to the root's react@17.0.2, not the latest version.
+	assert.Equal(t, []string{"beta-widget", "left-pad", "needs-peer", "old-widget", "react-dom", "react", "styled", "typo-widget"}, manifest.Name)
+	assert.Equal(t, []string{"1.0.0", "1.3.0", "1.0.0", "1.0.0", "17.0.2", "17.0.2", "1.0.0", "1.0.0"}, manifest.Version)
+
+	lists := manifest.DependencyLists()
+	assert.Equal(t, []uint{1}, lists[2])
+	assert.Equal(t, []uint{5}, lists[3])
+	assert.Equal(t, []uint{5}, lists[4])
+	assert.Empty(t, manifest.Failures.Name)
+
+	// A range that doesn't parse is a conflict, instead of being satisfied by anything
+	assert.Equal(t, []string{
+		"react@^16.0.0 is a peer dependency of old-widget@1.0.0, but root depends on react@17.0.2",
+		"react@react@17 is a peer dependency of typo-widget@1.0.0, but root depends on react@17.0.2",
+	}, manifest.PeerConflicts.Messages())
+}
@@ -0,0 +1,182 @@
+package lockfile
+
+import (
+	"path/filepath"
+	"runtime"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Platform is what package.json's "os", "cpu" and "libc" fields are checked against, using node's names like "darwin" and "x64".
+// An empty field matches any package.
+type Platform struct {
+	OS   string
+	CPU  string
+	Libc string
+}
+
+// HostPlatform is the platform duck is running on.
+func HostPlatform() Platform {
+	platform := Platform{
+		OS:  NodeOS(runtime.GOOS),
+		CPU: NodeCPU(runtime.GOARCH),
+	}
+
+	if platform.OS == "linux" {
+		platform.Libc = "glibc"
+		if matches, _ := filepath.Glob("/lib/ld-musl-*"); len(matches) > 0 {
+			platform.Libc = "musl"
+		}
+	}
+
+	return platform
+}
+
+// NewTargetPlatform is the host's platform, with each non-empty argument replacing its part.
+// Picking another OS also picks its libc, since the host's usually doesn't apply.
+func NewTargetPlatform(os string, cpu string, libc string) Platform {
+	platform := HostPlatform()
+	if os != "" && os != platform.OS {
+		platform.OS = os
+		platform.Libc = ""
+		if os == "linux" {
+			platform.Libc = "glibc"
+		}
+	}
+
+	if cpu != "" {
+		platform.CPU = cpu
+	}
+
+	if libc != "" {
+		platform.Libc = libc
+	}
+
+	return platform
+}
+
+// NodeOS converts a GOOS to node's process.platform.
+func NodeOS(goos string) string {
+	switch goos {
+	case "windows":
+		{
+			return "win32"
+		}
+	case "illumos", "solaris":
+		{
+			return "sunos"
+		}
+	}
+
+	return goos
+}
+
+// NodeCPU converts a GOARCH to node's process.arch.
+func NodeCPU(goarch string) string {
+	switch goarch {
+	case "amd64":
+		{
+			return "x64"
+		}
+	case "386":
+		{
+			return "ia32"
+		}
+	case "ppc64le":
+		{
+			return "ppc64"
+		}
+	}
+
+	return goarch
+}
+
+func (p Platform) String() string {
+	if p.Libc == "" {
+		return p.OS + "-" + p.CPU
+	}
+
+	return p.OS + "-" + p.CPU + "-" + p.Libc
+}
+
+// Supports reports whether manifest can be installed on p.
+func (p Platform) Supports(manifest *JavascriptPackageManifestPartial) bool {
+	return platformListAllows(manifest.Os, p.OS) && platformListAllows(manifest.Cpu, p.CPU) && platformListAllows(manifest.Libc, p.Libc)
+}
+
+// platformListAllows checks value against a list like ["darwin", "linux"] or ["!win32"], the same way npm does.
+func platformListAllows(list []string, value string) bool {
+	if len(list) == 0 || value == "" {
+		return true
+	}
+
+	hasAllowed := false
+	allowed := false
+	for _, entry := range list {
+		if len(entry) > 0 && entry[0] == '!' {
+			if entry[1:] == value {
+				return false
+			}
+			continue
+		}
+
+		hasAllowed = true
+		if entry == value || entry == "any" {
+			allowed = true
+		}
+	}
+
+	return allowed || !hasAllowed
+}
+
+// stringListFromAny reads a field that's either a string or an array of strings.
+func stringListFromAny(obj jsoniter.Any) []string {
+	switch obj.ValueType() {
+	case jsoniter.StringValue:
+		{
+			return []string{obj.ToString()}
+		}
+	case jsoniter.ArrayValue:
+		{
+			list := make([]string, 0, obj.Size())
+			for i := 0; i < obj.Size(); i++ {
+				if value := obj.Get(i); value.ValueType() == jsoniter.StringValue {
+					list = append(list, value.ToString())
+				}
+			}
+			return list
+		}
+	}
+
+	return nil
+}
+
+func removeDependencies(names []string, versions []string, remove []string) ([]string, []string) {
+	removed := make(map[string]bool, len(remove))
+	for _, name := range remove {
+		removed[name] = true
+	}
+
+	keptNames := names[:0]
+	keptVersions := versions[:0]
+	for i, name := range names {
+		if !removed[name] {
+			keptNames = append(keptNames, name)
+			keptVersions = append(keptVersions, versions[i])
+		}
+	}
+
+	return keptNames, keptVersions
+}
+
+// markOptional records that parentKey's dependency on name is optional, so failing to resolve it isn't an error.
+func (p *PackageFlatPack) markOptional(parentKey string, name string) {
+	p.packageKeysMutex.Lock()
+	defer p.packageKeysMutex.Unlock()
+	p.optional[edgeKey(parentKey, name)] = true
+}
+
+// edgeKey identifies parentKey's dependency on name.
+func edgeKey(parentKey string, name string) string {
+	return parentKey + "\x00" + name
+}
@@ -0,0 +1,73 @@
+package lockfile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesOptional(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"esbuild":              `{"tags": {"latest": "0.14.0"}, "versions": ["0.14.0"]}`,
+			"esbuild-linux-64":     `{"tags": {"latest": "0.14.0"}, "versions": ["0.14.0"]}`,
+			"esbuild-darwin-arm64": `{"tags": {"latest": "0.14.0"}, "versions": ["0.14.0"]}`,
+			"fsevents":             `{"tags": {"latest": "2.3.2"}, "versions": ["2.3.2"]}`,
+		},
+		map[string]string{
+			"esbuild/0.14.0":              `{"name": "esbuild", "version": "0.14.0", "optionalDependencies": {"esbuild-linux-64": "0.14.0", "esbuild-darwin-arm64": "0.14.0", "esbuild-gone": "0.14.0"}}`,
+			"esbuild-linux-64/0.14.0":     `{"name": "esbuild-linux-64", "version": "0.14.0", "os": ["linux"], "cpu": ["x64"]}`,
+			"esbuild-darwin-arm64/0.14.0": `{"name": "esbuild-darwin-arm64", "version": "0.14.0", "os": ["darwin"], "cpu": ["arm64"]}`,
+			"fsevents/2.3.2":              `{"name": "fsevents", "version": "2.3.2", "os": ["darwin"]}`,
+		},
+	)
+
+	body := []byte(`{"name": "root", "dependencies": {"esbuild": "^0.14.0", "fsevents": "^2.3.2"}, "optionalDependencies": {"fsevents": "^2.3.2"}}`)
+	root, err := lockfile.NewJavascriptPackageManifestPartial(&body, false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"esbuild"}, root.DependencyNames)
+	assert.Equal(t, []string{"fsevents"}, root.OptionalDependencyNames)
+
+	manifest, err := store.ResolveDependenciesWithOptions(&root, lockfile.ResolveOptions{}, context.Background())
+	assert.Nil(t, err)
+	// Packages for every platform are kept, so the lockfile is the same on each one. The installer skips the ones that don't match.
+	assert.Equal(t, []string{"esbuild-darwin-arm64", "esbuild-linux-64", "esbuild", "fsevents"}, manifest.Name)
+	assert.ElementsMatch(t, []uint{0, 1}, manifest.DependencyLists()[2])
+	assert.Equal(t, []uint{2, 3}, manifest.RootDependencies)
+	assert.Equal(t, []string{"darwin", "linux", "", "darwin"}, manifest.Os)
+	assert.Equal(t, []string{"arm64", "x64", "", ""}, manifest.Cpu)
+	// Optional dependencies that failed aren't failures
+	assert.Empty(t, manifest.Failures.Name)
+
+	packages := manifest.Packages()
+	linux := lockfile.Platform{OS: "linux", CPU: "x64", Libc: "glibc"}
+	assert.False(t, linux.Supports(&packages[0]))
+	assert.True(t, linux.Supports(&packages[1]))
+	assert.True(t, linux.Supports(&packages[2]))
+	assert.False(t, linux.Supports(&packages[3]))
+}
+
+func TestPlatformSupports(t *testing.T) {
+	linux := lockfile.Platform{OS: "linux", CPU: "arm64", Libc: "musl"}
+
+	assert.True(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{}))
+	assert.True(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{Os: []string{"linux", "darwin"}}))
+	assert.True(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{Os: []string{"!win32"}}))
+	assert.False(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{Os: []string{"!linux"}}))
+	assert.False(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{Cpu: []string{"x64"}}))
+	assert.False(t, linux.Supports(&lockfile.JavascriptPackageManifestPartial{Libc: []string{"glibc"}}))
+
+	// libc only matters on linux
+	darwin := lockfile.Platform{OS: "darwin", CPU: "arm64"}
+	assert.True(t, darwin.Supports(&lockfile.JavascriptPackageManifestPartial{Libc: []string{"glibc"}}))
+
+	assert.True(t, lockfile.Platform{}.Supports(&lockfile.JavascriptPackageManifestPartial{Os: []string{"aix"}}))
+
+	target := lockfile.NewTargetPlatform("linux", "x64", "")
+	assert.Equal(t, "linux", target.OS)
+	assert.Equal(t, "x64", target.CPU)
+	assert.NotEmpty(t, target.Libc)
+	assert.Equal(t, "win32-ia32", lockfile.NewTargetPlatform("win32", "ia32", "").String())
+}
@@ -0,0 +1,264 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jarred-sumner/devserverless/config"
+)
+
+// Registrar is where packages come from: each version's package.json, the list of versions, and the tarball that's installed.
+type Registrar interface {
+	// String is the setting for this registrar, which lockfiles save
+	String() string
+	// ManifestURL is name@version's package.json
+	ManifestURL(name string, version string) string
+	// MetadataURL is name's packument, which has every version's package.json. It's false when the registrar doesn't have packuments.
+	MetadataURL(name string) (string, bool)
+	// TarballURL is where name@version is downloaded from to install it
+	TarballURL(name string, version string) string
+	// Public is whether jsDelivr mirrors the registrar's packages, so their versions can come from jsDelivr instead of MetadataURL
+	Public() bool
+	DecodeManifest(body []byte) (JavascriptPackageManifestPartial, error)
+	DecodeMetadata(body []byte) (PackageMetadata, error)
+}
+
+const npmRegistryURL = "https://registry.npmjs.org/"
+
+// DefaultRegistrar is npm's registry.
+var DefaultRegistrar Registrar = &npmRegistrar{url: npmRegistryURL}
+
+var errInvalidRegistrar = errors.New("Expected registrar to be \"npm\", \"jspm\", \"skypack\", a registry URL, or a local directory")
+
+// NewRegistrar reads the --registrar setting.
+// Besides "npm", "jspm" & "skypack", it can be a registry's URL, or a local directory laid out like a registry (see localRegistrar).
+// URLs with two %s are templates for package.json files, where the first %s is the name and the second is the version. Lockfiles from before registrars saved those.
+func NewRegistrar(setting string) (Registrar, error) {
+	switch {
+	case setting == "npm" || setting == "":
+		{
+			return DefaultRegistrar, nil
+		}
+	case setting == "jspm" || setting == string(config.JSRegistrarFormatterStringJSPM):
+		{
+			return &cdnRegistrar{setting: "jspm", template: string(config.JSRegistrarFormatterStringJSPM)}, nil
+		}
+	case setting == "skypack" || setting == string(config.JSRegistrarFormatterStringSkypack):
+		{
+			return &cdnRegistrar{setting: "skypack", template: string(config.JSRegistrarFormatterStringSkypack)}, nil
+		}
+	case strings.HasPrefix(setting, "https://") || strings.HasPrefix(setting, "http://"):
+		{
+			switch strings.Count(setting, "%s") {
+			case 0:
+				{
+					return &npmRegistrar{url: withTrailingSlash(setting)}, nil
+				}
+			case 2:
+				{
+					// Registries used to be "<registry>/%s/%s", since that's their package.json URL
+					if strings.HasSuffix(setting, "/%s/%s") {
+						return &npmRegistrar{url: strings.TrimSuffix(setting, "%s/%s")}, nil
+					}
+
+					return &cdnRegistrar{setting: setting, template: setting}, nil
+				}
+			}
+		}
+	case strings.HasPrefix(setting, "file://"):
+		{
+			return newLocalRegistrar(strings.TrimPrefix(setting, "file://"))
+		}
+	case filepath.IsAbs(setting) || strings.HasPrefix(setting, "."):
+		{
+			return newLocalRegistrar(setting)
+		}
+	}
+
+	return nil, errInvalidRegistrar
+}
+
+// SameRegistrar is whether saved, the registrar a lockfile was resolved from, is registrar.
+// Settings from before registrars are compared by what they point to.
+func SameRegistrar(saved string, registrar Registrar) bool {
+	if savedRegistrar, err := NewRegistrar(saved); err == nil {
+		return savedRegistrar.String() == registrar.String()
+	}
+
+	return saved == registrar.String()
+}
+
+// ScopeRegistrar is the registrar name comes from: its scope's registry in npmrc, or registrar. Both can be nil.
+func ScopeRegistrar(registrar Registrar, npmrc *config.Npmrc, name string) Registrar {
+	if registry, ok := npmrc.ScopeRegistry(name); ok {
+		// jsDelivr doesn't know about packages on a scope's own registry, since they're usually private
+		return &npmRegistrar{url: registry, private: true}
+	} else if registrar == nil {
+		return DefaultRegistrar
+	}
+
+	return registrar
+}
+
+// ValidateMetadataSource checks that registrar has packuments, when they're where versions come from.
+func ValidateMetadataSource(registrar Registrar, source config.MetadataSource) error {
+	if _, ok := registrar.MetadataURL("react"); !ok && source == config.MetadataSourceRegistry {
+		return fmt.Errorf("Expected registrar to have packuments when metadata is \"registry\", but %s doesn't", registrar)
+	}
+
+	return nil
+}
+
+func withTrailingSlash(url string) string {
+	if strings.HasSuffix(url, "/") {
+		return url
+	}
+
+	return url + "/"
+}
+
+// A scoped package's tarball is named without its scope, like "@babel/core/-/core-7.0.0.tgz"
+func tarballPath(name string, version string) string {
+	return fmt.Sprintf("%s/-/%s-%s.tgz", name, name[strings.LastIndexByte(name, '/')+1:], version)
+}
+
+func decodeManifest(body []byte) (JavascriptPackageManifestPartial, error) {
+	return NewJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, false)
+}
+
+// npmRegistrar is a registry with npm's API, like registry.npmjs.org or a company's own.
+type npmRegistrar struct {
+	// url ends with a slash
+	url string
+	// private registries are from .npmrc scopes, which jsDelivr doesn't mirror
+	private bool
+}
+
+func (r *npmRegistrar) String() string {
+	return r.url
+}
+
+func (r *npmRegistrar) ManifestURL(name string, version string) string {
+	return r.url + name + "/" + version
+}
+
+func (r *npmRegistrar) MetadataURL(name string) (string, bool) {
+	// A scoped package's name is one path segment
+	return r.url + strings.Replace(name, "/", "%2f", 1), true
+}
+
+func (r *npmRegistrar) TarballURL(name string, version string) string {
+	return r.url + tarballPath(name, version)
+}
+
+func (r *npmRegistrar) Public() bool {
+	return !r.private
+}
+
+func (r *npmRegistrar) DecodeManifest(body []byte) (JavascriptPackageManifestPartial, error) {
+	return decodeManifest(body)
+}
+
+func (r *npmRegistrar) DecodeMetadata(body []byte) (PackageMetadata, error) {
+	return newPackumentMetadata(body)
+}
+
+// cdnRegistrar is a CDN with the package.json of every package on npm, like jspm or skypack.
+// They don't have packuments or tarballs, so packages are installed from npm.
+type cdnRegistrar struct {
+	setting string
+	// template's first %s is the name, and the second is the version
+	template string
+}
+
+func (r *cdnRegistrar) String() string {
+	return r.setting
+}
+
+func (r *cdnRegistrar) ManifestURL(name string, version string) string {
+	return fmt.Sprintf(r.template, name, version)
+}
+
+func (r *cdnRegistrar) MetadataURL(name string) (string, bool) {
+	return "", false
+}
+
+func (r *cdnRegistrar) TarballURL(name string, version string) string {
+	return DefaultRegistrar.TarballURL(name, version)
+}
+
+func (r *cdnRegistrar) Public() bool {
+	return true
+}
+
+func (r *cdnRegistrar) DecodeManifest(body []byte) (JavascriptPackageManifestPartial, error) {
+	return decodeManifest(body)
+}
+
+func (r *cdnRegistrar) DecodeMetadata(body []byte) (PackageMetadata, error) {
+	return PackageMetadata{}, fmt.Errorf("%s doesn't have packuments", r.setting)
+}
+
+// localRegistrar is a directory laid out like a registry, for installing without a network:
+//
+//	<dir>/<name>/index.json                    the packument
+//	<dir>/<name>/<version>.json                each version's package.json
+//	<dir>/<name>/-/<basename>-<version>.tgz    each version's tarball, like "npm pack" makes
+//
+// Scoped packages are in a folder for their scope, like "<dir>/@acme/widgets/index.json".
+type localRegistrar struct {
+	// dir is absolute
+	dir string
+}
+
+func newLocalRegistrar(dir string) (*localRegistrar, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localRegistrar{dir: dir}, nil
+}
+
+func (r *localRegistrar) fileURL(path string) string {
+	return "file://" + filepath.ToSlash(filepath.Join(r.dir, path))
+}
+
+func (r *localRegistrar) String() string {
+	return "file://" + filepath.ToSlash(r.dir) + "/"
+}
+
+func (r *localRegistrar) ManifestURL(name string, version string) string {
+	return r.fileURL(name + "/" + version + ".json")
+}
+
+func (r *localRegistrar) MetadataURL(name string) (string, bool) {
+	return r.fileURL(name + "/index.json"), true
+}
+
+func (r *localRegistrar) TarballURL(name string, version string) string {
+	return r.fileURL(tarballPath(name, version))
+}
+
+func (r *localRegistrar) Public() bool {
+	return false
+}
+
+func (r *localRegistrar) DecodeManifest(body []byte) (JavascriptPackageManifestPartial, error) {
+	return decodeManifest(body)
+}
+
+func (r *localRegistrar) DecodeMetadata(body []byte) (PackageMetadata, error) {
+	return newPackumentMetadata(body)
+}
+
+// FilePath is the path of a file:// URL, like a local directory registrar's.
+func FilePath(url string) (string, bool) {
+	if !strings.HasPrefix(url, "file://") {
+		return "", false
+	}
+
+	return filepath.FromSlash(strings.TrimPrefix(url, "file://")), true
+}
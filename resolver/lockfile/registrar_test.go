@@ -0,0 +1,107 @@
+package lockfile_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistrar(t *testing.T) {
+	npm, err := lockfile.NewRegistrar("npm")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://registry.npmjs.org/", npm.String())
+	assert.Equal(t, "https://registry.npmjs.org/@babel/core/7.0.0", npm.ManifestURL("@babel/core", "7.0.0"))
+	assert.Equal(t, "https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz", npm.TarballURL("@babel/core", "7.0.0"))
+	url, ok := npm.MetadataURL("@babel/core")
+	assert.True(t, ok)
+	assert.Equal(t, "https://registry.npmjs.org/@babel%2fcore", url)
+	assert.True(t, npm.Public())
+
+	// Lockfiles from before registrars saved a package.json template
+	assert.True(t, lockfile.SameRegistrar(string(config.JSRegistrarFormatterStringNPM), npm))
+
+	registry, err := lockfile.NewRegistrar("https://npm.acme.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://npm.acme.com/", registry.String())
+	assert.Equal(t, "https://npm.acme.com/react/-/react-17.0.2.tgz", registry.TarballURL("react", "17.0.2"))
+	assert.True(t, lockfile.SameRegistrar("https://npm.acme.com/%s/%s", registry))
+	assert.False(t, lockfile.SameRegistrar("npm", registry))
+
+	jspm, err := lockfile.NewRegistrar("jspm")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://ga.jspm.io/npm:@babel/core@7.0.0/package.json", jspm.ManifestURL("@babel/core", "7.0.0"))
+	assert.Equal(t, "https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz", jspm.TarballURL("@babel/core", "7.0.0"))
+	_, ok = jspm.MetadataURL("@babel/core")
+	assert.False(t, ok)
+	assert.True(t, lockfile.SameRegistrar(string(config.JSRegistrarFormatterStringJSPM), jspm))
+	assert.NotNil(t, lockfile.ValidateMetadataSource(jspm, config.MetadataSourceRegistry))
+	assert.Nil(t, lockfile.ValidateMetadataSource(jspm, config.MetadataSourceJSDelivr))
+
+	skypack, err := lockfile.NewRegistrar("skypack")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://cdn.skypack.dev/react@17.0.2/package.json", skypack.ManifestURL("react", "17.0.2"))
+
+	template, err := lockfile.NewRegistrar("https://cdn.example.com/%s@%s/package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://cdn.example.com/react@17.0.2/package.json", template.ManifestURL("react", "17.0.2"))
+
+	local, err := lockfile.NewRegistrar("/srv/registry")
+	assert.Nil(t, err)
+	assert.Equal(t, "file:///srv/registry/", local.String())
+	assert.Equal(t, "file:///srv/registry/@acme/widgets/-/widgets-1.0.0.tgz", local.TarballURL("@acme/widgets", "1.0.0"))
+	assert.False(t, local.Public())
+	assert.True(t, lockfile.SameRegistrar("file:///srv/registry/", local))
+
+	_, err = lockfile.NewRegistrar("registry.npmjs.org")
+	assert.NotNil(t, err)
+}
+
+func TestScopeRegistrar(t *testing.T) {
+	npmrc := &config.Npmrc{Scopes: map[string]string{"@acme": "https://npm.acme.com/"}}
+
+	acme := lockfile.ScopeRegistrar(lockfile.DefaultRegistrar, npmrc, "@acme/widgets")
+	assert.Equal(t, "https://npm.acme.com/@acme/widgets/-/widgets-1.0.0.tgz", acme.TarballURL("@acme/widgets", "1.0.0"))
+	assert.False(t, acme.Public())
+
+	assert.Equal(t, lockfile.DefaultRegistrar, lockfile.ScopeRegistrar(nil, npmrc, "react"))
+	assert.Equal(t, lockfile.DefaultRegistrar, lockfile.ScopeRegistrar(nil, nil, "@acme/widgets"))
+}
+
+func TestResolveDependenciesLocalRegistrar(t *testing.T) {
+	dir := t.TempDir()
+	packuments := map[string]string{
+		"@acme/widgets": `{"name": "@acme/widgets", "dist-tags": {"latest": "1.0.0"}, "versions": {
+			"1.0.0": {"name": "@acme/widgets", "version": "1.0.0", "dependencies": {"foo": "^1.0.0"}}
+		}}`,
+		"foo": `{"name": "foo", "dist-tags": {"latest": "1.1.0"}, "versions": {
+			"1.0.0": {"name": "foo", "version": "1.0.0"},
+			"1.1.0": {"name": "foo", "version": "1.1.0"}
+		}}`,
+	}
+	for name, packument := range packuments {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name, "index.json"), []byte(packument), 0644))
+	}
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.Registrar, _ = lockfile.NewRegistrar(dir)
+
+	root := lockfile.NewJavascriptPackageManifestPartialFromNameVersion("@acme/widgets", "^1.0.0", true)
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"@acme/widgets", "foo"}, manifest.Name)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, manifest.Version)
+	assert.Empty(t, manifest.Failures.Name)
+
+	root = lockfile.NewJavascriptPackageManifestPartialFromNameVersion("missing", "^1.0.0", true)
+	manifest, err = store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []lockfile.PackageResolutionStatus{lockfile.PackageResolutionStatusNotFound}, manifest.Failures.Status)
+}
@@ -0,0 +1,99 @@
+package lockfile
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/jarred-sumner/devserverless/config"
+	"github.com/jarred-sumner/devserverless/resolver/internal/tarball"
+	"go.uber.org/zap"
+)
+
+var errTarballCacheDirMissing = errors.New("tarball dependencies need a cache folder")
+
+// enqueueTarballPackage downloads a .tgz dependency into the store's TarballCacheDir and reads package.json out of it.
+func (p *PackageFlatPack) enqueueTarballPackage(name string, version string, parentName string, parentKey string, protocol PackageVersionProtocol) {
+	s := p.store
+	if s.TarballCacheDir == "" {
+		s.Logger.Warn("Skipping tarball dependency", zap.String("name", name), zap.String("version", config.RedactURL(version)), zap.String("parent", parentName), zap.Error(errTarballCacheDirMissing))
+		p.recordFailure(name, version, PackageResolutionStatusNotFound, parentKey)
+		atomic.AddUint64(&p.ErrorPackageCount, 1)
+		return
+	}
+
+	key := NewPackageManifestKey(name, version)
+	p.setParent(key, parentKey)
+
+	if !p.claim(key) {
+		manifest, exists := p.GetManifest(key)
+		if exists && s.Installer != nil && manifest.Status == PackageResolutionStatusSuccess {
+			s.Installer.Enqueue(manifest)
+		}
+		return
+	}
+
+	w := p.Waiter
+	w.Add(1)
+	s.PackageJSONWorkers.Submit(func() {
+		defer w.Done()
+		if contextDone(p.ctx) {
+			return
+		}
+
+		manifest, status, err := s.FetchTarballPackage(name, version, protocol, p.ctx)
+		if err != nil {
+			if contextDone(p.ctx) {
+				return
+			}
+
+			s.Logger.Warn("Failed to fetch tarball dependency", zap.String("name", name), zap.String("version", config.RedactURL(version)), zap.String("parent", parentName), zap.Error(err))
+			p.recordFailure(name, version, status, parentKey)
+			atomic.AddUint64(&p.ErrorPackageCount, 1)
+			return
+		}
+
+		p.appendCachedManifest(key, manifest, version, parentKey)
+	})
+}
+
+// FetchTarballPackage reads package.json from the tarball at the URL version. The tarball is cached, so installing it doesn't download it again.
+func (s *PackageManifestStore) FetchTarballPackage(name string, version string, protocol PackageVersionProtocol, ctx context.Context) (*JavascriptPackageManifestPartial, PackageResolutionStatus, error) {
+	cache := tarball.Cache{Dir: s.TarballCacheDir, HTTP: s.HTTP, Authorization: s.Npmrc.Authorization(version, "")}
+	tarballPath, integrity, err := cache.Fetch(ctx, version)
+	if err != nil {
+		var httpError *tarball.HTTPError
+		if errors.As(err, &httpError) && httpError.StatusCode == 404 {
+			return nil, PackageResolutionStatusNotFound, err
+		} else if errors.As(err, &httpError) && httpError.StatusCode == 429 {
+			return nil, PackageResolutionStatusRateLimit, err
+		}
+
+		return nil, PackageResolutionStatusInternal, err
+	}
+
+	body, err := tarball.ReadPackageJSON(tarballPath)
+	if err != nil {
+		return nil, PackageResolutionStatusCorruptPackage, err
+	}
+
+	manifest, err := NewJavascriptPackageManifestPartial(&body, config.BLACKLIST_PACKAGES, false)
+	if err != nil {
+		return nil, PackageResolutionStatusCorruptPackage, err
+	}
+
+	s.Logger.Debug("Fetched tarball", zap.String("name", name), zap.String("url", config.RedactURL(version)), zap.String("integrity", integrity))
+
+	manifest.Name = name
+	manifest.Provider = PackageProviderTgz
+	manifest.Status = PackageResolutionStatusSuccess
+	manifest.Integrity = integrity
+	// Not SetVersion, since it lowercases the URL
+	manifest.Version = Version{
+		Protocol:    protocol,
+		OriginalTag: version,
+		Tag:         version,
+	}
+
+	return &manifest, PackageResolutionStatusSuccess, nil
+}
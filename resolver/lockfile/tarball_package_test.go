@@ -0,0 +1,56 @@
+package lockfile_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/cache"
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDependenciesTarball(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	writer := tar.NewWriter(gz)
+	packageJSON := `{"name": "pkg", "version": "1.2.3", "main": "index.js"}`
+	writer.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0644, Size: int64(len(packageJSON)), Typeflag: tar.TypeReg})
+	writer.Write([]byte(packageJSON))
+	writer.Close()
+	gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pkg-1.2.3.tgz" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	store := cache.NewMemoryPackageManifestStore()
+	store.TarballCacheDir = t.TempDir()
+
+	root := lockfile.JavascriptPackageManifestPartial{
+		Name:               "root",
+		Status:             lockfile.PackageResolutionStatusSuccess,
+		DependencyNames:    []string{"dep", "gone"},
+		DependencyVersions: []string{server.URL + "/pkg-1.2.3.tgz", server.URL + "/gone-1.0.0.tgz"},
+	}
+	manifest, err := store.ResolveDependencies(&root, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"dep"}, manifest.Name)
+	assert.Equal(t, []string{server.URL + "/pkg-1.2.3.tgz"}, manifest.Version)
+	assert.Equal(t, []uint{0}, manifest.RootDependencies)
+	assert.Equal(t, []string{"gone"}, manifest.Failures.Name)
+	assert.Equal(t, []lockfile.PackageResolutionStatus{lockfile.PackageResolutionStatusNotFound}, manifest.Failures.Status)
+
+	version := "https://example.com/pkg-1.2.3.tgz"
+	assert.Equal(t, lockfile.PackageVersionProtocolHttpsTarball, lockfile.NewPackageVersionProtocol(version, len(version)))
+}
@@ -0,0 +1,304 @@
+package lockfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash"
+	"github.com/jarred-sumner/devserverless/resolver/node_semver"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const workspacePrefix = "workspace:"
+const workspacePrefixLength = len(workspacePrefix)
+
+func isWorkspacePrefix(version string, length int) bool {
+	return length > workspacePrefixLength && version[0:workspacePrefixLength] == workspacePrefix
+}
+
+// Workspace is a package in a monorepo. It resolves from disk instead of the registry.
+type Workspace struct {
+	// Path is the folder relative to the root package.json, with forward slashes
+	Path     string
+	Manifest JavascriptPackageManifestPartial
+}
+
+// ResolveOptions are the extra inputs ResolveDependenciesWithOptions accepts.
+type ResolveOptions struct {
+	// RootDir is the folder of the root package.json. Path dependencies like "file:../my-lib" only resolve when it's set.
+	RootDir string
+	// Workspaces resolve into the same graph as the root package.
+	Workspaces []Workspace
+	// Groups picks whether the root & workspace packages' devDependencies and peerDependencies resolve. Empty is DefaultDependencyGroups.
+	Groups DependencyGroup
+	// Locked is the previous lockfile. A dependency it resolved stays at the same version while that's still in range,
+	// so only what was added or changed resolves again.
+	Locked *JavascriptPackageManifest
+}
+
+// NewWorkspaceVersion is the version a workspace package is stored under, like "workspace:packages/ui".
+func NewWorkspaceVersion(path string) string {
+	return workspacePrefix + path
+}
+
+// WorkspacePath returns the folder of a version made by NewWorkspaceVersion.
+func WorkspacePath(version string) (string, bool) {
+	if !isWorkspacePrefix(version, len(version)) {
+		return "", false
+	}
+
+	return version[workspacePrefixLength:], true
+}
+
+// ReadWorkspacePatterns returns the globs in package.json's "workspaces" field.
+// Both the array form and yarn's {"packages": [...]} form are supported.
+func ReadWorkspacePatterns(body *[]byte) []string {
+	workspaces := jsoniter.ConfigFastest.Get(*body, "workspaces")
+	if workspaces.ValueType() == jsoniter.ObjectValue {
+		workspaces = workspaces.Get("packages")
+	}
+
+	if workspaces.ValueType() != jsoniter.ArrayValue {
+		return nil
+	}
+
+	patterns := make([]string, 0, workspaces.Size())
+	for i := 0; i < workspaces.Size(); i++ {
+		pattern := strings.TrimSpace(workspaces.Get(i).ToString())
+		if len(pattern) > 0 {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// LoadWorkspaces reads the package.json of every folder in rootDir that matches patterns.
+// Patterns starting with "!" exclude folders, and folders without a package.json are skipped.
+func LoadWorkspaces(rootDir string, patterns []string, enableBlacklist bool) ([]Workspace, error) {
+	include := make([]string, 0, len(patterns))
+	exclude := make([]string, 0)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(path.Clean(strings.TrimPrefix(pattern, "./")), "/")
+		if strings.HasPrefix(pattern, "!") {
+			exclude = append(exclude, strings.TrimPrefix(pattern[1:], "./"))
+		} else {
+			include = append(include, pattern)
+		}
+	}
+
+	dirs := make(map[string]bool, len(include))
+	for _, pattern := range include {
+		matches, err := globWorkspace(rootDir, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern %s: %w", pattern, err)
+		}
+
+		for _, dir := range matches {
+			dirs[dir] = true
+		}
+	}
+
+	// Excludes are globbed like includes, so "!packages/legacy/**" works too
+	for _, pattern := range exclude {
+		matches, err := globWorkspace(rootDir, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern !%s: %w", pattern, err)
+		}
+
+		for _, dir := range matches {
+			delete(dirs, dir)
+		}
+	}
+
+	paths := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+
+	workspaces := make([]Workspace, 0, len(paths))
+	names := make(map[string]string, len(paths))
+	for _, dir := range paths {
+		packageJSONPath := filepath.Join(rootDir, filepath.FromSlash(dir), "package.json")
+		body, err := ioutil.ReadFile(packageJSONPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		manifest, err := NewJavascriptPackageManifestPartial(&body, enableBlacklist, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", packageJSONPath, err)
+		}
+
+		if manifest.Name == "" {
+			return nil, fmt.Errorf("%s is missing a name", packageJSONPath)
+		}
+
+		if other, exists := names[manifest.Name]; exists {
+			return nil, fmt.Errorf("%s is in two workspaces: %s and %s", manifest.Name, other, dir)
+		}
+		names[manifest.Name] = dir
+
+		workspaces = append(workspaces, Workspace{Path: dir, Manifest: manifest})
+	}
+
+	return workspaces, nil
+}
+
+// globWorkspace matches pattern against folders in rootDir. A trailing "/**" matches every folder below it, skipping node_modules.
+func globWorkspace(rootDir string, pattern string) ([]string, error) {
+	if strings.HasSuffix(pattern, "/**") {
+		parents, err := globWorkspace(rootDir, strings.TrimSuffix(pattern, "/**"))
+		if err != nil {
+			return nil, err
+		}
+
+		dirs := make([]string, 0, len(parents))
+		for _, parent := range parents {
+			filepath.Walk(filepath.Join(rootDir, filepath.FromSlash(parent)), func(file string, info os.FileInfo, err error) error {
+				if err != nil || !info.IsDir() {
+					return nil
+				}
+
+				if info.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+
+				if rel, err := filepath.Rel(rootDir, file); err == nil && rel != filepath.FromSlash(parent) {
+					dirs = append(dirs, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+		}
+
+		return dirs, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(rootDir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if rel, err := filepath.Rel(rootDir, match); err == nil {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+	}
+
+	return dirs, nil
+}
+
+// GenerateWorkspaceHash is GeneratePackageHash, extended to the dependencies & versions of each workspace.
+func GenerateWorkspaceHash(root *JavascriptPackageManifestPartial, workspaces []Workspace) string {
+	if len(workspaces) == 0 {
+		return root.GeneratePackageHash()
+	}
+
+	parts := make([]string, 0, len(workspaces)+1)
+	parts = append(parts, root.GeneratePackageHash())
+	for _, workspace := range workspaces {
+		parts = append(parts, workspace.Path+":"+NewPackageManifestKey(workspace.Manifest.Name, workspace.Manifest.Version.Tag)+":"+workspace.Manifest.GeneratePackageHash())
+	}
+
+	return strconv.FormatUint(xxhash.Sum64String(strings.Join(parts, ",")), 16)
+}
+
+// workspaceSatisfies is whether a dependency on version should link to a workspace package at workspaceVersion.
+// "workspace:" ranges always do. Registry ranges only do when the workspace's version is in range, like npm & yarn.
+func workspaceSatisfies(version string, workspaceVersion string) bool {
+	if isWorkspacePrefix(version, len(version)) {
+		return true
+	}
+
+	if NewPackageVersionProtocol(version, len(version)) != PackageVersionProtocolDefault {
+		return false
+	}
+
+	switch version {
+	case "", "*", "latest":
+		{
+			return true
+		}
+	}
+
+	tokenized := node_semver.Tokenize(version)
+	return tokenized.Value != node_semver.TokenizeResultValueNone && tokenized.TestString(workspaceVersion)
+}
+
+// addWorkspaces adds every workspace package to the pack before anything is fetched, so dependencies on them resolve locally.
+func (p *PackageFlatPack) addWorkspaces(workspaces []Workspace) {
+	keys := make([]string, len(workspaces))
+	manifests := make([]*JavascriptPackageManifestPartial, len(workspaces))
+	for i := range workspaces {
+		manifest := workspaces[i].Manifest
+		version := NewWorkspaceVersion(workspaces[i].Path)
+		manifest.Provider = PackageProviderWorkspace
+		manifest.Status = PackageResolutionStatusSuccess
+		// Not SetVersion, since it lowercases the path
+		manifest.Version = Version{
+			Protocol:    PackageVersionProtocolWorkspace,
+			OriginalTag: version,
+			Tag:         version,
+		}
+
+		keys[i] = NewPackageManifestKey(manifest.Name, version)
+		manifests[i] = &manifest
+		p.workspaces[manifest.Name] = workspaceMember{key: keys[i], version: workspaces[i].Manifest.Version.Tag}
+		p.roots[keys[i]] = true
+		p.putLocalManifest(keys[i], &manifest)
+		p.Append(keys[i], true)
+		atomic.AddUint64(&p.PackageCount, 1)
+	}
+
+	for i, manifest := range manifests {
+		p.FetchDependencies(manifest, keys[i])
+	}
+}
+
+type workspaceMember struct {
+	key string
+	// version is from the workspace's package.json
+	version string
+}
+
+// workspaceKey returns the key of the workspace package name@version links to.
+func (p *PackageFlatPack) workspaceKey(name string, version string) (string, bool) {
+	member, ok := p.workspaces[name]
+	if !ok || !workspaceSatisfies(version, member.version) {
+		return "", false
+	}
+
+	return member.key, true
+}
+
+// isRoot is whether parentKey is the root package or a workspace package, whose dependencies are direct.
+func (p *PackageFlatPack) isRoot(parentKey string) bool {
+	return parentKey == "" || p.roots[parentKey]
+}
+
+// WorkspaceIndex returns the index of the workspace package at path.
+func (p *JavascriptPackageManifest) WorkspaceIndex(path string) (uint, bool) {
+	version := NewWorkspaceVersion(path)
+	for i := range p.Version {
+		if p.Version[i] == version {
+			return uint(i), true
+		}
+	}
+
+	return 0, false
+}
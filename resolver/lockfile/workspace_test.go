@@ -0,0 +1,103 @@
+package lockfile_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/lockfile"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeWorkspaceFile(t *testing.T, dir string, file string, body string) {
+	file = filepath.Join(dir, filepath.FromSlash(file))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+	assert.Nil(t, os.WriteFile(file, []byte(body), 0644))
+}
+
+func TestReadWorkspacePatterns(t *testing.T) {
+	body := []byte(`{"name": "root", "workspaces": ["packages/*", "!packages/legacy"]}`)
+	assert.Equal(t, []string{"packages/*", "!packages/legacy"}, lockfile.ReadWorkspacePatterns(&body))
+
+	body = []byte(`{"name": "root", "workspaces": {"packages": ["apps/*"], "nohoist": ["**/react"]}}`)
+	assert.Equal(t, []string{"apps/*"}, lockfile.ReadWorkspacePatterns(&body))
+
+	body = []byte(`{"name": "root"}`)
+	assert.Empty(t, lockfile.ReadWorkspacePatterns(&body))
+}
+
+func TestLoadWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "packages/ui/package.json", `{"name": "@acme/ui", "version": "1.2.0"}`)
+	writeWorkspaceFile(t, dir, "packages/app/package.json", `{"name": "app", "version": "0.0.1"}`)
+	writeWorkspaceFile(t, dir, "packages/legacy/package.json", `{"name": "legacy", "version": "0.0.1"}`)
+	writeWorkspaceFile(t, dir, "packages/docs/README.md", `# Not a package`)
+
+	workspaces, err := lockfile.LoadWorkspaces(dir, []string{"./packages/*", "!packages/legacy"}, true)
+	assert.Nil(t, err)
+	assert.Len(t, workspaces, 2)
+	assert.Equal(t, "packages/app", workspaces[0].Path)
+	assert.Equal(t, "app", workspaces[0].Manifest.Name)
+	assert.Equal(t, "packages/ui", workspaces[1].Path)
+	assert.Equal(t, "@acme/ui", workspaces[1].Manifest.Name)
+
+	writeWorkspaceFile(t, dir, "other/ui/package.json", `{"name": "@acme/ui", "version": "1.0.0"}`)
+	_, err = lockfile.LoadWorkspaces(dir, []string{"packages/*", "other/*"}, true)
+	assert.NotNil(t, err)
+}
+
+func TestLoadWorkspacesExcludeGlobstar(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "packages/app/package.json", `{"name": "app", "version": "0.0.1"}`)
+	writeWorkspaceFile(t, dir, "packages/legacy/ui/package.json", `{"name": "legacy-ui", "version": "0.0.1"}`)
+	writeWorkspaceFile(t, dir, "packages/legacy/old/app/package.json", `{"name": "legacy-app", "version": "0.0.1"}`)
+
+	workspaces, err := lockfile.LoadWorkspaces(dir, []string{"packages/**", "!packages/legacy/**"}, true)
+	assert.Nil(t, err)
+	assert.Len(t, workspaces, 1)
+	assert.Equal(t, "packages/app", workspaces[0].Path)
+}
+
+func TestResolveDependenciesWorkspaces(t *testing.T) {
+	store := newFakeRegistry(t,
+		map[string]string{
+			"left-pad": `{"tags": {"latest": "1.3.0"}, "versions": ["1.1.0", "1.3.0"]}`,
+		},
+		map[string]string{
+			"left-pad/1.1.0": `{"name": "left-pad", "version": "1.1.0", "main": "index.js"}`,
+			"left-pad/1.3.0": `{"name": "left-pad", "version": "1.3.0", "main": "index.js"}`,
+		},
+	)
+
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "packages/ui/package.json", `{"name": "@acme/ui", "version": "1.2.0", "main": "index.js", "dependencies": {"left-pad": "~1.1.0"}}`)
+	writeWorkspaceFile(t, dir, "packages/app/package.json", `{"name": "app", "version": "0.0.1", "dependencies": {"@acme/ui": "workspace:*", "left-pad": "^1.0.0"}}`)
+	writeWorkspaceFile(t, dir, "packages/site/package.json", `{"name": "site", "version": "0.0.1", "dependencies": {"@acme/ui": "^1.0.0", "@acme/missing": "workspace:^"}}`)
+
+	workspaces, err := lockfile.LoadWorkspaces(dir, []string{"packages/*"}, true)
+	assert.Nil(t, err)
+
+	root := lockfile.JavascriptPackageManifestPartial{Name: "root", Status: lockfile.PackageResolutionStatusSuccess}
+	manifest, err := store.ResolveDependenciesWithOptions(&root, lockfile.ResolveOptions{Workspaces: workspaces}, context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"@acme/ui", "app", "left-pad", "left-pad", "site"}, manifest.Name)
+	assert.Equal(t, []string{"workspace:packages/ui", "workspace:packages/app", "1.1.0", "1.3.0", "workspace:packages/site"}, manifest.Version)
+	assert.Equal(t, []string{"@acme/missing"}, manifest.Failures.Name)
+	assert.Equal(t, []string{"@acme/missing@workspace:^: not found (via site@workspace:packages/site)"}, manifest.Failures.Messages())
+
+	index, ok := manifest.WorkspaceIndex("packages/app")
+	assert.True(t, ok)
+	bytes, err := lockfile.NewWorkspaceImportMap(&manifest, importMapHost, index)
+	assert.Nil(t, err)
+
+	importMap := lockfile.ImportMap{}
+	assert.Nil(t, jsoniter.Unmarshal(bytes, &importMap))
+	assert.Equal(t, "/packages/ui/index.js", importMap.Imports["@acme/ui"])
+	assert.Equal(t, importMapHost+"left-pad@1.3.0/index.js", importMap.Imports["left-pad"])
+
+	// @acme/ui asked for ~1.1.0, so it gets its own scope
+	assert.Equal(t, importMapHost+"left-pad@1.1.0/index.js", importMap.Scopes["/packages/ui/"]["left-pad"])
+}
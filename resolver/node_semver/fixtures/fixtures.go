@@ -244,6 +244,26 @@ func RangeExclude() stringTuple {
 		{">=1.0.0 <1.1.0", "1.1.0"},
 		{">=1.0.0 <1.1.0", "1.1.0-pre"},
 		{">=1.0.0 <1.1.0-pre", "1.1.0-pre"},
+
+		// Prereleases only match ranges with a prerelease of the same major.minor.patch
+		{"2.x", "2.0.0-pre.0"},
+		{"2.x", "2.1.0-pre.0"},
+		{"1.1.x", "1.1.0-a"},
+		{"1.1.x", "1.1.1-a"},
+		{"*", "1.0.0-rc1"},
+		{"^1.0.0-0", "1.0.1-rc1"},
+		{"^1.0.0-rc2", "1.0.1-rc1"},
+		{"^1.0.0", "1.0.1-rc1"},
+		{"^1.0.0", "1.1.0-rc1"},
+		{"1 - 2", "2.0.0-pre"},
+		{"1 - 2", "1.0.0-pre"},
+		{"1.0 - 2", "1.0.0-pre"},
+
+		{"=0.7.x", "0.7.0-asdf"},
+		{">=0.7.x", "0.7.0-asdf"},
+		{"<=0.7.x", "0.7.0-asdf"},
+
+		{">=1.0.0 <=1.1.0", "1.1.0-pre"},
 	}
 }
 
@@ -355,24 +375,6 @@ func RangesInclusive() stringTuple {
 		{"1.0.0 - x", "1.9.7"},
 		{"1.x - x", "1.9.7"},
 		{"<=7.x", "7.9.9"},
-		{"2.x", "2.0.0-pre.0"},
-		{"2.x", "2.1.0-pre.0"},
-		{"1.1.x", "1.1.0-a"},
-		{"1.1.x", "1.1.1-a"},
-		{"*", "1.0.0-rc1"},
-		{"^1.0.0-0", "1.0.1-rc1"},
-		{"^1.0.0-rc2", "1.0.1-rc1"},
-		{"^1.0.0", "1.0.1-rc1"},
-		{"^1.0.0", "1.1.0-rc1"},
-		{"1 - 2", "2.0.0-pre"},
-		{"1 - 2", "1.0.0-pre"},
-		{"1.0 - 2", "1.0.0-pre"},
-
-		{"=0.7.x", "0.7.0-asdf"},
-		{">=0.7.x", "0.7.0-asdf"},
-		{"<=0.7.x", "0.7.0-asdf"},
-
-		{">=1.0.0 <=1.1.0", "1.1.0-pre"},
 	}
 
 }
@@ -178,8 +178,8 @@ func (vv *Version) ToWildcardRange(wildcard WildcardType) Range {
 				Major: vv.Major,
 				Minor: vv.Minor + 1,
 			}
+			// The prerelease stays, so "~1.2.3-beta" includes 1.2.3-beta.2
 			vv.Build = nil
-			vv.Pre = nil
 			return v.ToRange(compLT).AND(vv.ToRange(compGE))
 		}
 
@@ -344,21 +344,8 @@ func (v Version) SortableCompare(o Version) int {
 // -1 == v is less than o
 // 0 == v is equal to o
 // 1 == v is greater than o
+// A prerelease is less than its release, so 1.0.0-beta.2 < 1.0.0-beta.10 < 1.0.0.
 func (v Version) Compare(o Version) int {
-
-	hasPre := len(v.Pre) > 0
-	otherHasPre := len(o.Pre) > 0
-
-	if hasPre != otherHasPre && otherHasPre {
-		return 1
-	}
-
-	// if hasPre && !otherHasPre {
-	// 	return -1
-	// } else if otherHasPre && !hasPre {
-	// 	return 1
-	// }
-
 	if v.Major > o.Major {
 		return 1
 	} else if v.Major < o.Major {
@@ -373,6 +360,10 @@ func (v Version) Compare(o Version) int {
 		return -1
 	}
 
+	if comparison := comparePrerelease(v.Pre, o.Pre); comparison != 0 {
+		return comparison
+	}
+
 	hasBuild := len(v.Build) > 0
 	otherHasBuild := len(o.Build) > 0
 	if hasBuild && !otherHasBuild {
@@ -451,6 +442,56 @@ func (v Version) Compare(o Version) int {
 	return 0
 }
 
+// comparePrerelease orders prereleases by their identifiers, like npm. No prerelease is higher than any prerelease.
+// Parts can have more than one identifier, like "beta.1", so they're split on "." first.
+func comparePrerelease(pre []string, otherPre []string) int {
+	if len(pre) == 0 && len(otherPre) == 0 {
+		return 0
+	} else if len(pre) == 0 {
+		return 1
+	} else if len(otherPre) == 0 {
+		return -1
+	}
+
+	identifiers := strings.Split(strings.Join(pre, "."), ".")
+	otherIdentifiers := strings.Split(strings.Join(otherPre, "."), ".")
+	for i := 0; i < len(identifiers) && i < len(otherIdentifiers); i++ {
+		if comparison := compareIdentifier(identifiers[i], otherIdentifiers[i]); comparison != 0 {
+			return comparison
+		}
+	}
+
+	if len(identifiers) > len(otherIdentifiers) {
+		return 1
+	} else if len(identifiers) < len(otherIdentifiers) {
+		return -1
+	}
+
+	return 0
+}
+
+// compareIdentifier compares numeric identifiers as numbers, and sorts them before alphanumeric ones.
+func compareIdentifier(identifier string, other string) int {
+	number, err := strconv.ParseUint(identifier, 10, 64)
+	otherNumber, otherErr := strconv.ParseUint(other, 10, 64)
+
+	if err == nil && otherErr == nil {
+		if number > otherNumber {
+			return 1
+		} else if number < otherNumber {
+			return -1
+		}
+
+		return 0
+	} else if err == nil {
+		return -1
+	} else if otherErr == nil {
+		return 1
+	}
+
+	return strings.Compare(identifier, other)
+}
+
 // rangeFunc creates a Range from the given versionRange.
 func (vr *versionRange) rangeFunc() Range {
 	return Range(func(v Version) bool {
@@ -574,7 +615,7 @@ func buildRangeFunc(comp comparator, major uint64, minor uint64, patch uint64) R
 	})
 }
 
-var preparsedTable = map[string]TokenizeResult{
+var preparsedTable = withoutPrereleases(map[string]TokenizeResult{
 	"*":  {Range: buildRangeFunc(compGE, 0, 0, 0), Value: TokenizeResultValueRange},
 	"X":  {Range: buildRangeFunc(compGE, 0, 0, 0), Value: TokenizeResultValueRange},
 	"x":  {Range: buildRangeFunc(compGE, 0, 0, 0), Value: TokenizeResultValueRange},
@@ -590,6 +631,16 @@ var preparsedTable = map[string]TokenizeResult{
 	"9":  {Range: buildRangeFunc(compGE, 9, 0, 0).AND(buildRangeFunc(compLT, 10, 0, 0)), Value: TokenizeResultValueRange},
 	"10": {Range: buildRangeFunc(compGE, 10, 0, 0).AND(buildRangeFunc(compLT, 11, 0, 0)), Value: TokenizeResultValueRange},
 	"":   {Range: buildRangeFunc(compGE, 0, 0, 0), Value: TokenizeResultValueRange},
+})
+
+// withoutPrereleases is table, with prereleases excluded from its ranges like every other range's.
+func withoutPrereleases(table map[string]TokenizeResult) map[string]TokenizeResult {
+	for input, result := range table {
+		result.Range = excludePrereleases(result.Range, input)
+		table[input] = result
+	}
+
+	return table
 }
 
 func (result *TokenizeResult) AppendVersion(v *Version) {
@@ -732,7 +783,8 @@ func (t *semverToken) ToRange(v *Version) Range {
 
 	case SevmerTokenTypeTilda:
 		{
-			if v.Minor == 0 || t.Wildcard == MinorWildcard || t.Wildcard == MajorWildcard {
+			// "~1" is 1.x.x, and "~1.0" & "~1.0.3" are 1.0.x
+			if t.Wildcard == MinorWildcard || t.Wildcard == MajorWildcard {
 				return v.ToWildcardRange(MinorWildcard)
 			} else {
 				return v.ToWildcardRange(PatchWildcard)
@@ -746,6 +798,17 @@ func (t *semverToken) ToRange(v *Version) Range {
 
 	case SevmerTokenTypeLE:
 		{
+			switch t.Wildcard {
+			case PatchWildcard:
+				{
+					v.Patch = math.MaxUint64
+				}
+			case MinorWildcard:
+				{
+					v.Minor = math.MaxUint64
+					v.Patch = math.MaxUint64
+				}
+			}
 			return v.ToRange(compLE)
 		}
 
@@ -769,7 +832,6 @@ func (t *semverToken) ToRange(v *Version) Range {
 			case PatchWildcard:
 				{
 					v.Patch = 0
-					v.Minor = 0
 				}
 			case MinorWildcard:
 				{
@@ -784,11 +846,120 @@ func (t *semverToken) ToRange(v *Version) Range {
 	return v.ToWildcardRange(t.Wildcard)
 }
 
+// Tokenize parses a version like "1.2.3", or a range like "^1.2.0 || 2.0.0 - 3".
+// Like npm, a prerelease is only in a range when one of the range's "||" alternatives has a prerelease of the same major.minor.patch,
+// so "^1.0.0" doesn't include 2.0.0-beta, and ">=1.0.0-beta" doesn't include 3.0.0-alpha.
 func Tokenize(input string) TokenizeResult {
 	if preparsedRange, hasPreparsedRange := preparsedTable[input]; hasPreparsedRange {
 		return preparsedRange
 	}
 
+	if !strings.Contains(input, "||") {
+		return tokenizeAlternative(input)
+	}
+
+	result := TokenizeResult{}
+	for _, alternative := range strings.Split(input, "||") {
+		tokenized := tokenizeAlternative(alternative)
+		switch tokenized.Value {
+		case TokenizeResultValueVersion:
+			{
+				result.AppendVersion(tokenized.Version)
+			}
+		case TokenizeResultValueRange:
+			{
+				result.AppendORRange(tokenized.Range)
+			}
+		}
+	}
+
+	return result
+}
+
+// tokenizeAlternative parses one of a range's "||" alternatives.
+func tokenizeAlternative(input string) TokenizeResult {
+	if strings.TrimSpace(input) == "" {
+		return preparsedTable[""]
+	}
+
+	// Hyphen ranges need spaces around the "-", so "1.0.0-beta" is a prerelease
+	if strings.IndexByte(input, '-') > -1 {
+		if fields := strings.Fields(input); len(fields) == 3 && fields[1] == "-" {
+			if hyphen, ok := hyphenRange(fields[0], fields[2]); ok {
+				return TokenizeResult{Range: excludePrereleases(hyphen, input), Value: TokenizeResultValueRange}
+			}
+		}
+	}
+
+	result := tokenizeComparators(input)
+	if result.Value == TokenizeResultValueRange {
+		result.Range = excludePrereleases(result.Range, input)
+	}
+
+	return result
+}
+
+// hyphenRange is "from - to", including to. A partial to includes every version it starts with, so "1.0.0 - 2" is ">=1.0.0 <3.0.0-0".
+func hyphenRange(from string, to string) (Range, bool) {
+	var lower, upper Version
+	fromWildcard, fromValid, _ := parseVersion(strings.TrimPrefix(from, "v"), &lower)
+	toWildcard, toValid, _ := parseVersion(strings.TrimPrefix(to, "v"), &upper)
+	if !fromValid || !toValid {
+		return nil, false
+	}
+
+	hyphen := Range(func(Version) bool { return true })
+	if fromWildcard != MajorWildcard {
+		lower.Build = nil
+		hyphen = hyphen.AND(lower.ToRange(compGE))
+	}
+
+	switch toWildcard {
+	case MajorWildcard:
+		{
+			return hyphen, true
+		}
+	case MinorWildcard:
+		{
+			return hyphen.AND(Version{Major: upper.Major + 1, Pre: []string{"0"}}.ToRange(compLT)), true
+		}
+	case PatchWildcard:
+		{
+			return hyphen.AND(Version{Major: upper.Major, Minor: upper.Minor + 1, Pre: []string{"0"}}.ToRange(compLT)), true
+		}
+	}
+
+	upper.Build = nil
+	return hyphen.AND(upper.ToRange(compLE)), true
+}
+
+// excludePrereleases limits r to releases, and prereleases of the major.minor.patch of a prerelease in comparators.
+func excludePrereleases(r Range, comparators string) Range {
+	var prereleases []Version
+	for _, field := range strings.Fields(comparators) {
+		var parsed Version
+		if parseVersion(strings.TrimLeft(field, "<>=~^v"), &parsed); len(parsed.Pre) > 0 {
+			prereleases = append(prereleases, Version{Major: parsed.Major, Minor: parsed.Minor, Patch: parsed.Patch})
+		}
+	}
+
+	return Range(func(v Version) bool {
+		if len(v.Pre) == 0 {
+			return r(v)
+		}
+
+		for _, prerelease := range prereleases {
+			if prerelease.Major == v.Major && prerelease.Minor == v.Minor && prerelease.Patch == v.Patch {
+				return r(v)
+			}
+		}
+
+		return false
+	})
+}
+
+// tokenizeComparators parses comparators like ">=1.2.0 <2", without any "||".
+func tokenizeComparators(input string) TokenizeResult {
 	i := 0
 	length := len(input)
 	lastNonwhitespace := -1
@@ -816,7 +987,7 @@ func Tokenize(input string) TokenizeResult {
 					isOR = false
 				}
 
-				if input[i+1] == '=' {
+				if i+1 < length && input[i+1] == '=' {
 					wipToken = SevmerTokenTypeGE
 					i++
 				} else {
@@ -830,8 +1001,8 @@ func Tokenize(input string) TokenizeResult {
 			}
 		case '<':
 			{
-				if input[i+1] == '=' {
-					wipToken = SevmerTokenTypeGE
+				if i+1 < length && input[i+1] == '=' {
+					wipToken = SevmerTokenTypeLE
 					i++
 				} else {
 					wipToken = SevmerTokenTypeLT
@@ -1185,20 +1356,22 @@ func parseBuild(parts *Version, input string) int {
 	preSegments := make([]string, 0, preCount)
 
 	start := 0
+	end := len(input)
 	trailingIsBuild := false
 	stoppedAt = 0
 	for i, char := range input {
 		stoppedAt = i
 		if char == ' ' {
-
+			end = i
 			break
 		}
 
-		if char == '-' || char == '+' {
+		// A "-" inside a prerelease is part of it, like "alpha-1"
+		if char == '+' || (char == '-' && (start == 0 || trailingIsBuild)) {
 			if i > start && trailingIsBuild {
-				buildSegments = append(buildSegments, input[start:i+1])
+				buildSegments = append(buildSegments, input[start:i])
 			} else if i > start && !trailingIsBuild {
-				preSegments = append(preSegments, input[start:i+1])
+				preSegments = append(preSegments, input[start:i])
 			}
 
 			start = i + 1
@@ -1206,10 +1379,10 @@ func parseBuild(parts *Version, input string) int {
 		}
 	}
 
-	if start > 0 && trailingIsBuild {
-		buildSegments = append(buildSegments, input[start:])
-	} else if start > 0 && !trailingIsBuild {
-		preSegments = append(preSegments, input[start:])
+	if start > 0 && start < end && trailingIsBuild {
+		buildSegments = append(buildSegments, input[start:end])
+	} else if start > 0 && start < end && !trailingIsBuild {
+		preSegments = append(preSegments, input[start:end])
 	}
 	parts.Build = buildSegments
 	parts.Pre = preSegments
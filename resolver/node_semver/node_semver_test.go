@@ -459,6 +459,59 @@ func TestLTRange(t *testing.T) {
 	assertRangeMatch(t, "<2.0.0", "0.2.9")
 }
 
+func TestLERange(t *testing.T) {
+	assertRangeMatch(t, "<=1.2.3", "1.2.3")
+	assertRangeMatch(t, "<=1.2.3", "1.0.0")
+	assertRangeNotMatch(t, "<=1.2.3", "1.2.4")
+	assertRangeMatch(t, "<=1.2", "1.2.9")
+	assertRangeNotMatch(t, "<=1.2", "1.3.0")
+}
+
+func TestHyphenRange(t *testing.T) {
+	assertRangeMatch(t, "1.0.0 - 2.0.0", "1.0.0")
+	assertRangeMatch(t, "1.0.0 - 2.0.0", "2.0.0")
+	assertRangeNotMatch(t, "1.0.0 - 2.0.0", "2.0.1")
+	assertRangeNotMatch(t, "1.0.0 - 2.0.0", "0.9.9")
+
+	assertRangeMatch(t, "1.0.0 - 2", "2.9.9")
+	assertRangeNotMatch(t, "1.0.0 - 2", "3.0.0")
+	assertRangeNotMatch(t, "1.0.0 - 2", "3.0.0-beta")
+	assertRangeMatch(t, "1.0.0 - 2.3", "2.3.9")
+	assertRangeNotMatch(t, "1.0.0 - 2.3", "2.4.0")
+
+	assertRangeMatch(t, "1.0.0 - 2.0.0 || 4.0.0", "4.0.0")
+	assertRangeNotMatch(t, "1.0.0 - 2.0.0 || 4.0.0", "3.0.0")
+}
+
+func TestTildeRange(t *testing.T) {
+	assertRangeMatch(t, "~1.0.3", "1.0.9")
+	assertRangeNotMatch(t, "~1.0.3", "1.0.2")
+	assertRangeNotMatch(t, "~1.0.3", "1.1.0")
+	assertRangeMatch(t, "~1.0.0-beta", "1.0.0-beta.2")
+}
+
+func TestPrereleaseRange(t *testing.T) {
+	assertRangeMatch(t, ">=1.0.0-beta.0", "1.0.0-beta.1")
+	assertRangeMatch(t, ">=1.0.0-beta.0", "3.0.0")
+	assertRangeNotMatch(t, ">=1.0.0-beta.0", "3.0.0-alpha")
+	assertRangeNotMatch(t, "^1.2.3", "1.2.3-pre")
+	assertRangeNotMatch(t, "^1.2.3", "1.5.0-pre")
+	assertRangeNotMatch(t, "*", "1.0.0-beta")
+	assertRangeMatch(t, "^1.2.3-pre.1", "1.2.3-pre.2")
+	assertRangeNotMatch(t, "^1.2.3-pre.1", "1.2.3-pre.0")
+}
+
+func TestComparePrerelease(t *testing.T) {
+	beta2 := node_semver.Tokenize("1.0.0-beta.2").Version
+	beta10 := node_semver.Tokenize("1.0.0-beta.10").Version
+	release := node_semver.Tokenize("1.0.0").Version
+
+	assert.Equal(t, -1, beta2.Compare(*beta10))
+	assert.Equal(t, -1, beta10.Compare(*release))
+	assert.Equal(t, 1, release.Compare(*beta2))
+	assert.Equal(t, 0, beta10.Compare(*beta10))
+}
+
 func shuffleInPlace(isArray []string) {
 	l := len(isArray) - 1
 	for i := 0; i <= l; i++ {
@@ -12,6 +12,7 @@ type BusSubscriber interface {
 	SubscribeAsync(topic string, fn interface{}, transactional bool) error
 	SubscribeOnce(topic string, fn interface{}) error
 	SubscribeOnceAsync(topic string, fn interface{}) error
+	SubscribeOnceAsyncFirst(topic string, fn interface{}) (bool, error)
 	Unsubscribe(topic string, handler interface{}) error
 }
 
@@ -104,6 +105,22 @@ func (bus *EventBus) SubscribeOnceAsync(topic string, fn interface{}) error {
 	})
 }
 
+// SubscribeOnceAsyncFirst is SubscribeOnceAsync, and also returns true if no other callback was subscribed to the topic.
+// Checking and subscribing happen under one lock, so exactly one of several concurrent subscribers is first,
+// and a Publish can't run between the check and the subscription.
+func (bus *EventBus) SubscribeOnceAsyncFirst(topic string, fn interface{}) (bool, error) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if !(reflect.TypeOf(fn).Kind() == reflect.Func) {
+		return false, fmt.Errorf("%s is not of type reflect.Func", reflect.TypeOf(fn).Kind())
+	}
+	first := len(bus.handlers[topic]) == 0
+	bus.handlers[topic] = append(bus.handlers[topic], &eventHandler{
+		reflect.ValueOf(fn), true, true, false, sync.Mutex{},
+	})
+	return first, nil
+}
+
 // HasCallback returns true if exists any callback subscribed to the topic.
 func (bus *EventBus) HasCallback(topic string) bool {
 	bus.lock.Lock()
@@ -0,0 +1,43 @@
+package runner_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jarred-sumner/devserverless/resolver/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeOnceAsyncFirst(t *testing.T) {
+	bus := runner.New()
+
+	var firsts, calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first, err := bus.SubscribeOnceAsyncFirst("react", func() {
+				atomic.AddInt32(&calls, 1)
+			})
+			assert.Nil(t, err)
+			if first {
+				atomic.AddInt32(&firsts, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), firsts)
+
+	bus.Publish("react")
+	bus.WaitAsync()
+	assert.Equal(t, int32(50), calls)
+	assert.False(t, bus.HasCallback("react"))
+
+	// Once they've all run, the next subscriber is first again
+	first, err := bus.SubscribeOnceAsyncFirst("react", func() {})
+	assert.Nil(t, err)
+	assert.True(t, first)
+}
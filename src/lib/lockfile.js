@@ -6,13 +6,15 @@ const PackageProvider = {
   "5": 5,
   "6": 6,
   "7": 7,
+  "8": 8,
   "npm": 1,
   "git": 2,
   "https": 3,
   "tgz": 4,
   "disk": 5,
   "other": 6,
-  "github": 7
+  "github": 7,
+  "workspace": 8
 };
 const PackageProviderKeys = {
   "1": "npm",
@@ -22,13 +24,15 @@ const PackageProviderKeys = {
   "5": "disk",
   "6": "other",
   "7": "github",
+  "8": "workspace",
   "npm": "npm",
   "git": "git",
   "https": "https",
   "tgz": "tgz",
   "disk": "disk",
   "other": "other",
-  "github": "github"
+  "github": "github",
+  "workspace": "workspace"
 };
 const PackageVersionProtocol = {
   "1": 1,
@@ -43,6 +47,8 @@ const PackageVersionProtocol = {
   "10": 10,
   "11": 11,
   "12": 12,
+  "13": 13,
+  "14": 14,
   "github_bare": 1,
   "github_dot_com": 2,
   "github_tarball": 3,
@@ -54,7 +60,9 @@ const PackageVersionProtocol = {
   "git": 9,
   "git_ssh": 10,
   "pathlike": 11,
-  "default": 12
+  "default": 12,
+  "npm": 13,
+  "workspace": 14
 };
 const PackageVersionProtocolKeys = {
   "1": "github_bare",
@@ -69,6 +77,8 @@ const PackageVersionProtocolKeys = {
   "10": "git_ssh",
   "11": "pathlike",
   "12": "default",
+  "13": "npm",
+  "14": "workspace",
   "github_bare": "github_bare",
   "github_dot_com": "github_dot_com",
   "github_tarball": "github_tarball",
@@ -80,7 +90,9 @@ const PackageVersionProtocolKeys = {
   "git": "git",
   "git_ssh": "git_ssh",
   "pathlike": "pathlike",
-  "default": "default"
+  "default": "default",
+  "npm": "npm",
+  "workspace": "workspace"
 };
 const VersionRange = {
   "1": 1,
@@ -119,6 +131,7 @@ const PackageResolutionStatus = {
   "6": 6,
   "7": 7,
   "8": 8,
+  "9": 9,
   "success": 1,
   "missingName": 2,
   "missingVersion": 3,
@@ -126,7 +139,8 @@ const PackageResolutionStatus = {
   "corruptPackage": 5,
   "rateLimit": 6,
   "invalidVersion": 7,
-  "internal": 8
+  "internal": 8,
+  "unsupportedPlatform": 9
 };
 const PackageResolutionStatusKeys = {
   "1": "success",
@@ -137,6 +151,7 @@ const PackageResolutionStatusKeys = {
   "6": "rateLimit",
   "7": "invalidVersion",
   "8": "internal",
+  "9": "unsupportedPlatform",
   "success": "success",
   "missingName": "missingName",
   "missingVersion": "missingVersion",
@@ -144,7 +159,28 @@ const PackageResolutionStatusKeys = {
   "corruptPackage": "corruptPackage",
   "rateLimit": "rateLimit",
   "invalidVersion": "invalidVersion",
-  "internal": "internal"
+  "internal": "internal",
+  "unsupportedPlatform": "unsupportedPlatform"
+};
+const DependencyGroup = {
+  "1": 1,
+  "2": 2,
+  "4": 4,
+  "8": 8,
+  "prod": 1,
+  "dev": 2,
+  "peer": 4,
+  "optional": 8
+};
+const DependencyGroupKeys = {
+  "1": "prod",
+  "2": "dev",
+  "4": "peer",
+  "8": "optional",
+  "prod": "prod",
+  "dev": "dev",
+  "peer": "peer",
+  "optional": "optional"
 };
 const BareField = {
   "1": 1,
@@ -403,6 +439,178 @@ function encodeRawDependencyList(message, bb) {
 
 }
 
+function decodePackageResolutionFailures(bb) {
+  var result = {};
+
+  var length = bb.readVarUint();
+  var values = result["name"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["range"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["status"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = PackageResolutionStatus[bb.readByte()];
+  var length = bb.readVarUint();
+  var values = result["parentCount"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readVarUint();
+  var length = bb.readVarUint();
+  var values = result["parents"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  return result;
+}
+
+function encodePackageResolutionFailures(message, bb) {
+
+  var value = message["name"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"name\"");
+  }
+
+  var value = message["range"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"range\"");
+  }
+
+  var value = message["status"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      var encoded = PackageResolutionStatus[value];
+if (encoded === void 0) throw new Error("Invalid value " + JSON.stringify(value) + " for enum \"PackageResolutionStatus\"");
+bb.writeByte(encoded);
+    }
+  } else {
+    throw new Error("Missing required field \"status\"");
+  }
+
+  var value = message["parentCount"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeVarUint(value);
+    }
+  } else {
+    throw new Error("Missing required field \"parentCount\"");
+  }
+
+  var value = message["parents"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"parents\"");
+  }
+
+}
+
+function decodePeerDependencyConflicts(bb) {
+  var result = {};
+
+  var length = bb.readVarUint();
+  var values = result["name"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["range"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["requester"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["provided"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["provider"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  return result;
+}
+
+function encodePeerDependencyConflicts(message, bb) {
+
+  var value = message["name"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"name\"");
+  }
+
+  var value = message["range"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"range\"");
+  }
+
+  var value = message["requester"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"requester\"");
+  }
+
+  var value = message["provided"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"provided\"");
+  }
+
+  var value = message["provider"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"provider\"");
+  }
+
+}
+
 function decodeJavascriptPackageManifest(bb) {
   var result = {};
 
@@ -425,6 +633,31 @@ function decodeJavascriptPackageManifest(bb) {
   var length = bb.readVarUint();
   var values = result["dependencies"] = Array(length);
   for (var i = 0; i < length; i++) values[i] = bb.readVarUint();
+  var length = bb.readVarUint();
+  var values = result["rootDependencies"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readVarUint();
+  result["failures"] = decodePackageResolutionFailures(bb);
+  var length = bb.readVarUint();
+  var values = result["groups"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readVarUint();
+  result["peerConflicts"] = decodePeerDependencyConflicts(bb);
+  result["registrar"] = bb.readString();
+  result["importMapHost"] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["hashInputs"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["integrity"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["os"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["cpu"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
+  var length = bb.readVarUint();
+  var values = result["libc"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readString();
   return result;
 }
 
@@ -520,6 +753,118 @@ bb.writeByte(encoded);
     throw new Error("Missing required field \"dependencies\"");
   }
 
+  var value = message["rootDependencies"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeVarUint(value);
+    }
+  } else {
+    throw new Error("Missing required field \"rootDependencies\"");
+  }
+
+  var value = message["failures"];
+  if (value != null) {
+    encodePackageResolutionFailures(value, bb);
+  } else {
+    throw new Error("Missing required field \"failures\"");
+  }
+
+  var value = message["groups"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeVarUint(value);
+    }
+  } else {
+    throw new Error("Missing required field \"groups\"");
+  }
+
+  var value = message["peerConflicts"];
+  if (value != null) {
+    encodePeerDependencyConflicts(value, bb);
+  } else {
+    throw new Error("Missing required field \"peerConflicts\"");
+  }
+
+  var value = message["registrar"];
+  if (value != null) {
+    bb.writeString(value);
+  } else {
+    throw new Error("Missing required field \"registrar\"");
+  }
+
+  var value = message["importMapHost"];
+  if (value != null) {
+    bb.writeString(value);
+  } else {
+    throw new Error("Missing required field \"importMapHost\"");
+  }
+
+  var value = message["hashInputs"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"hashInputs\"");
+  }
+
+  var value = message["integrity"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"integrity\"");
+  }
+
+  var value = message["os"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"os\"");
+  }
+
+  var value = message["cpu"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"cpu\"");
+  }
+
+  var value = message["libc"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeString(value);
+    }
+  } else {
+    throw new Error("Missing required field \"libc\"");
+  }
+
 }
 
 function decodeResolvedJavascriptPackageTag(bb) {
@@ -595,6 +940,32 @@ function decodeJavascriptPackageManifestPartial(bb) {
   var values = result["binValues"] = Array(length);
   for (var i = 0; i < length; i++) values[i] = bb.readString();
   result["hasPostInstall"] = !!bb.readByte();
+  var length = bb.readVarUint();
+  var values = result["overrideSelectors"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["overrideVersions"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["optionalDependencyNames"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["optionalDependencyVersions"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["os"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["cpu"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["libc"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  var length = bb.readVarUint();
+  var values = result["optionalPeerDependencyNames"] = Array(length);
+  for (var i = 0; i < length; i++) values[i] = bb.readAlphanumeric();
+  result["binDirectory"] = bb.readString();
+  result["integrity"] = bb.readString();
   return result;
 }
 
@@ -766,6 +1137,116 @@ bb.writeByte(encoded);
     throw new Error("Missing required field \"hasPostInstall\"");
   }
 
+  var value = message["overrideSelectors"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"overrideSelectors\"");
+  }
+
+  var value = message["overrideVersions"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"overrideVersions\"");
+  }
+
+  var value = message["optionalDependencyNames"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"optionalDependencyNames\"");
+  }
+
+  var value = message["optionalDependencyVersions"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"optionalDependencyVersions\"");
+  }
+
+  var value = message["os"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"os\"");
+  }
+
+  var value = message["cpu"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"cpu\"");
+  }
+
+  var value = message["libc"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"libc\"");
+  }
+
+  var value = message["optionalPeerDependencyNames"];
+  if (value != null) {
+    var values = value, n = values.length;
+    bb.writeVarUint(n);
+    for (var i = 0; i < n; i++) {
+      value = values[i];
+      bb.writeAlphanumeric(value);
+    }
+  } else {
+    throw new Error("Missing required field \"optionalPeerDependencyNames\"");
+  }
+
+  var value = message["binDirectory"];
+  if (value != null) {
+    bb.writeString(value);
+  } else {
+    throw new Error("Missing required field \"binDirectory\"");
+  }
+
+  var value = message["integrity"];
+  if (value != null) {
+    bb.writeString(value);
+  } else {
+    throw new Error("Missing required field \"integrity\"");
+  }
+
 }
 
 function decodeJavascriptPackageRequest(bb) {
@@ -792,6 +1273,22 @@ function decodeJavascriptPackageRequest(bb) {
       result["manifest"] = decodeJavascriptPackageManifestPartial(bb);
       break;
 
+    case 5:
+      result["os"] = bb.readAlphanumeric();
+      break;
+
+    case 6:
+      result["cpu"] = bb.readAlphanumeric();
+      break;
+
+    case 7:
+      result["libc"] = bb.readAlphanumeric();
+      break;
+
+    case 8:
+      result["groups"] = bb.readVarUint();
+      break;
+
     default:
       throw new Error("Attempted to parse invalid message");
     }
@@ -823,6 +1320,30 @@ function encodeJavascriptPackageRequest(message, bb) {
     bb.writeVarUint(4);
     encodeJavascriptPackageManifestPartial(value, bb);
   }
+
+  var value = message["os"];
+  if (value != null) {
+    bb.writeVarUint(5);
+    bb.writeAlphanumeric(value);
+  }
+
+  var value = message["cpu"];
+  if (value != null) {
+    bb.writeVarUint(6);
+    bb.writeAlphanumeric(value);
+  }
+
+  var value = message["libc"];
+  if (value != null) {
+    bb.writeVarUint(7);
+    bb.writeAlphanumeric(value);
+  }
+
+  var value = message["groups"];
+  if (value != null) {
+    bb.writeVarUint(8);
+    bb.writeVarUint(value);
+  }
   bb.writeVarUint(0);
 
 }
@@ -926,6 +1447,8 @@ export { VersionRange }
 export { VersionRangeKeys }
 export { PackageResolutionStatus }
 export { PackageResolutionStatusKeys }
+export { DependencyGroup }
+export { DependencyGroupKeys }
 export { BareField }
 export { BareFieldKeys }
 export { decodeExportsManifest }
@@ -936,6 +1459,10 @@ export { decodeVersion }
 export { encodeVersion }
 export { decodeRawDependencyList }
 export { encodeRawDependencyList }
+export { decodePackageResolutionFailures }
+export { encodePackageResolutionFailures }
+export { decodePeerDependencyConflicts }
+export { encodePeerDependencyConflicts }
 export { decodeJavascriptPackageManifest }
 export { encodeJavascriptPackageManifest }
 export { decodeResolvedJavascriptPackageTag }
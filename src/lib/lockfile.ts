@@ -20,7 +20,8 @@ type uint32 = number;
     tgz = 4,
     disk = 5,
     other = 6,
-    github = 7
+    github = 7,
+    workspace = 8
   }
   export const PackageProviderKeys = {
     1: "npm",
@@ -36,7 +37,9 @@ type uint32 = number;
     6: "other",
     other: "other",
     7: "github",
-    github: "github"
+    github: "github",
+    8: "workspace",
+    workspace: "workspace"
   }
   export enum PackageVersionProtocol {
     github_bare = 1,
@@ -50,7 +53,9 @@ type uint32 = number;
     git = 9,
     git_ssh = 10,
     pathlike = 11,
-    default = 12
+    default = 12,
+    npm = 13,
+    workspace = 14
   }
   export const PackageVersionProtocolKeys = {
     1: "github_bare",
@@ -76,7 +81,11 @@ type uint32 = number;
     11: "pathlike",
     pathlike: "pathlike",
     12: "default",
-    default: "default"
+    default: "default",
+    13: "npm",
+    npm: "npm",
+    14: "workspace",
+    workspace: "workspace"
   }
   export enum VersionRange {
     exact = 1,
@@ -108,7 +117,8 @@ type uint32 = number;
     corruptPackage = 5,
     rateLimit = 6,
     invalidVersion = 7,
-    internal = 8
+    internal = 8,
+    unsupportedPlatform = 9
   }
   export const PackageResolutionStatusKeys = {
     1: "success",
@@ -126,7 +136,25 @@ type uint32 = number;
     7: "invalidVersion",
     invalidVersion: "invalidVersion",
     8: "internal",
-    internal: "internal"
+    internal: "internal",
+    9: "unsupportedPlatform",
+    unsupportedPlatform: "unsupportedPlatform"
+  }
+  export enum DependencyGroup {
+    prod = 1,
+    dev = 2,
+    peer = 4,
+    optional = 8
+  }
+  export const DependencyGroupKeys = {
+    1: "prod",
+    prod: "prod",
+    2: "dev",
+    dev: "dev",
+    4: "peer",
+    peer: "peer",
+    8: "optional",
+    optional: "optional"
   }
   export enum BareField {
     otherField = 1,
@@ -197,6 +225,22 @@ type uint32 = number;
     versions: string[];
   }
 
+  export interface PackageResolutionFailures {
+    name: alphanumeric[];
+    range: string[];
+    status: PackageResolutionStatus[];
+    parentCount: uint[];
+    parents: alphanumeric[];
+  }
+
+  export interface PeerDependencyConflicts {
+    name: alphanumeric[];
+    range: string[];
+    requester: alphanumeric[];
+    provided: alphanumeric[];
+    provider: alphanumeric[];
+  }
+
   export interface JavascriptPackageManifest {
     hash: string;
     count: uint;
@@ -207,6 +251,17 @@ type uint32 = number;
     exportsManifest: ExportsManifest;
     exportsManifestIndex: uint[];
     dependencies: uint[];
+    rootDependencies: uint[];
+    failures: PackageResolutionFailures;
+    groups: uint[];
+    peerConflicts: PeerDependencyConflicts;
+    registrar: string;
+    importMapHost: string;
+    hashInputs: string[];
+    integrity: string[];
+    os: string[];
+    cpu: string[];
+    libc: string[];
   }
 
   export interface ResolvedJavascriptPackageTag {
@@ -232,6 +287,16 @@ type uint32 = number;
     binKeys: string[];
     binValues: string[];
     hasPostInstall: boolean;
+    overrideSelectors: alphanumeric[];
+    overrideVersions: alphanumeric[];
+    optionalDependencyNames: alphanumeric[];
+    optionalDependencyVersions: alphanumeric[];
+    os: alphanumeric[];
+    cpu: alphanumeric[];
+    libc: alphanumeric[];
+    optionalPeerDependencyNames: alphanumeric[];
+    binDirectory: string;
+    integrity: string;
   }
 
   export interface JavascriptPackageRequest {
@@ -239,6 +304,10 @@ type uint32 = number;
     name?: alphanumeric;
     enableDenylist?: boolean;
     manifest?: JavascriptPackageManifestPartial;
+    os?: alphanumeric;
+    cpu?: alphanumeric;
+    libc?: alphanumeric;
+    groups?: uint;
   }
 
   export interface JavascriptPackageResponse {
@@ -257,6 +326,10 @@ type uint32 = number;
   export declare function decodeVersion(buffer: ByteBuffer): Version;
   export declare function  encodeRawDependencyList(message: RawDependencyList, bb: ByteBuffer): void;
   export declare function decodeRawDependencyList(buffer: ByteBuffer): RawDependencyList;
+  export declare function  encodePackageResolutionFailures(message: PackageResolutionFailures, bb: ByteBuffer): void;
+  export declare function decodePackageResolutionFailures(buffer: ByteBuffer): PackageResolutionFailures;
+  export declare function  encodePeerDependencyConflicts(message: PeerDependencyConflicts, bb: ByteBuffer): void;
+  export declare function decodePeerDependencyConflicts(buffer: ByteBuffer): PeerDependencyConflicts;
   export declare function  encodeJavascriptPackageManifest(message: JavascriptPackageManifest, bb: ByteBuffer): void;
   export declare function decodeJavascriptPackageManifest(buffer: ByteBuffer): JavascriptPackageManifest;
   export declare function  encodeResolvedJavascriptPackageTag(message: ResolvedJavascriptPackageTag, bb: ByteBuffer): void;